@@ -35,6 +35,21 @@ const GetToken: Component = () => {
     }
   };
 
+  const signInWithDiscogs = async () => {
+    try {
+      const response = await postApi("auth/oauth/request", {});
+      window.location.href = response.data.authorizeUrl;
+    } catch (error) {
+      console.error("Error starting Discogs sign in:", error);
+      setIsError(true);
+      setErrorMessage(
+        error.response?.status === 503
+          ? "Signing in with Discogs is not configured on this server"
+          : "Failed to start Discogs sign in",
+      );
+    }
+  };
+
   const handleSubmit = async (e: Event) => {
     e.preventDefault();
     if (!token().trim()) {
@@ -86,6 +101,10 @@ const GetToken: Component = () => {
         </ol>
       </div>
 
+      <button type="button" class={styles.button} onClick={signInWithDiscogs}>
+        Sign in with Discogs
+      </button>
+
       <form onSubmit={handleSubmit} class={styles.form}>
         <div class={styles.formGroup}>
           <label for="token" class={styles.label}>
@@ -51,7 +51,7 @@ export const deleteRelease = async (id: number) => {
 
 export const archiveRelease = async (id: number) => {
   try {
-    const response = await deleteApi(`releases/${id}/archive`);
+    const response = await apiClient.post(`/releases/${id}/archive`);
     if (response.status !== 200) {
       throw new Error("Failed to archive release");
     }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"kleio/internal/logging"
 	"kleio/internal/server"
 	"log"
 	"net/http"
@@ -36,6 +37,7 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 }
 
 func main() {
+	logging.Setup()
 	log.Println("Starting server...")
 
 	server := server.NewServer()
@@ -0,0 +1,59 @@
+// Command copytopostgres copies a SQLite database into a new Postgres
+// database, to move a Kleio install over. Stop Kleio before running it. The
+// SQLite database is migrated to this build's schema first. Stored
+// credentials are encrypted again with the Postgres install's key, so that
+// key, from KLEIO_SECRET_KEY or the key file in its DATA_DIR, has to be used
+// afterwards.
+package main
+
+import (
+	"flag"
+	"kleio/internal/database"
+	"kleio/internal/logging"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+)
+
+func main() {
+	sqlitePath := flag.String("sqlite", database.DefaultPath(), "SQLite database to copy from")
+	postgresURL := flag.String("postgres", os.Getenv("DATABASE_URL"), "Postgres database to copy into, defaults to DATABASE_URL")
+	flag.Parse()
+
+	logging.Setup()
+
+	if *postgresURL == "" {
+		log.Fatal("No Postgres database given, set -postgres or DATABASE_URL")
+	}
+	if _, err := os.Stat(*sqlitePath); err != nil {
+		log.Fatalf("Failed to find SQLite database: %v", err)
+	}
+
+	source, err := database.Open(*sqlitePath)
+	if err != nil {
+		log.Fatalf("Failed to open SQLite database: %v", err)
+	}
+	defer source.Close()
+
+	dest, err := database.OpenPostgres(*postgresURL)
+	if err != nil {
+		log.Fatalf("Failed to open Postgres database: %v", err)
+	}
+	defer dest.Close()
+
+	copied, err := source.CopyTo(dest)
+	if err != nil {
+		log.Fatalf("Failed to copy database: %v", err)
+	}
+
+	tables := make([]string, 0, len(copied))
+	var total int64
+	for table, rows := range copied {
+		tables = append(tables, table)
+		total += rows
+	}
+	sort.Strings(tables)
+
+	slog.Info("Copied SQLite database into Postgres", "tables", len(tables), "rows", total)
+}
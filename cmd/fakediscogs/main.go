@@ -0,0 +1,38 @@
+// Command fakediscogs serves the canned collection from internal/discogs/fake.
+// Run Kleio with DISCOGS_BASE_URL=http://localhost:38181 to sync against it.
+package main
+
+import (
+	"flag"
+	"kleio/internal/discogs"
+	"kleio/internal/discogs/fake"
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":38181", "address to listen on")
+	rateLimit := flag.Int("rate-limit", 60, "requests allowed per minute")
+	retryAfter := flag.Duration("retry-after", time.Second, "Retry-After sent with 429 responses")
+	throttleEvery := flag.Int("throttle-every", 0, "force a 429 on every nth request (0 disables)")
+	consumerKey := flag.String("consumer-key", fake.DefaultConsumerKey, "OAuth consumer key to accept")
+	consumerSecret := flag.String("consumer-secret", fake.DefaultConsumerSecret, "OAuth consumer secret to accept")
+	flag.Parse()
+
+	server, err := fake.New()
+	if err != nil {
+		log.Fatalf("Failed to create fake Discogs server: %v", err)
+	}
+
+	server.RateLimit = *rateLimit
+	server.RetryAfter = *retryAfter
+	server.ThrottleEvery = *throttleEvery
+	server.Consumer = discogs.OAuthConsumer{Key: *consumerKey, Secret: *consumerSecret}
+
+	slog.Info("Fake Discogs server listening", "addr", *addr)
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.Fatalf("Fake Discogs server error: %v", err)
+	}
+}
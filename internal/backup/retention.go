@@ -0,0 +1,62 @@
+package backup
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Retention decides which snapshots are kept: the newest KeepLast, plus the
+// newest of each of the last KeepDaily days and KeepWeekly weeks that have
+// one. Days and weeks are in UTC. The newest snapshot is always kept.
+type Retention struct {
+	KeepLast   int `json:"keepLast"`
+	KeepDaily  int `json:"keepDaily"`
+	KeepWeekly int `json:"keepWeekly"`
+}
+
+var DefaultRetention = Retention{KeepLast: 7, KeepDaily: 14, KeepWeekly: 8}
+
+// Expired returns the snapshots the rules don't keep
+func (r Retention) Expired(snapshots []Snapshot) []Snapshot {
+	sorted := append([]Snapshot(nil), snapshots...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+
+	keep := make(map[string]bool, len(sorted))
+	for i := 0; i < len(sorted) && i < max(r.KeepLast, 1); i++ {
+		keep[sorted[i].Name] = true
+	}
+
+	keepNewestPer := func(limit int, period func(Snapshot) string) {
+		seen := make(map[string]bool)
+		for _, snapshot := range sorted {
+			if len(seen) >= limit {
+				return
+			}
+			key := period(snapshot)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			keep[snapshot.Name] = true
+		}
+	}
+
+	keepNewestPer(r.KeepDaily, func(snapshot Snapshot) string {
+		return snapshot.CreatedAt.UTC().Format("2006-01-02")
+	})
+	keepNewestPer(r.KeepWeekly, func(snapshot Snapshot) string {
+		year, week := snapshot.CreatedAt.UTC().ISOWeek()
+		return fmt.Sprintf("%d-%02d", year, week)
+	})
+
+	var expired []Snapshot
+	for _, snapshot := range sorted {
+		if !keep[snapshot.Name] {
+			expired = append(expired, snapshot)
+		}
+	}
+
+	return expired
+}
@@ -0,0 +1,180 @@
+// Package backup keeps database snapshots. Where they live is up to a Store,
+// so the local directory can be swapped for something off the machine, and
+// which ones are kept is up to a Retention.
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+var (
+	ErrInvalidName = errors.New("invalid snapshot name")
+	ErrNotFound    = errors.New("snapshot not found")
+)
+
+// Snapshot describes a stored copy of the database
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Label     string    `json:"label,omitempty"` // Why it was taken, e.g. "scheduled"
+	CreatedAt time.Time `json:"createdAt"`
+	Size      int64     `json:"size"`
+}
+
+// Store is somewhere snapshots can be kept. Names always come from NewName.
+type Store interface {
+	// Save stores the snapshot read from r
+	Save(name string, r io.Reader) (Snapshot, error)
+	// List returns every snapshot, newest first
+	List() ([]Snapshot, error)
+	Open(name string) (io.ReadCloser, error)
+	Delete(name string) error
+}
+
+const nameTimeLayout = "20060102T150405.000Z"
+
+var namePattern = regexp.MustCompile(`^kleio-(\d{8}T\d{6}\.\d{3}Z)(?:-([a-z]+(?:-[a-z]+)*))?\.db$`)
+
+// NewName names a snapshot taken at createdAt, e.g.
+// kleio-20240102T030405.678Z-scheduled.db. The label is optional.
+func NewName(createdAt time.Time, label string) string {
+	name := "kleio-" + createdAt.UTC().Format(nameTimeLayout)
+	if label != "" {
+		name += "-" + label
+	}
+
+	return name + ".db"
+}
+
+// ParseName reads the time and label back out of a snapshot name
+func ParseName(name string) (Snapshot, error) {
+	match := namePattern.FindStringSubmatch(name)
+	if match == nil {
+		return Snapshot{}, ErrInvalidName
+	}
+
+	createdAt, err := time.Parse(nameTimeLayout, match[1])
+	if err != nil {
+		return Snapshot{}, ErrInvalidName
+	}
+
+	return Snapshot{Name: name, Label: match[2], CreatedAt: createdAt}, nil
+}
+
+// DirStore keeps snapshots as files in a local directory
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore creates a store in dir, which is created on first save
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+func (s *DirStore) Save(name string, r io.Reader) (Snapshot, error) {
+	snapshot, err := ParseName(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// Write then rename so a half written snapshot is never listed
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	snapshot.Size, err = io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return Snapshot{}, fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to store snapshot file: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+func (s *DirStore) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		snapshot, err := ParseName(entry.Name())
+		if err != nil || !entry.Type().IsRegular() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snapshot.Size = info.Size()
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+
+	return snapshots, nil
+}
+
+func (s *DirStore) Open(name string) (io.ReadCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return file, err
+}
+
+func (s *DirStore) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// path only accepts well formed names, so nothing outside dir can be reached
+func (s *DirStore) path(name string) (string, error) {
+	if _, err := ParseName(name); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(s.dir, name), nil
+}
@@ -1,16 +1,37 @@
 package controller
 
 import (
+	"errors"
+	"kleio/internal/database"
+	"kleio/internal/discogs"
 	"log/slog"
 )
 
+const (
+	AuthMethodToken = "token"
+	AuthMethodOAuth = "oauth"
+)
+
+// ErrOAuthNotConfigured is returned when no Discogs consumer key and secret
+// are set, so only personal access tokens can be used
+var ErrOAuthNotConfigured = errors.New("OAuth sign in is not configured")
+
+// ErrNotSignedIn is returned by anything that has to call Discogs before
+// credentials have been saved
+var ErrNotSignedIn = errors.New("not signed in to Discogs")
+
 func (c *Controller) GetAuth() (payload Payload, err error) {
-	payload.Token, err = c.DB.GetToken()
+	payload.Token, err = c.Auth.GetToken()
 	if err != nil {
 		slog.Error("Failed to get token", "error", err)
 		return Payload{}, nil
 	}
 
+	payload.AuthMethod = AuthMethodToken
+	if user, err := c.Auth.GetUser(); err == nil && user.UsesOAuth() {
+		payload.AuthMethod = AuthMethodOAuth
+	}
+
 	err = payload.GetPayload(c)
 	if err != nil {
 		slog.Error("Failed to get payload", "error", err)
@@ -21,26 +42,90 @@ func (c *Controller) GetAuth() (payload Payload, err error) {
 }
 
 func (c *Controller) SaveToken(token string) (payload Payload, err error) {
-	username, err := GetUserIdentity(token)
+	identity, err := c.Discogs.GetUserIdentity(database.User{Token: token})
 	if err != nil {
 		slog.Error("Failed to get user identity", "error", err)
 		return payload, err
 	}
 
-	err = c.DB.SaveToken(token, username)
+	err = c.Auth.SaveToken(token, identity.Username)
 	if err != nil {
 		slog.Error("Failed to save token", "error", err)
 		return payload, err
 	}
 
+	return c.signedIn()
+}
+
+// StartOAuth gets a request token from Discogs and returns the page where the
+// user approves it. Discogs then sends them to callbackURL.
+func (c *Controller) StartOAuth(callbackURL string) (string, error) {
+	if !c.Discogs.OAuthEnabled() {
+		return "", ErrOAuthNotConfigured
+	}
+
+	requestToken, err := c.Discogs.GetRequestToken(callbackURL)
+	if err != nil {
+		slog.Error("Failed to get OAuth request token", "error", err)
+		return "", err
+	}
+
+	if err := c.Auth.SaveOAuthRequestToken(requestToken.Token, requestToken.Secret); err != nil {
+		return "", err
+	}
+
+	return c.Discogs.AuthorizeURL(requestToken.Token), nil
+}
+
+// CompleteOAuth exchanges an approved request token for an access token and
+// signs in with it. It returns sql.ErrNoRows for a request token that is
+// unknown, expired or already used.
+func (c *Controller) CompleteOAuth(requestToken, verifier string) (payload Payload, err error) {
+	secret, err := c.Auth.TakeOAuthRequestToken(requestToken)
+	if err != nil {
+		return payload, err
+	}
+
+	accessToken, err := c.Discogs.GetAccessToken(
+		discogs.OAuthToken{Token: requestToken, Secret: secret},
+		verifier,
+	)
+	if err != nil {
+		slog.Error("Failed to get OAuth access token", "error", err)
+		return payload, err
+	}
+
+	identity, err := c.Discogs.GetUserIdentity(database.User{
+		OAuthToken:       accessToken.Token,
+		OAuthTokenSecret: accessToken.Secret,
+	})
+	if err != nil {
+		slog.Error("Failed to get user identity", "error", err)
+		return payload, err
+	}
+
+	err = c.Auth.SaveOAuthCredentials(identity.Username, accessToken.Token, accessToken.Secret)
+	if err != nil {
+		slog.Error("Failed to save OAuth credentials", "error", err)
+		return payload, err
+	}
+
+	slog.Info("Signed in with Discogs OAuth", "username", identity.Username)
+	return c.signedIn()
+}
+
+// signedIn returns the payload for newly saved credentials and starts a sync
+func (c *Controller) signedIn() (payload Payload, err error) {
 	payload, err = c.GetAuth()
 	if err != nil {
 		slog.Error("Failed to get auth", "error", err)
 		return payload, err
 	}
 
+	// New credentials are synced straight away rather than on the schedule
 	go c.AsyncCollection()
 	payload.SyncingData = true
+	payload.NextSync = nil
 
 	return payload, nil
 }
@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"kleio/internal/backup"
+	"kleio/internal/database"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultBackupInterval is how often a scheduled snapshot is taken
+	DefaultBackupInterval = 24 * time.Hour
+
+	// backupTimeout bounds a single backup or restore, which mostly waits
+	// for writers to let go of the database
+	backupTimeout = 5 * time.Minute
+	// failedBackupRetryDelay is how long the scheduler waits after a failure
+	failedBackupRetryDelay = 30 * time.Minute
+
+	BackupLabelManual     = "manual"
+	BackupLabelScheduled  = "scheduled"
+	BackupLabelPreRestore = "pre-restore"
+)
+
+// BackupConfig controls the scheduled snapshots and how many are kept. A zero
+// Interval turns the schedule off, snapshots can still be taken on demand.
+type BackupConfig struct {
+	Dir       string
+	Interval  time.Duration
+	Retention backup.Retention
+}
+
+// LoadBackupConfig reads BACKUP_DIR, which defaults to a backups directory
+// in dataDir, BACKUP_INTERVAL, a duration or "off", and
+// BACKUP_KEEP_LAST, BACKUP_KEEP_DAILY and BACKUP_KEEP_WEEKLY. Invalid values
+// are logged and the defaults used instead.
+func LoadBackupConfig(dataDir string) BackupConfig {
+	config := BackupConfig{
+		Dir:       os.Getenv("BACKUP_DIR"),
+		Interval:  DefaultBackupInterval,
+		Retention: backup.DefaultRetention,
+	}
+	if config.Dir == "" {
+		config.Dir = filepath.Join(dataDir, "backups")
+	}
+
+	switch value := os.Getenv("BACKUP_INTERVAL"); value {
+	case "":
+	case "off", "0":
+		config.Interval = 0
+	default:
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < time.Minute {
+			slog.Error("Invalid BACKUP_INTERVAL, using the default",
+				"value", value,
+				"default", DefaultBackupInterval)
+		} else {
+			config.Interval = interval
+		}
+	}
+
+	for name, keep := range map[string]*int{
+		"BACKUP_KEEP_LAST":   &config.Retention.KeepLast,
+		"BACKUP_KEEP_DAILY":  &config.Retention.KeepDaily,
+		"BACKUP_KEEP_WEEKLY": &config.Retention.KeepWeekly,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			slog.Error("Invalid backup retention, using the default",
+				"name", name,
+				"value", value,
+				"default", *keep)
+			continue
+		}
+		*keep = count
+	}
+
+	return config
+}
+
+// CreateBackup takes a snapshot of the database now and applies the
+// retention rules
+func (c *Controller) CreateBackup(label string) (backup.Snapshot, error) {
+	if c.BackupSource == nil {
+		return backup.Snapshot{}, database.ErrBackupUnsupported
+	}
+
+	c.backupMutex.Lock()
+	defer c.backupMutex.Unlock()
+
+	return c.createBackup(label)
+}
+
+func (c *Controller) ListBackups() ([]backup.Snapshot, error) {
+	return c.Backups.List()
+}
+
+// RestoreBackup swaps the named snapshot in for the live database. The data
+// it replaces is backed up first, and that snapshot is returned so the
+// restore can be undone. It won't run alongside a sync.
+func (c *Controller) RestoreBackup(name string) (backup.Snapshot, error) {
+	if _, err := backup.ParseName(name); err != nil {
+		return backup.Snapshot{}, err
+	}
+	if c.BackupSource == nil {
+		return backup.Snapshot{}, database.ErrBackupUnsupported
+	}
+
+	if !c.syncMutex.TryLock() {
+		return backup.Snapshot{}, ErrSyncInProgress
+	}
+	defer c.syncMutex.Unlock()
+
+	c.backupMutex.Lock()
+	defer c.backupMutex.Unlock()
+
+	// The restore migrates the file it is given, so it works on a copy
+	tmp, err := os.CreateTemp("", "kleio-restore-*.db")
+	if err != nil {
+		return backup.Snapshot{}, fmt.Errorf("failed to create restore file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	snapshot, err := c.Backups.Open(name)
+	if err != nil {
+		tmp.Close()
+		return backup.Snapshot{}, err
+	}
+	_, err = io.Copy(tmp, snapshot)
+	snapshot.Close()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return backup.Snapshot{}, fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+
+	// Checked before anything is backed up or replaced
+	if _, err := database.ValidateSnapshot(tmp.Name()); err != nil {
+		return backup.Snapshot{}, err
+	}
+
+	preRestore, err := c.createBackup(BackupLabelPreRestore)
+	if err != nil {
+		slog.Error("Failed to back up database before restoring", "error", err)
+		return backup.Snapshot{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	if err := c.BackupSource.Restore(ctx, tmp.Name()); err != nil {
+		return backup.Snapshot{}, err
+	}
+
+	slog.Info("Restored backup", "name", name)
+
+	// Cached images and the sync history came back with the snapshot
+	c.signalImageCache()
+	c.signalScheduler()
+
+	return preRestore, nil
+}
+
+// createBackup writes a snapshot to a temporary file, hands it to the store
+// and prunes. backupMutex must be held.
+func (c *Controller) createBackup(label string) (backup.Snapshot, error) {
+	tmp, err := os.CreateTemp("", "kleio-backup-*.db")
+	if err != nil {
+		return backup.Snapshot{}, fmt.Errorf("failed to create backup file: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	if err := c.BackupSource.Backup(ctx, tmp.Name()); err != nil {
+		return backup.Snapshot{}, err
+	}
+
+	file, err := os.Open(tmp.Name())
+	if err != nil {
+		return backup.Snapshot{}, fmt.Errorf("failed to read backup file: %w", err)
+	}
+	defer file.Close()
+
+	snapshot, err := c.Backups.Save(backup.NewName(time.Now(), label), file)
+	if err != nil {
+		slog.Error("Failed to store backup", "error", err)
+		return backup.Snapshot{}, err
+	}
+
+	slog.Info("Backed up database", "name", snapshot.Name, "size", snapshot.Size)
+
+	if err := c.pruneBackups(); err != nil {
+		slog.Warn("Failed to prune backups", "error", err)
+	}
+
+	return snapshot, nil
+}
+
+func (c *Controller) pruneBackups() error {
+	snapshots, err := c.Backups.List()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, snapshot := range c.BackupConfig.Retention.Expired(snapshots) {
+		if err := c.Backups.Delete(snapshot.Name); err != nil && !errors.Is(err, backup.ErrNotFound) {
+			errs = append(errs, err)
+			continue
+		}
+		slog.Info("Deleted expired backup", "name", snapshot.Name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// RunBackupScheduler takes a snapshot every BackupConfig.Interval, counting
+// from the newest one stored, so restarts don't cause extra snapshots. It
+// never returns.
+func (c *Controller) RunBackupScheduler() {
+	if c.BackupConfig.Interval <= 0 {
+		slog.Info("Scheduled backups are turned off")
+		return
+	}
+	if c.BackupSource == nil {
+		slog.Info("Scheduled backups are not available for this database")
+		return
+	}
+
+	slog.Info("Backup scheduler started", "interval", c.BackupConfig.Interval, "dir", c.BackupConfig.Dir)
+
+	for {
+		wait := time.Duration(0)
+		snapshots, err := c.Backups.List()
+		if err != nil {
+			slog.Error("Failed to list backups", "error", err)
+			wait = schedulerIdlePoll
+		} else if len(snapshots) > 0 {
+			wait = time.Until(snapshots[0].CreatedAt.Add(c.BackupConfig.Interval))
+		}
+
+		if wait > 0 {
+			time.Sleep(min(wait, schedulerMaxSleep))
+			continue
+		}
+
+		if _, err := c.CreateBackup(BackupLabelScheduled); err != nil {
+			slog.Error("Scheduled backup failed", "error", err)
+			time.Sleep(failedBackupRetryDelay)
+		}
+	}
+}
@@ -9,7 +9,7 @@ import (
 func (c *Controller) CreateCleaningHistory(
 	history *database.CleaningHistory,
 ) (payload Payload, err error) {
-	err = c.DB.CreateCleaningHistory(history)
+	err = c.Cleanings.CreateCleaningHistory(history)
 	if err != nil {
 		slog.Error("Failed to create cleaning history", "error", err)
 		return
@@ -26,7 +26,7 @@ func (c *Controller) CreateCleaningHistory(
 func (c *Controller) UpdateCleaningHistory(
 	history *database.CleaningHistory,
 ) (payload Payload, err error) {
-	err = c.DB.UpdateCleaningHistory(history)
+	err = c.Cleanings.UpdateCleaningHistory(history)
 	if err != nil {
 		slog.Error("Failed to update cleaning history", "error", err)
 		return
@@ -41,7 +41,7 @@ func (c *Controller) UpdateCleaningHistory(
 }
 
 func (c *Controller) DeleteCleaningHistory(id int) (payload Payload, err error) {
-	err = c.DB.DeleteCleaningHistory(id)
+	err = c.Cleanings.DeleteCleaningHistory(id)
 	if err != nil {
 		slog.Error("Failed to delete cleaning history", "error", err)
 		return
@@ -58,7 +58,7 @@ func (c *Controller) DeleteCleaningHistory(id int) (payload Payload, err error)
 func (c *Controller) GetCleaningsByTimeRange(
 	start, end time.Time,
 ) ([]database.CleaningHistory, error) {
-	cleanings, err := c.DB.GetCleaningsByTimeRange(start, end)
+	cleanings, err := c.Cleanings.GetCleaningsByTimeRange(start, end)
 	if err != nil {
 		slog.Error("Failed to get cleanings by time range", "error", err)
 		return nil, err
@@ -68,7 +68,7 @@ func (c *Controller) GetCleaningsByTimeRange(
 }
 
 func (c *Controller) CountCleaningsByRelease() (map[int]int, error) {
-	counts, err := c.DB.CountCleaningsByRelease()
+	counts, err := c.Cleanings.CountCleaningsByRelease()
 	if err != nil {
 		slog.Error("Failed to count cleanings by release", "error", err)
 		return nil, err
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"kleio/internal/database"
 	"log/slog"
@@ -16,45 +17,157 @@ func (c *Controller) GetCollection() (payload Payload, err error) {
 	return payload, err
 }
 
-func (c *Controller) SyncCollection() error {
-	if err := c.SyncFolders(); err != nil {
-		slog.Error("Failed to sync folders", "error", err)
-		return err
+// FullSyncInterval is how often an incremental sync is replaced by a full
+// reconciliation, which also picks up removals, folder moves and rating changes
+const FullSyncInterval = 7 * 24 * time.Hour
+
+// SyncCollection runs a sync job from its current phase, saving a checkpoint
+// as it goes so an interrupted job can pick up where it left off
+func (c *Controller) SyncCollection(job *database.Sync) error {
+	// Local edits go out first so the sync reads them back rather than
+	// overwriting them
+	if err := c.PushDiscogsWrites(); err != nil {
+		slog.Warn("Failed to push Discogs writes before sync", "error", err)
 	}
 
-	if err := c.SyncReleases(); err != nil {
-		slog.Error("Failed to sync collection", "error", err)
-		return err
+	if job.Phase == database.SyncPhaseFolders {
+		c.publishPhase(job)
+		folderChanges, err := c.SyncFolders(job.ID)
+		if err != nil {
+			slog.Error("Failed to sync folders", "error", err)
+			return err
+		}
+
+		// Notes are still stored by field ID without the definitions, so a
+		// failure here only costs their names until the next sync
+		if err := c.SyncCollectionFields(); err != nil {
+			slog.Warn("Failed to sync collection fields", "error", err)
+		}
+
+		// Only a full sync revisits existing releases, which is how releases
+		// in a deleted folder get moved to the folder they are in now
+		if job.Mode == database.SyncModeIncremental && hasDeletedFolder(folderChanges) {
+			slog.Info("Folders were deleted on Discogs, switching to a full sync", "syncID", job.ID)
+			job.Mode = database.SyncModeFull
+		}
+
+		job.Phase = database.SyncPhaseReleases
+		job.FolderID = nil
+		job.Page = 1
+		job.ReleasesProcessed = 0
+		if err := c.Syncs.SaveSyncCheckpoint(*job); err != nil {
+			return err
+		}
+	}
+
+	if job.Phase == database.SyncPhaseReleases {
+		c.publishPhase(job)
+		syncReleases := c.SyncReleases
+		if job.Mode == database.SyncModeIncremental {
+			syncReleases = c.SyncReleasesIncremental
+		}
+
+		if err := syncReleases(job); err != nil {
+			slog.Error("Failed to sync collection", "error", err, "mode", job.Mode)
+			return err
+		}
+
+		job.Phase = database.SyncPhaseWantlist
+		job.ReleasesProcessed = 0
+		if err := c.Syncs.SaveSyncCheckpoint(*job); err != nil {
+			return err
+		}
+	}
+
+	if job.Phase == database.SyncPhaseWantlist {
+		c.publishPhase(job)
+		if err := c.SyncWantlist(job); err != nil {
+			slog.Error("Failed to sync wantlist", "error", err)
+			return err
+		}
+
+		job.Phase = database.SyncPhaseTracks
+		job.ReleasesProcessed = 0
+		if err := c.Syncs.SaveSyncCheckpoint(*job); err != nil {
+			return err
+		}
 	}
 
-	if err := c.syncTracksAndDuration(); err != nil {
+	c.publishPhase(job)
+	if err := c.syncTracksAndDuration(job); err != nil {
 		slog.Error("Failed to sync tracks and duration", "error", err)
 		return err
 	}
 
+	// The estimates stand until the next sync, so a failure isn't fatal
+	if err := c.refreshEstimatedDurations(); err != nil {
+		slog.Warn("Failed to refresh estimated durations", "error", err)
+	}
+
 	return nil
 }
 
-func (c *Controller) AsyncCollection() (err error) {
-	slog.Info("AsyncCollection started")
-	defer func() {
-		if r := recover(); r != nil {
-			slog.Error("AsyncCollection panicked", "panic", r)
-			err = fmt.Errorf("sync panicked: %v", r)
+func hasDeletedFolder(changes []database.FolderChange) bool {
+	for _, change := range changes {
+		if change.Type == database.FolderChangeDeleted {
+			return true
 		}
-		if err != nil {
-			slog.Error("AsyncCollection failed", "error", err)
-			err := c.DB.CleanupAbandonedSyncs()
-			if err != nil {
-				slog.Error("Failed to cleanup abandoned syncs", "error", err)
-			}
-		} else {
-			slog.Info("AsyncCollection completed successfully")
-		}
-	}()
+	}
+
+	return false
+}
+
+func (c *Controller) publishPhase(job *database.Sync) {
+	c.Events.Publish(SyncEvent{
+		Type:      SyncEventPhase,
+		SyncID:    job.ID,
+		Mode:      job.Mode,
+		Phase:     job.Phase,
+		Processed: job.ReleasesProcessed,
+	})
+}
+
+// nextSyncMode picks a full sync when there hasn't been a successful one within
+// FullSyncInterval, and an incremental sync otherwise
+func (c *Controller) nextSyncMode() (database.SyncMode, error) {
+	lastFullSync, err := c.Syncs.GetLatestCompletedSync(database.SyncModeFull)
+	if err != nil {
+		slog.Error("Failed to get latest full sync", "error", err)
+		return "", err
+	}
+
+	if lastFullSync.ID == 0 || time.Since(lastFullSync.SyncStart) > FullSyncInterval {
+		return database.SyncModeFull, nil
+	}
+
+	return database.SyncModeIncremental, nil
+}
+
+// AsyncCollection syncs the collection, choosing between an incremental sync
+// and a periodic full reconciliation
+func (c *Controller) AsyncCollection() error {
+	mode, err := c.nextSyncMode()
+	if err != nil {
+		return err
+	}
+
+	return c.AsyncCollectionWithMode(mode)
+}
+
+// ErrSyncInProgress is returned when a sync is asked for while one is running
+var ErrSyncInProgress = errors.New("sync already in progress")
+
+func (c *Controller) AsyncCollectionWithMode(mode database.SyncMode) error {
+	// Checking for a running sync and starting one has to happen as one step,
+	// or two callers can both see none running
+	if !c.syncMutex.TryLock() {
+		slog.Warn("Another sync is already starting or running")
+		return ErrSyncInProgress
+	}
+	defer c.syncMutex.Unlock()
 
 	// Check if sync is already in progress
-	latestSync, err := c.DB.GetLatestSync()
+	latestSync, err := c.Syncs.GetLatestSync()
 	if err != nil {
 		slog.Error("Failed to check latest sync", "error", err)
 		return err
@@ -64,41 +177,156 @@ func (c *Controller) AsyncCollection() (err error) {
 		slog.Warn("Another sync is already in progress", 
 			"syncID", latestSync.ID,
 			"syncStarted", latestSync.SyncStart)
-		return fmt.Errorf("sync already in progress (ID: %d)", latestSync.ID)
+		return fmt.Errorf("%w (ID: %d)", ErrSyncInProgress, latestSync.ID)
 	}
 
-	id, err := c.DB.StartSync()
+	sessionID := generateSyncSessionID()
+	id, err := c.Syncs.StartSync(mode, sessionID)
 	if err != nil {
 		slog.Error("Failed to start sync", "error", err)
 		return err
 	}
 
-	slog.Info("Starting full collection sync", "syncID", id)
+	job := database.Sync{
+		ID:        id,
+		SyncStart: time.Now(),
+		Status:    "in_progress",
+		Mode:      mode,
+		Phase:     database.SyncPhaseFolders,
+		SessionID: sessionID,
+		Page:      1,
+	}
+
+	slog.Info("Starting collection sync", "syncID", id, "mode", mode)
+	return c.runSync(&job)
+}
+
+// ResumeInterruptedSync continues the newest sync left in progress by a
+// previous run of the process from its last checkpoint. Older leftovers are
+// marked failed.
+func (c *Controller) ResumeInterruptedSync() error {
+	c.syncMutex.Lock()
+	defer c.syncMutex.Unlock()
+
+	syncs, err := c.Syncs.GetInterruptedSyncs()
+	if err != nil {
+		slog.Error("Failed to get interrupted syncs", "error", err)
+		return err
+	}
+
+	if len(syncs) == 0 {
+		return nil
+	}
+
+	for _, stale := range syncs[1:] {
+		if err := c.Syncs.CompleteSync(stale.ID, false); err != nil {
+			slog.Error("Failed to fail stale sync", "error", err, "syncID", stale.ID)
+		}
+	}
+
+	job := syncs[0]
+	if job.SessionID == "" {
+		// Started before sync checkpoints existed, so there is nothing to resume
+		slog.Warn("Interrupted sync has no checkpoint, marking failed", "syncID", job.ID)
+		return c.Syncs.CompleteSync(job.ID, false)
+	}
 
-	if err = c.SyncCollection(); err != nil {
-		slog.Error("Failed to sync collection", "error", err, "syncID", id)
-		return c.DB.CompleteSync(id, false)
+	var folderID any
+	if job.FolderID != nil {
+		folderID = *job.FolderID
 	}
 
-	if err := c.DB.CompleteSync(id, true); err != nil {
-		slog.Error("Failed to complete sync", "error", err, "syncID", id)
+	slog.Info("Resuming interrupted sync",
+		"syncID", job.ID,
+		"mode", job.Mode,
+		"phase", job.Phase,
+		"folderID", folderID,
+		"page", job.Page,
+		"releasesProcessed", job.ReleasesProcessed)
+
+	return c.runSync(&job)
+}
+
+// runSync runs a sync job to completion and records the outcome
+func (c *Controller) runSync(job *database.Sync) (err error) {
+	slog.Info("AsyncCollection started", "syncID", job.ID, "mode", job.Mode, "phase", job.Phase)
+
+	var syncErr error
+	defer func() {
+		summary := SyncEvent{
+			Type:            SyncEventSummary,
+			SyncID:          job.ID,
+			Mode:            job.Mode,
+			Phase:           job.Phase,
+			Status:          "complete",
+			Processed:       job.ReleasesProcessed,
+			Failed:          job.FailedFolders,
+			DurationSeconds: time.Since(job.SyncStart).Seconds(),
+		}
+		if syncErr == nil {
+			syncErr = err
+		}
+		if syncErr != nil {
+			summary.Status = "failed"
+			summary.Error = syncErr.Error()
+		}
+		c.Events.Publish(summary)
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("AsyncCollection panicked", "panic", r)
+			err = fmt.Errorf("sync panicked: %v", r)
+		}
+		if err != nil {
+			slog.Error("AsyncCollection failed", "error", err)
+			err := c.Syncs.CleanupAbandonedSyncs()
+			if err != nil {
+				slog.Error("Failed to cleanup abandoned syncs", "error", err)
+			}
+		} else {
+			slog.Info("AsyncCollection completed successfully")
+		}
+	}()
+
+	if err = c.SyncCollection(job); err != nil {
+		syncErr = err
+		slog.Error("Failed to sync collection", "error", err, "syncID", job.ID)
+		return c.Syncs.CompleteSync(job.ID, false)
+	}
+
+	if err := c.Syncs.CompleteSync(job.ID, true); err != nil {
+		slog.Error("Failed to complete sync", "error", err, "syncID", job.ID)
 		return err
 	}
 
-	slog.Info("Full collection sync completed", "syncID", id)
+	slog.Info("Collection sync completed", "syncID", job.ID, "mode", job.Mode)
+
+	c.signalImageCache()
+	c.signalScheduler()
+
+	go func() {
+		if err := c.RecordCollectionValue(job.ID); err != nil {
+			slog.Error("Failed to record collection value", "error", err, "syncID", job.ID)
+		}
+	}()
+
 	return nil
 }
 
-func (c *Controller) syncTracksAndDuration() error {
-	releases, err := c.DB.GetReleasesWithoutDuration()
+// syncTracksAndDuration fetches tracks and metadata for every release still
+// missing a duration or details. Finished releases drop out of that list, so a
+// resumed job simply carries on with whatever is left.
+func (c *Controller) syncTracksAndDuration(job *database.Sync) error {
+	releases, err := c.Releases.GetReleasesWithoutDetails()
 	if err != nil {
-		slog.Error("Failed to get releases without duration", "error", err)
+		slog.Error("Failed to get releases without details", "error", err)
 		return err
 	}
 
 	slog.Info("Starting track and duration sync", "totalReleases", len(releases))
 
-	user, err := c.DB.GetUser()
+	user, err := c.Auth.GetUser()
 	if err != nil {
 		slog.Error("Failed to get user", "error", err)
 		return err
@@ -107,6 +335,17 @@ func (c *Controller) syncTracksAndDuration() error {
 	processed := 0
 	failed := 0
 
+	publishProgress := func(handled int) {
+		c.Events.Publish(SyncEvent{
+			Type:      SyncEventProgress,
+			SyncID:    job.ID,
+			Phase:     database.SyncPhaseTracks,
+			Processed: handled,
+			Total:     len(releases),
+			Failed:    failed,
+		})
+	}
+
 	for i, release := range releases {
 		slog.Info("Processing release", 
 			"releaseID", release.ID, 
@@ -122,19 +361,19 @@ func (c *Controller) syncTracksAndDuration() error {
 				"releaseID", release.ID,
 				"title", release.Title)
 			failed++
+			publishProgress(i + 1)
 			// Continue processing other releases instead of failing completely
 			continue
 		}
 		processed++
 
-		if c.RateLimit.ShouldThrottle() {
-			current := c.RateLimit.GetCurrent()
-			slog.Info("Rate limit throttling", 
-				"remaining", current.Remaining,
-				"used", current.Used,
-				"limit", current.Limit)
-			time.Sleep(15 * time.Second)
+		job.ReleasesProcessed++
+		if err := c.Syncs.SaveSyncCheckpoint(*job); err != nil {
+			return err
 		}
+
+		publishProgress(i + 1)
+
 	}
 
 	slog.Info("Completed track and duration sync", 
@@ -155,7 +394,7 @@ func (c *Controller) processReleaseTracks(release database.Release, user databas
 		"title", release.Title,
 		"resourceURL", release.ResourceURL)
 
-	tracks, err := c.GetReleaseDetails(release, user.Token)
+	metadata, tracks, err := c.GetReleaseDetails(release, user)
 	if err != nil {
 		slog.Error("processReleaseTracks: Failed to get track and duration", 
 			"error", err,
@@ -169,9 +408,19 @@ func (c *Controller) processReleaseTracks(release database.Release, user databas
 		"releaseID", release.ID,
 		"trackCount", len(tracks))
 
-	err = c.DB.SaveTracks(release.ID, tracks)
+	return c.saveReleaseTracks(release, metadata, tracks)
+}
+
+// saveReleaseTracks stores a release's details and tracks, then works out its
+// duration from them, estimating it from the formats when tracks have none
+func (c *Controller) saveReleaseTracks(
+	release database.Release,
+	metadata database.ReleaseMetadata,
+	tracks []database.Track,
+) error {
+	err := c.Releases.SaveReleaseDetails(release.ID, metadata, tracks)
 	if err != nil {
-		slog.Error("processReleaseTracks: Failed to save tracks", 
+		slog.Error("processReleaseTracks: Failed to save release details", 
 			"error", err,
 			"releaseID", release.ID,
 			"trackCount", len(tracks))
@@ -205,7 +454,7 @@ func (c *Controller) processReleaseTracks(release database.Release, user databas
 		// Don't return error for zero duration - this might be expected for some releases
 	}
 
-	err = c.DB.UpdateReleaseWithDetails(release.ID, durationSeconds, isDurationEstimated)
+	err = c.Releases.UpdateReleaseWithDetails(release.ID, durationSeconds, isDurationEstimated)
 	if err != nil {
 		slog.Error("processReleaseTracks: Failed to update release duration", 
 			"error", err,
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"kleio/internal/database"
+	"kleio/internal/discogs"
+	"kleio/internal/discogs/fake"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// newFakeDiscogsController creates a controller over a new SQLite database,
+// signed in to server
+func newFakeDiscogsController(t *testing.T, server *fake.Server) (*Controller, *database.Database) {
+	t.Helper()
+
+	dataDir := t.TempDir()
+	db, err := database.Open(filepath.Join(dataDir, "kleio.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	controller := InitNewController(DatabaseStores(db), dataDir)
+	controller.Discogs = discogs.NewClient(httpServer.URL, controller.RateLimit)
+
+	if err := db.SaveToken("fake-token", "kleio-fake"); err != nil {
+		t.Fatalf("failed to save token: %v", err)
+	}
+
+	return controller, db
+}
+
+// runTestSync runs a whole sync job the way runSync does, without the
+// background work that follows a successful one
+func runTestSync(t *testing.T, controller *Controller, mode database.SyncMode, sessionID string) database.Sync {
+	t.Helper()
+
+	id, err := controller.Syncs.StartSync(mode, sessionID)
+	if err != nil {
+		t.Fatalf("failed to start sync: %v", err)
+	}
+
+	job := database.Sync{
+		ID:        id,
+		Status:    "in_progress",
+		Mode:      mode,
+		Phase:     database.SyncPhaseFolders,
+		SessionID: sessionID,
+		Page:      1,
+	}
+	if err := controller.SyncCollection(&job); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if err := controller.Syncs.CompleteSync(id, true); err != nil {
+		t.Fatalf("failed to complete sync: %v", err)
+	}
+
+	return job
+}
+
+func releaseIDs(releases []database.Release) map[int]bool {
+	ids := make(map[int]bool, len(releases))
+	for _, release := range releases {
+		ids[release.ID] = true
+	}
+
+	return ids
+}
+
+func TestSyncCollectionAgainstFakeDiscogs(t *testing.T) {
+	collection, err := fake.DefaultCollection()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := fake.NewWithCollection(collection)
+	// A generous limit keeps the limiter from pacing the test to Discogs's
+	// one request a second
+	server.RateLimit = 600
+	// Every eighth request is turned away, which the client has to wait out
+	// and retry without losing a folder
+	server.ThrottleEvery = 8
+	server.RetryAfter = 0
+	controller, db := newFakeDiscogsController(t, server)
+
+	job := runTestSync(t, controller, database.SyncModeFull, "test_sync_1")
+	if job.FailedFolders != 0 {
+		t.Fatalf("failed folders = %d, want 0", job.FailedFolders)
+	}
+	if limited := controller.RateLimit.State().RateLimited; limited == 0 {
+		t.Fatalf("no 429 seen in %d requests, the test needs at least one", server.Requests())
+	}
+
+	releases, err := db.GetAllReleasesWithArchived()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != len(collection.Releases) {
+		t.Fatalf("synced %d releases, want %d", len(releases), len(collection.Releases))
+	}
+	for _, release := range releases {
+		if release.Archived {
+			t.Errorf("release %d archived after the first sync", release.ID)
+		}
+		if release.PlayDuration == nil {
+			t.Errorf("release %d has no duration", release.ID)
+		}
+	}
+
+	t.Run("removed release is archived", func(t *testing.T) {
+		server.ThrottleEvery = 0
+		if !server.RemoveRelease(5003) {
+			t.Fatal("fixture has no instance 5003")
+		}
+
+		runTestSync(t, controller, database.SyncModeFull, "test_sync_2")
+
+		archived, err := db.GetArchivedReleases()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := releaseIDs(archived); len(ids) != 1 || !ids[1003] {
+			t.Fatalf("archived releases = %v, want only 1003", ids)
+		}
+	})
+
+	t.Run("incremental sync picks up an added release", func(t *testing.T) {
+		added := collection.Releases[0]
+		added.ID = 1100
+		added.InstanceID = 5100
+		added.DateAdded = "2025-01-01T12:00:00-08:00"
+		added.BasicInfo.ID = 1100
+		added.BasicInfo.Title = "Added Later"
+		server.AddRelease(added, collection.Details[collection.Releases[0].ID])
+
+		job := runTestSync(t, controller, database.SyncModeIncremental, "test_sync_3")
+		if job.ReleasesProcessed != 1 {
+			t.Errorf("incremental sync processed %d releases, want 1", job.ReleasesProcessed)
+		}
+
+		release, err := db.GetRelease(1100)
+		if err != nil {
+			t.Fatalf("added release not stored: %v", err)
+		}
+		if release.Title != "Added Later" {
+			t.Errorf("added release title = %q", release.Title)
+		}
+	})
+
+	t.Run("refresh replaces an estimated duration", func(t *testing.T) {
+		release, err := db.GetRelease(1004)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if release.PlayDurationEstimated == nil || !*release.PlayDurationEstimated {
+			t.Fatal("fixture release 1004 should start with an estimated duration")
+		}
+
+		details := collection.Details[1004]
+		details.Tracklist = append([]database.DiscogsTrack(nil), details.Tracklist...)
+		for i := range details.Tracklist {
+			details.Tracklist[i].Duration = "5:00"
+		}
+		if !server.UpdateReleaseDetails(details) {
+			t.Fatal("fixture has no details for 1004")
+		}
+
+		release, err = controller.RefreshRelease(1004)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if release.PlayDurationEstimated == nil || *release.PlayDurationEstimated {
+			t.Error("duration still estimated after refresh")
+		}
+		if want := len(details.Tracklist) * 300; release.PlayDuration == nil || *release.PlayDuration != want {
+			t.Errorf("duration = %v, want %d", release.PlayDuration, want)
+		}
+	})
+}
+
+func TestIncrementalSyncStopsAtSecondCopy(t *testing.T) {
+	collection, err := fake.DefaultCollection()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second copy of Kind Of Blue, added after everything else
+	original := collection.Releases[0]
+	second := original
+	second.InstanceID = 5200
+	second.DateAdded = "2025-01-01T12:00:00-08:00"
+
+	server := fake.NewWithCollection(collection)
+	server.RateLimit = 600
+	server.AddRelease(second, collection.Details[original.ID])
+	controller, db := newFakeDiscogsController(t, server)
+	runTestSync(t, controller, database.SyncModeFull, "test_sync_1")
+
+	// releases keeps whichever copy was saved last, here the older one
+	if _, err := db.DB.Exec("UPDATE releases SET instance_id = ? WHERE id = ?", original.InstanceID, original.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := controller.Syncs.StartSync(database.SyncModeIncremental, "test_sync_2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := database.Sync{
+		ID:        id,
+		Mode:      database.SyncModeIncremental,
+		Phase:     database.SyncPhaseReleases,
+		SessionID: "test_sync_2",
+		Page:      1,
+	}
+	if err := controller.SyncReleasesIncremental(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.ReleasesProcessed != 0 {
+		t.Errorf("incremental sync processed %d releases, want it to stop at the stored copy", job.ReleasesProcessed)
+	}
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"kleio/internal/database"
+	"log/slog"
+)
+
+// SyncCollectionFields stores the user's collection field definitions so
+// notes can be shown and filtered by name
+func (c *Controller) SyncCollectionFields() error {
+	user, err := c.Auth.GetUser()
+	if err != nil {
+		slog.Error("Failed to get user", "error", err)
+		return err
+	}
+
+	fields, err := c.Discogs.GetCollectionFields(user)
+	if err != nil {
+		slog.Error("Failed to get collection fields from Discogs", "error", err)
+		return err
+	}
+
+	if err := c.Releases.SaveCollectionFields(fields); err != nil {
+		return err
+	}
+
+	slog.Info("Collection fields synced", "fieldCount", len(fields))
+	return nil
+}
+
+func (c *Controller) GetCollectionFields() ([]database.CollectionField, error) {
+	return c.Releases.GetCollectionFields()
+}
+
+// FilterReleasesByNote returns releases whose note for a collection field
+// matches the filter, e.g. Media Condition at least VG+
+func (c *Controller) FilterReleasesByNote(filter database.NoteFilter) ([]database.Release, error) {
+	return c.Releases.GetReleasesByNote(filter)
+}
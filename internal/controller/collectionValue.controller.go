@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"kleio/internal/database"
+	"kleio/internal/discogs"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// PriceCheckInterval is how long a release's marketplace price is kept
+	// before it is looked up again
+	PriceCheckInterval = 7 * 24 * time.Hour
+
+	// priceCheckBatch caps the prices looked up per run, so a large
+	// collection doesn't hold the rate limiter for the whole of its first
+	// run and is worked through over several
+	priceCheckBatch = 25
+)
+
+// RecordCollectionValue stores a snapshot of the collection's value on
+// Discogs, then refreshes the marketplace price of releases that are due. It
+// runs after each successful sync and does nothing if a run is already going.
+func (c *Controller) RecordCollectionValue(syncID int64) error {
+	if !c.valueMutex.TryLock() {
+		slog.Info("Collection value update already running, skipping", "syncID", syncID)
+		return nil
+	}
+	defer c.valueMutex.Unlock()
+
+	user, err := c.Auth.GetUser()
+	if err != nil {
+		slog.Error("Failed to get user", "error", err)
+		return err
+	}
+
+	value, err := c.Discogs.GetCollectionValue(user)
+	if err != nil {
+		slog.Error("Failed to get collection value", "error", err)
+		return err
+	}
+
+	snapshot, err := parseCollectionValue(value)
+	if err != nil {
+		return err
+	}
+	snapshot.SyncID = &syncID
+
+	if err := c.Values.SaveCollectionValueSnapshot(snapshot); err != nil {
+		return err
+	}
+	slog.Info("Recorded collection value",
+		"syncID", syncID,
+		"minimum", value.Minimum,
+		"median", value.Median,
+		"maximum", value.Maximum)
+
+	return c.refreshReleasePrices(user)
+}
+
+func (c *Controller) refreshReleasePrices(user database.User) error {
+	releaseIDs, err := c.Values.GetReleasesDueForPriceCheck(PriceCheckInterval, priceCheckBatch)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Refreshing release prices", "releases", len(releaseIDs))
+
+	failed := 0
+	for _, releaseID := range releaseIDs {
+		stats, err := c.Discogs.GetMarketplaceStats(releaseID, user)
+		if err != nil {
+			var statusErr *discogs.StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+				slog.Warn("Failed to get marketplace stats", "error", err, "releaseID", releaseID)
+				failed++
+				continue
+			}
+			// Releases without a marketplace page are recorded as having
+			// nothing for sale so they aren't asked about again until due
+			stats = discogs.MarketplaceStats{}
+		}
+
+		price := database.ReleasePrice{
+			ReleaseID:       releaseID,
+			NumForSale:      stats.NumForSale,
+			BlockedFromSale: stats.BlockedFromSale,
+		}
+		if stats.LowestPrice != nil {
+			price.LowestPrice = &stats.LowestPrice.Value
+			price.Currency = &stats.LowestPrice.Currency
+		}
+
+		if err := c.Values.SaveReleasePrice(price); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("Finished refreshing release prices", "releases", len(releaseIDs), "failed", failed)
+	return nil
+}
+
+// parseCollectionValue turns the formatted amounts Discogs returns, such as
+// "$1,234.56", into numbers
+func parseCollectionValue(value discogs.CollectionValue) (database.CollectionValueSnapshot, error) {
+	var snapshot database.CollectionValueSnapshot
+	var err error
+
+	if snapshot.Minimum, snapshot.Currency, err = parseMoney(value.Minimum); err != nil {
+		return snapshot, err
+	}
+	if snapshot.Median, _, err = parseMoney(value.Median); err != nil {
+		return snapshot, err
+	}
+	if snapshot.Maximum, _, err = parseMoney(value.Maximum); err != nil {
+		return snapshot, err
+	}
+
+	return snapshot, nil
+}
+
+// commaDecimalCurrencies are the currency symbols Discogs shows amounts in
+// with a decimal comma, such as "€1.234,56", for amounts like "€1.2345" that
+// don't say which their separator is
+var commaDecimalCurrencies = map[string]bool{
+	"€":   true,
+	"R$":  true,
+	"kr":  true,
+	"SEK": true,
+}
+
+// parseMoney splits an amount such as "$1,234.56", "CA$12.00" or "€1.234,56"
+// into its value and currency symbol. With both separators in the amount the
+// last one is the decimal point. With only one, the digits after it decide,
+// and the currency when they can't.
+func parseMoney(amount string) (float64, string, error) {
+	amount = strings.TrimSpace(amount)
+	start := strings.IndexFunc(amount, unicode.IsDigit)
+	end := strings.LastIndexFunc(amount, unicode.IsDigit)
+	if start < 0 {
+		return 0, "", fmt.Errorf("no amount in %q", amount)
+	}
+
+	currency := strings.TrimSpace(amount[:start] + amount[end+1:])
+	number := strings.Map(func(r rune) rune {
+		// Spaces and apostrophes group thousands in some currencies
+		if unicode.IsSpace(r) || r == '\'' {
+			return -1
+		}
+		return r
+	}, amount[start:end+1])
+
+	decimal := '.'
+	if commaDecimalCurrencies[currency] {
+		decimal = ','
+	}
+	lastComma, lastDot := strings.LastIndex(number, ","), strings.LastIndex(number, ".")
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		decimal = '.'
+		if lastComma > lastDot {
+			decimal = ','
+		}
+	case lastComma >= 0:
+		decimal = separatorKind(number, ',', decimal)
+	case lastDot >= 0:
+		decimal = separatorKind(number, '.', decimal)
+	}
+
+	thousands := ","
+	if decimal == ',' {
+		thousands = "."
+	}
+	number = strings.ReplaceAll(number, thousands, "")
+	number = strings.Replace(number, string(decimal), ".", 1)
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to parse amount %q: %w", amount, err)
+	}
+
+	return value, currency, nil
+}
+
+// separatorKind tells whether separator, the only one in number, is the
+// decimal point or groups thousands, returning the decimal point either way.
+// One followed by a digit or two is the decimal point and ones followed by
+// groups of three digits group thousands. Anything else is read the way the
+// currency's own decimal point, fallback, says.
+func separatorKind(number string, separator rune, fallback rune) rune {
+	other := '.'
+	if separator == '.' {
+		other = ','
+	}
+
+	groups := strings.Split(number, string(separator))
+	if last := groups[len(groups)-1]; len(groups) == 2 && len(last) >= 1 && len(last) <= 2 {
+		return separator
+	}
+	for _, group := range groups[1:] {
+		if len(group) != 3 {
+			return fallback
+		}
+	}
+
+	return other
+}
+
+// GetCollectionValueHistory returns the value snapshots taken since the given
+// time, oldest first
+func (c *Controller) GetCollectionValueHistory(since time.Time) ([]database.CollectionValueSnapshot, error) {
+	return c.Values.GetCollectionValueSnapshots(since)
+}
@@ -0,0 +1,47 @@
+package controller
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		value    float64
+		currency string
+	}{
+		{"$1,234.56", 1234.56, "$"},
+		{"$12.00", 12, "$"},
+		{"CA$12.50", 12.5, "CA$"},
+		{"£1,234,567.89", 1234567.89, "£"},
+		{"¥12,345", 12345, "¥"},
+		{"€1.234,56", 1234.56, "€"},
+		{"€12,50", 12.5, "€"},
+		{"€1.234", 1234, "€"},
+		{"€12.50", 12.5, "€"},
+		{"€12.5", 12.5, "€"},
+		{"R$12.50", 12.5, "R$"},
+		{"12.50 SEK", 12.5, "SEK"},
+		{"€1.234.567", 1234567, "€"},
+		{"$12,50", 12.5, "$"},
+		{"$1,234", 1234, "$"},
+		{"€1,234.56", 1234.56, "€"},
+		{"R$1.234,56", 1234.56, "R$"},
+		{"1 234,56 kr", 1234.56, "kr"},
+		{"CHF 1'234.50", 1234.5, "CHF"},
+		{"$1.234.567", 1234567, "$"},
+	}
+
+	for _, test := range tests {
+		value, currency, err := parseMoney(test.amount)
+		if err != nil {
+			t.Errorf("parseMoney(%q) failed: %v", test.amount, err)
+			continue
+		}
+		if value != test.value || currency != test.currency {
+			t.Errorf("parseMoney(%q) = %v %q, want %v %q", test.amount, value, currency, test.value, test.currency)
+		}
+	}
+
+	if _, _, err := parseMoney("$"); err == nil {
+		t.Error("parseMoney without an amount didn't fail")
+	}
+}
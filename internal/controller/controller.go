@@ -1,22 +1,105 @@
 package controller
 
 import (
+	"kleio/internal/backup"
 	"kleio/internal/database"
+	"kleio/internal/discogs"
+	"kleio/internal/images"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 )
 
-const (
-	BaseURL   = "https://api.discogs.com"
-	UserAgent = "KleioApp/1.0 +https://github.com/bparsons0904/kleio"
-)
+// DiscogsClient is everything the controller needs from the Discogs API.
+// discogs.Client implements it against the real API or the fake server.
+type DiscogsClient interface {
+	GetUserIdentity(user database.User) (discogs.Identity, error)
+	GetFolders(user database.User) ([]database.Folder, error)
+	GetCollectionFields(user database.User) ([]database.DiscogsCollectionField, error)
+	GetReleasesPage(
+		user database.User,
+		folderID, page, perPage int,
+		sort *discogs.ReleaseSort,
+	) (database.DiscogsResponse, error)
+	GetWantlistPage(user database.User, page, perPage int) (database.DiscogsWantlistResponse, error)
+	GetRelease(releaseID int, user database.User) (discogs.ReleaseDetails, error)
+	GetCollectionValue(user database.User) (discogs.CollectionValue, error)
+	GetMarketplaceStats(releaseID int, user database.User) (discogs.MarketplaceStats, error)
+	DownloadImage(imageURL string) (discogs.Image, error)
+	UpdateInstance(
+		user database.User,
+		folderID, releaseID, instanceID int,
+		update discogs.InstanceUpdate,
+	) error
+
+	OAuthEnabled() bool
+	GetRequestToken(callbackURL string) (discogs.OAuthToken, error)
+	AuthorizeURL(requestToken string) string
+	GetAccessToken(requestToken discogs.OAuthToken, verifier string) (discogs.OAuthToken, error)
+}
 
 type Controller struct {
-	DB        database.Database
-	RateLimit RateLimit
+	Stores
+	Discogs   DiscogsClient
+	RateLimit *discogs.RateLimiter
+	Events    *SyncEvents
+
+	// Serialises pushes of the Discogs write queue, which run from the
+	// background worker and at the start of each sync
+	writeBackMutex  sync.Mutex
+	writeBackSignal chan struct{}
+
+	// Held while a collection value snapshot and price refresh runs
+	valueMutex sync.Mutex
+
+	// Local copies of release artwork, kept next to the database
+	Images      *images.Store
+	imageSignal chan struct{}
+
+	// Schedule is when background syncs run. syncMutex is held while a sync
+	// is started or resumed so two can never run at once.
+	Schedule       SyncSchedule
+	scheduleSignal chan struct{}
+	syncMutex      sync.Mutex
+
+	// Snapshots of the database. backupMutex is held while one is taken or
+	// restored.
+	Backups      backup.Store
+	BackupConfig BackupConfig
+	backupMutex  sync.Mutex
 }
 
-func InitNewController() *Controller {
-	return &Controller{
-		DB:        database.New(),
-		RateLimit: RateLimit{},
+// InitNewController creates a controller over stores, talking to
+// DISCOGS_BASE_URL or the public Discogs API when it is unset. Artwork and
+// backups are kept in dataDir unless configured otherwise.
+func InitNewController(stores Stores, dataDir string) *Controller {
+	controller := &Controller{
+		Stores:    stores,
+		RateLimit: discogs.NewRateLimiter(discogs.DefaultRateLimit),
+		Events:    NewSyncEvents(),
+
+		writeBackSignal: make(chan struct{}, 1),
+		Images:          images.NewStore(filepath.Join(dataDir, "images")),
+		imageSignal:     make(chan struct{}, 1),
+		Schedule:        LoadSyncSchedule(),
+		scheduleSignal:  make(chan struct{}, 1),
+		BackupConfig:    LoadBackupConfig(dataDir),
 	}
+	controller.Backups = backup.NewDirStore(controller.BackupConfig.Dir)
+	controller.RateLimit.OnWait = func(wait time.Duration, state discogs.RateLimitState) {
+		controller.Events.Publish(SyncEvent{
+			Type:        SyncEventThrottle,
+			WaitSeconds: wait.Seconds(),
+			Remaining:   &state.Remaining,
+		})
+	}
+	client := discogs.NewClient(os.Getenv("DISCOGS_BASE_URL"), controller.RateLimit)
+	client.Consumer = discogs.OAuthConsumer{
+		Key:    os.Getenv("DISCOGS_CONSUMER_KEY"),
+		Secret: os.Getenv("DISCOGS_CONSUMER_SECRET"),
+	}
+	controller.Discogs = client
+
+	return controller
 }
@@ -1,88 +1,55 @@
 package controller
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
+	"errors"
 	"kleio/internal/database"
+	"kleio/internal/discogs"
 	"log/slog"
-	"net/http"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
 )
 
+// GetReleaseDetails fetches the release resource and returns its metadata
+// and playable tracks
 func (c *Controller) GetReleaseDetails(
 	release database.Release,
-	token string,
-) ([]database.Track, error) {
-	resourceURL := release.ResourceURL + "?token=" + token
-
-	// Create a new request
-	req, err := http.NewRequest("GET", resourceURL, nil)
+	user database.User,
+) (database.ReleaseMetadata, []database.Track, error) {
+	releaseDetails, err := c.fetchRelease(release, user)
 	if err != nil {
-		slog.Error("Failed to create request", "error", err)
-		return nil, err
+		return database.ReleaseMetadata{}, nil, err
 	}
 
-	req.Header.Set("User-Agent", UserAgent)
-
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	return releaseMetadata(release.ID, releaseDetails), releaseTracks(release, releaseDetails), nil
+}
 
-	// Make the request
-	resp, err := client.Do(req)
+func (c *Controller) fetchRelease(release database.Release, user database.User) (discogs.ReleaseDetails, error) {
+	releaseDetails, err := c.Discogs.GetRelease(release.ID, user)
 	if err != nil {
-		slog.Error("Failed to make request", "error", err, "url", resourceURL)
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	c.RateLimit.UpdateLimits(resp)
-
-	// Check for rate limiting first
-	if resp.StatusCode == http.StatusTooManyRequests {
-		retryAfter := resp.Header.Get("Retry-After")
-		slog.Warn("Rate limited while fetching release details", 
-			"url", resourceURL, 
-			"retryAfter", retryAfter,
-			"releaseID", release.ID)
-		// Return error to allow caller to handle retry
-		return nil, fmt.Errorf("rate limited: retry after %s seconds", retryAfter)
-	}
+		var rateLimitErr *discogs.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			slog.Warn("Rate limited while fetching release details", 
+				"retryAfter", rateLimitErr.RetryAfter,
+				"releaseID", release.ID)
+			// The client has already retried, so this release is skipped until the next sync
+			return discogs.ReleaseDetails{}, err
+		}
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		slog.Error("API returned non-200 status for release details",
-			"status", resp.StatusCode,
-			"body", string(body),
-			"url", resourceURL,
+		slog.Error("Failed to fetch release details",
+			"error", err,
 			"releaseID", release.ID,
 			"releaseTitle", release.Title,
 		)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Decode the response
-	var releaseDetails struct {
-		ID        int                     `json:"id"`
-		Tracklist []database.DiscogsTrack `json:"tracklist"`
+		return discogs.ReleaseDetails{}, err
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&releaseDetails); err != nil {
-		slog.Error("Error decoding release details response", 
-			"error", err,
-			"url", resourceURL,
-			"releaseID", release.ID,
-			"releaseTitle", release.Title)
-		return nil, err
-	}
+	return releaseDetails, nil
+}
 
+// releaseTracks returns the playable tracks on a release resource
+func releaseTracks(release database.Release, releaseDetails discogs.ReleaseDetails) []database.Track {
 	var tracks []database.Track
 	slog.Info("Processing tracklist", 
 		"releaseID", release.ID,
@@ -107,6 +74,7 @@ func (c *Controller) GetReleaseDetails(
 			Title:           discTrack.Title,
 			DurationText:    discTrack.Duration,
 			DurationSeconds: durationSeconds,
+			Credits:         releaseCredits(release.ID, discTrack.ExtraArtists),
 		}
 
 		tracks = append(tracks, track)
@@ -116,7 +84,56 @@ func (c *Controller) GetReleaseDetails(
 		"releaseID", release.ID,
 		"validTracks", len(tracks))
 
-	return tracks, nil
+	return tracks
+}
+
+func releaseMetadata(releaseID int, details discogs.ReleaseDetails) database.ReleaseMetadata {
+	metadata := database.ReleaseMetadata{
+		Country:  details.Country,
+		Released: details.Released,
+		Credits:  releaseCredits(releaseID, details.ExtraArtists),
+	}
+	if details.MasterID > 0 {
+		metadata.MasterID = &details.MasterID
+	}
+
+	for _, identifier := range details.Identifiers {
+		metadata.Identifiers = append(metadata.Identifiers, database.ReleaseIdentifier{
+			ReleaseID:   releaseID,
+			Type:        identifier.Type,
+			Value:       identifier.Value,
+			Description: identifier.Description,
+		})
+	}
+
+	for _, company := range details.Companies {
+		metadata.Companies = append(metadata.Companies, database.ReleaseCompany{
+			ReleaseID:      releaseID,
+			CompanyID:      company.ID,
+			Name:           company.Name,
+			EntityTypeName: company.EntityTypeName,
+			CatNo:          company.CatNo,
+			ResourceURL:    company.ResourceURL,
+		})
+	}
+
+	return metadata
+}
+
+func releaseCredits(releaseID int, extraArtists []database.DiscogsCredit) []database.ReleaseCredit {
+	var credits []database.ReleaseCredit
+	for _, artist := range extraArtists {
+		credits = append(credits, database.ReleaseCredit{
+			ReleaseID: releaseID,
+			ArtistID:  artist.ID,
+			Name:      artist.Name,
+			ANV:       artist.ANV,
+			Role:      artist.Role,
+			Tracks:    artist.Tracks,
+		})
+	}
+
+	return credits
 }
 
 func (c *Controller) calculateTrackDurations(
@@ -133,7 +150,7 @@ func (c *Controller) calculateTrackDurations(
 	// If we don't have valid durations, estimate based on format
 	if totalDurationSeconds == 0 {
 		// Get release to check formats
-		release, err := c.DB.GetReleaseByID(releaseID)
+		release, err := c.Releases.GetReleaseByID(releaseID)
 		if err != nil {
 			slog.Error(
 				"Failed to get release for duration estimation",
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"errors"
+	"kleio/internal/database"
+	"kleio/internal/discogs"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// WriteBackInterval is how often the queue is checked for writes that
+	// are due again after a failure
+	WriteBackInterval = 30 * time.Second
+
+	// MaxWriteBackAttempts is how many times a write is tried before it is
+	// given up on and the next sync restores the Discogs values
+	MaxWriteBackAttempts = 8
+
+	writeBackBaseDelay = time.Minute
+	writeBackMaxDelay  = time.Hour
+	writeHistoryLimit  = 100
+)
+
+// UpdateReleaseRating rates a release and queues the rating for Discogs
+func (c *Controller) UpdateReleaseRating(releaseID, rating int) (payload Payload, err error) {
+	if err = c.Releases.UpdateReleaseRating(releaseID, rating); err != nil {
+		return payload, err
+	}
+	c.signalWriteBack()
+
+	err = payload.GetPayload(c)
+	if err != nil {
+		slog.Error("Failed to get payload after rating release", "error", err)
+	}
+
+	return payload, err
+}
+
+// MoveRelease moves a release to another folder and queues the move for Discogs
+func (c *Controller) MoveRelease(releaseID, folderID int) (payload Payload, err error) {
+	if err = c.Releases.MoveRelease(releaseID, folderID); err != nil {
+		return payload, err
+	}
+	c.signalWriteBack()
+
+	err = payload.GetPayload(c)
+	if err != nil {
+		slog.Error("Failed to get payload after moving release", "error", err)
+	}
+
+	return payload, err
+}
+
+func (c *Controller) GetDiscogsWrites() ([]database.DiscogsWrite, error) {
+	return c.Writes.GetDiscogsWrites(writeHistoryLimit)
+}
+
+func (c *Controller) signalWriteBack() {
+	select {
+	case c.writeBackSignal <- struct{}{}:
+	default:
+	}
+}
+
+// RunWriteBackQueue pushes queued writes to Discogs as they are made and
+// retries failed ones once their backoff has passed. It never returns.
+func (c *Controller) RunWriteBackQueue() {
+	ticker := time.NewTicker(WriteBackInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.PushDiscogsWrites(); err != nil {
+			slog.Error("Failed to push Discogs writes", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-c.writeBackSignal:
+		}
+	}
+}
+
+// PushDiscogsWrites sends every due write to Discogs. Writes that fail are
+// retried with exponential backoff, apart from ones Discogs rejects outright.
+func (c *Controller) PushDiscogsWrites() error {
+	c.writeBackMutex.Lock()
+	defer c.writeBackMutex.Unlock()
+
+	writes, err := c.Writes.GetDueDiscogsWrites()
+	if err != nil {
+		return err
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	user, err := c.Auth.GetUser()
+	if err != nil {
+		slog.Error("Failed to get user", "error", err)
+		return err
+	}
+	if !user.HasCredentials() {
+		return nil
+	}
+
+	for _, write := range writes {
+		update := discogs.InstanceUpdate{Rating: write.Rating, FolderID: write.FolderID}
+		err := c.Discogs.UpdateInstance(user, write.FromFolderID, write.ReleaseID, write.InstanceID, update)
+		if err == nil {
+			slog.Info("Pushed change to Discogs", "writeID", write.ID, "releaseID", write.ReleaseID)
+			if err := c.Writes.CompleteDiscogsWrite(write); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if isPermanentWriteError(err) || write.Attempts+1 >= MaxWriteBackAttempts {
+			slog.Error("Giving up on Discogs write",
+				"error", err,
+				"writeID", write.ID,
+				"releaseID", write.ReleaseID,
+				"attempts", write.Attempts+1)
+			if err := c.Writes.FailDiscogsWrite(write, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		delay := writeBackDelay(write.Attempts)
+		slog.Warn("Failed to push change to Discogs, will retry",
+			"error", err,
+			"writeID", write.ID,
+			"releaseID", write.ReleaseID,
+			"retryIn", delay)
+		if err := c.Writes.RetryDiscogsWrite(write, err.Error(), int(delay.Seconds())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isPermanentWriteError reports whether Discogs refused the write itself, as
+// opposed to being unreachable or overloaded
+func isPermanentWriteError(err error) bool {
+	var statusErr *discogs.StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+
+	return statusErr.StatusCode >= http.StatusBadRequest &&
+		statusErr.StatusCode < http.StatusInternalServerError &&
+		statusErr.StatusCode != http.StatusTooManyRequests
+}
+
+func writeBackDelay(attempts int) time.Duration {
+	delay := writeBackBaseDelay
+	for range attempts {
+		delay *= 2
+		if delay >= writeBackMaxDelay {
+			return writeBackMaxDelay
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"kleio/internal/database"
+	"kleio/internal/discogs"
+	"kleio/internal/discogs/fake"
+	"testing"
+)
+
+// pushHookClient runs beforeUpdate once, when the next instance update is
+// about to be sent
+type pushHookClient struct {
+	DiscogsClient
+	beforeUpdate func()
+}
+
+func (c *pushHookClient) UpdateInstance(
+	user database.User,
+	folderID, releaseID, instanceID int,
+	update discogs.InstanceUpdate,
+) error {
+	if hook := c.beforeUpdate; hook != nil {
+		c.beforeUpdate = nil
+		hook()
+	}
+
+	return c.DiscogsClient.UpdateInstance(user, folderID, releaseID, instanceID, update)
+}
+
+func TestPushDiscogsWritesWithChangeMergedDuringPush(t *testing.T) {
+	server, err := fake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.RateLimit = 600
+	controller, db := newFakeDiscogsController(t, server)
+	runTestSync(t, controller, database.SyncModeFull, "test_sync_1")
+
+	// Rumours (1003) is in folder 2. It is moved, and rated while the move
+	// is on its way to Discogs.
+	client := &pushHookClient{DiscogsClient: controller.Discogs}
+	client.beforeUpdate = func() {
+		if err := db.UpdateReleaseRating(1003, 5); err != nil {
+			t.Errorf("failed to rate release: %v", err)
+		}
+	}
+	controller.Discogs = client
+
+	if err := db.MoveRelease(1003, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := controller.PushDiscogsWrites(); err != nil {
+		t.Fatal(err)
+	}
+
+	writes, err := db.GetDiscogsWrites(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(writes) != 1 {
+		t.Fatalf("got %d writes, want 1", len(writes))
+	}
+	write := writes[0]
+	if write.Status != database.DiscogsWritePending {
+		t.Fatalf("write status = %s, want pending for the merged rating", write.Status)
+	}
+	if write.FromFolderID != 3 || write.FolderID != nil {
+		t.Errorf("write moves from %d to %v, want it already in 3 and not moving", write.FromFolderID, write.FolderID)
+	}
+	if write.Rating == nil || *write.Rating != 5 {
+		t.Errorf("write rating = %v, want 5", write.Rating)
+	}
+
+	// The rating goes out from the new folder, rather than 404ing against
+	// the old one and being given up on
+	if err := controller.PushDiscogsWrites(); err != nil {
+		t.Fatal(err)
+	}
+	writes, err = db.GetDiscogsWrites(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writes[0].Status != database.DiscogsWriteDone {
+		t.Fatalf("write status = %s (%v), want done", writes[0].Status, writes[0].LastError)
+	}
+
+	// Discogs has both changes, so syncing back finds nothing to change
+	job := runTestSync(t, controller, database.SyncModeFull, "test_sync_2")
+	changes, err := db.GetSyncChanges(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("sync after the push recorded changes: %+v", changes)
+	}
+
+	release, err := db.GetRelease(1003)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.FolderID != 3 || release.Rating != 5 {
+		t.Errorf("release in folder %d rated %d, want folder 3 rated 5", release.FolderID, release.Rating)
+	}
+}
@@ -29,21 +29,21 @@ type ExportData struct {
 
 func (c *Controller) ExportHistory() (ExportData, error) {
 	// Get all play history
-	playHistory, err := c.DB.GetAllPlayHistory()
+	playHistory, err := c.Plays.GetAllPlayHistory()
 	if err != nil {
 		slog.Error("Failed to get play history for export", "error", err)
 		return ExportData{}, err
 	}
 
 	// Get all cleaning history
-	cleaningHistory, err := c.DB.GetAllCleaningHistory()
+	cleaningHistory, err := c.Cleanings.GetAllCleaningHistory()
 	if err != nil {
 		slog.Error("Failed to get cleaning history for export", "error", err)
 		return ExportData{}, err
 	}
 
 	// Get all styluses (owned only, not base models)
-	styluses, err := c.DB.GetStyluses()
+	styluses, err := c.Styluses.GetStyluses()
 	if err != nil {
 		slog.Error("Failed to get styluses for export", "error", err)
 		return ExportData{}, err
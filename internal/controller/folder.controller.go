@@ -1,22 +1,22 @@
 package controller
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	. "kleio/internal/database"
+	"kleio/internal/discogs"
 	"log/slog"
-	"net/http"
-	"time"
 )
 
-func (c *Controller) SyncFolders() error {
+// SyncFolders brings the stored folders in line with Discogs and returns what
+// changed, recorded against syncID
+func (c *Controller) SyncFolders(syncID int64) ([]FolderChange, error) {
 	slog.Info("Starting folder sync")
 
-	user, err := c.DB.GetUser()
+	user, err := c.Auth.GetUser()
 	if err != nil {
 		slog.Error("Failed to get user", "error", err)
-		return err
+		return nil, err
 	}
 
 	slog.Debug("Retrieved user for folder sync", "username", user.Username)
@@ -24,80 +24,43 @@ func (c *Controller) SyncFolders() error {
 	folders, err := c.getDiscogFolders(user)
 	if err != nil {
 		slog.Error("Failed to get user folders from Discogs API", "error", err)
-		return err
+		return nil, err
 	}
 
 	slog.Info("Retrieved folders from Discogs", "folderCount", len(folders))
 
-	err = c.updateFolders(folders)
+	changes, err := c.updateFolders(syncID, folders)
 	if err != nil {
 		slog.Error("Failed to update folders in database", "error", err, "folderCount", len(folders))
-		return err
+		return nil, err
 	}
 
 	slog.Info("Folder sync completed successfully", "folderCount", len(folders))
-	return nil
+	return changes, nil
 }
 
 func (c *Controller) getDiscogFolders(user User) ([]Folder, error) {
-	url := fmt.Sprintf(
-		"%s/users/%s/collection/folders?token=%s",
-		BaseURL,
-		user.Username,
-		user.Token,
-	)
-
-	slog.Debug("Making API request for folders", "url", url, "username", user.Username)
+	slog.Debug("Making API request for folders", "username", user.Username)
 
-	req, err := http.NewRequest("GET", url, nil)
+	folders, err := c.Discogs.GetFolders(user)
 	if err != nil {
-		slog.Error("Failed to create request", "error", err)
-		return nil, err
-	}
-
-	req.Header.Set("User-Agent", UserAgent)
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		slog.Error("Failed to make request", "error", err)
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// Check for rate limiting
-	if resp.StatusCode == http.StatusTooManyRequests {
-		retryAfter := resp.Header.Get("Retry-After")
-		slog.Warn("Rate limited while fetching folders", 
-			"retryAfter", retryAfter,
-			"username", user.Username)
-		return nil, fmt.Errorf("rate limited while fetching folders: retry after %s seconds", retryAfter)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		slog.Error("API returned non-200 status for folders", 
-			"status", resp.StatusCode, 
-			"body", string(body),
-			"username", user.Username,
-			"url", url)
-		return nil, fmt.Errorf("API returned non-200 status: %d", resp.StatusCode)
-	}
+		var rateLimitErr *discogs.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			slog.Warn("Rate limited while fetching folders", 
+				"retryAfter", rateLimitErr.RetryAfter,
+				"username", user.Username)
+			return nil, fmt.Errorf("rate limited while fetching folders: %w", err)
+		}
 
-	var foldersResp FoldersResponse
-	if err := json.NewDecoder(resp.Body).Decode(&foldersResp); err != nil {
-		slog.Error("Error decoding response", "error", err)
+		slog.Error("Failed to get folders from Discogs", "error", err, "username", user.Username)
 		return nil, err
 	}
 
 	slog.Info("Successfully retrieved folders from API", 
-		"folderCount", len(foldersResp.Folders),
+		"folderCount", len(folders),
 		"username", user.Username)
 
-	for i, folder := range foldersResp.Folders {
+	for i, folder := range folders {
 		slog.Debug("Retrieved folder", 
 			"index", i,
 			"folderID", folder.ID,
@@ -105,40 +68,36 @@ func (c *Controller) getDiscogFolders(user User) ([]Folder, error) {
 			"itemCount", folder.Count)
 	}
 
-	return foldersResp.Folders, nil
+	return folders, nil
 }
 
-func (c *Controller) updateFolders(folders []Folder) error {
-	updated := 0
-	failed := 0
+func (c *Controller) updateFolders(syncID int64, folders []Folder) ([]FolderChange, error) {
+	changes, err := c.Folders.ReconcileFolders(syncID, folders)
+	if err != nil {
+		return nil, err
+	}
 
-	for i, folder := range folders {
-		slog.Debug("Updating folder", 
-			"index", i,
-			"folderID", folder.ID,
-			"folderName", folder.Name)
-
-		if err := c.DB.UpdateFolder(folder); err != nil {
-			slog.Error("Failed to update folder", 
-				"error", err,
-				"folderID", folder.ID,
-				"folderName", folder.Name)
-			failed++
-			continue // Continue with other folders
-		}
-		updated++
+	for _, change := range changes {
+		slog.Info("Folder changed on Discogs",
+			"folderID", change.FolderID,
+			"change", change.Type,
+			"oldName", change.OldName,
+			"newName", change.NewName)
 	}
 
 	slog.Info("Folder update completed", 
 		"totalFolders", len(folders),
-		"updated", updated,
-		"failed", failed)
+		"changes", len(changes))
 
-	if failed > 0 && updated == 0 {
-		return fmt.Errorf("failed to update any folders: %d failures", failed)
-	}
+	return changes, nil
+}
 
-	return nil
+func (c *Controller) GetFolderChanges(syncID int64) ([]FolderChange, error) {
+	changes, err := c.Syncs.GetFolderChanges(syncID)
+	if err != nil {
+		slog.Error("Failed to get folder changes", "error", err, "syncID", syncID)
+		return nil, err
+	}
 
-	// TODO: Check if any folders have been deleted
+	return changes, nil
 }
@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"kleio/internal/database"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const (
+	// ImageCacheInterval is how often the image cache looks for artwork it
+	// hasn't downloaded, besides straight after each sync
+	ImageCacheInterval = 15 * time.Minute
+	// ImageFetchDelay spaces out downloads so a first sync doesn't hammer the
+	// Discogs image servers
+	ImageFetchDelay = 250 * time.Millisecond
+
+	imageCacheBatch   = 50
+	imageRetryBackoff = 10 * time.Minute
+	maxImageBackoff   = 24 * time.Hour
+)
+
+// RunImageCache downloads release artwork into the local image store in the
+// background. It never returns.
+func (c *Controller) RunImageCache() {
+	ticker := time.NewTicker(ImageCacheInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.CacheImages(); err != nil {
+			slog.Error("Failed to cache images", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-c.imageSignal:
+		}
+	}
+}
+
+// signalImageCache wakes the image cache, for example after a sync brought in
+// new releases
+func (c *Controller) signalImageCache() {
+	select {
+	case c.imageSignal <- struct{}{}:
+	default:
+	}
+}
+
+// CacheImages downloads every image that is new or whose URL has changed,
+// then sweeps images nothing uses any more from the store. Failed downloads
+// are retried with a growing delay.
+func (c *Controller) CacheImages() error {
+	cached := 0
+	for {
+		pending, err := c.ReleaseImages.GetImagesToCache(imageCacheBatch)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		for _, image := range pending {
+			if err := c.cacheImage(image); err != nil {
+				delay := imageBackoff(image.Attempts)
+				slog.Warn("Failed to cache image",
+					"error", err,
+					"releaseID", image.ReleaseID,
+					"kind", image.Kind,
+					"retryIn", delay)
+				if err := c.ReleaseImages.RetryReleaseImage(image, err.Error(), int(delay.Seconds())); err != nil {
+					return err
+				}
+			} else {
+				cached++
+			}
+
+			time.Sleep(ImageFetchDelay)
+		}
+	}
+
+	if cached > 0 {
+		slog.Info("Cached release images", "count", cached)
+	}
+	return c.SweepImages()
+}
+
+// SweepImages deletes the artwork of archived and deleted releases, and
+// every stored file no release image refers to, such as the old copy of a
+// cover whose URL changed. It runs between downloads, so a file being
+// stored is always referenced before the sweep looks at it.
+func (c *Controller) SweepImages() error {
+	stale, err := c.ReleaseImages.DeleteStaleReleaseImages()
+	if err != nil {
+		return err
+	}
+
+	hashes, err := c.ReleaseImages.GetReleaseImageHashes()
+	if err != nil {
+		return err
+	}
+
+	removed, err := c.Images.Sweep(hashes)
+	if err != nil {
+		slog.Error("Failed to sweep image store", "error", err)
+		return err
+	}
+
+	if stale > 0 || removed > 0 {
+		slog.Info("Swept unused images", "releaseImages", stale, "files", removed)
+	}
+	return nil
+}
+
+func (c *Controller) cacheImage(image database.ReleaseImage) error {
+	downloaded, err := c.Discogs.DownloadImage(image.SourceURL)
+	if err != nil {
+		return err
+	}
+
+	hash, err := c.Images.Put(downloaded.Data)
+	if err != nil {
+		return err
+	}
+
+	image.Hash = hash
+	image.ContentType = downloaded.ContentType
+	image.Size = int64(len(downloaded.Data))
+	return c.ReleaseImages.SaveReleaseImage(image)
+}
+
+// imageBackoff doubles from imageRetryBackoff with each failed attempt, up to
+// maxImageBackoff
+func imageBackoff(attempts int) time.Duration {
+	if attempts > 10 {
+		return maxImageBackoff
+	}
+	return min(imageRetryBackoff<<attempts, maxImageBackoff)
+}
+
+// ErrImageNotCached is returned by OpenReleaseImage when only the remote copy
+// is available, in Image.SourceURL
+var ErrImageNotCached = errors.New("image not cached")
+
+// OpenReleaseImage returns a release's cached artwork and an open file to
+// serve it from, which the caller closes. It returns sql.ErrNoRows when the
+// release doesn't exist and ErrImageNotCached when there is no local copy.
+func (c *Controller) OpenReleaseImage(releaseID int, kind database.ImageKind) (database.ReleaseImage, *os.File, error) {
+	image, err := c.ReleaseImages.GetReleaseImage(releaseID, kind)
+	if err != nil {
+		return database.ReleaseImage{}, nil, err
+	}
+
+	if image.Hash == "" {
+		return image, nil, ErrImageNotCached
+	}
+
+	file, err := c.Images.Open(image.Hash)
+	if err != nil {
+		// The file went missing from the store, so have it downloaded again
+		slog.Warn("Cached image is missing", "error", err, "releaseID", releaseID, "kind", kind, "hash", image.Hash)
+		if image.SourceURL != "" && c.ReleaseImages.RetryReleaseImage(image, "cached file missing", 0) == nil {
+			c.signalImageCache()
+		}
+		return image, nil, fmt.Errorf("%w: %v", ErrImageNotCached, err)
+	}
+
+	return image, file, nil
+}
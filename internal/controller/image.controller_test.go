@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"kleio/internal/database"
+	"kleio/internal/discogs/fake"
+	"os"
+	"testing"
+)
+
+// storeTestImage stores data as a release's cached image, without going to
+// the image CDN
+func storeTestImage(t *testing.T, controller *Controller, releaseID int, kind database.ImageKind, data string) string {
+	t.Helper()
+
+	hash, err := controller.Images.Put([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.ReleaseImages.SaveReleaseImage(database.ReleaseImage{
+		ReleaseID:   releaseID,
+		Kind:        kind,
+		SourceURL:   "https://i.discogs.test/" + data,
+		Hash:        hash,
+		ContentType: "image/png",
+		Size:        int64(len(data)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hash
+}
+
+func imageStored(t *testing.T, controller *Controller, hash string) bool {
+	t.Helper()
+
+	path, err := controller.Images.Path(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(path)
+
+	return err == nil
+}
+
+func TestSweepImagesRemovesUnusedFiles(t *testing.T) {
+	server, err := fake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.RateLimit = 600
+	controller, _ := newFakeDiscogsController(t, server)
+	runTestSync(t, controller, database.SyncModeFull, "test_sync_1")
+
+	kept := storeTestImage(t, controller, 1001, database.ImageThumb, "thumb 1001")
+	shared := storeTestImage(t, controller, 1002, database.ImageThumb, "shared")
+	storeTestImage(t, controller, 1002, database.ImageCover, "shared")
+	replaced := storeTestImage(t, controller, 1001, database.ImageCover, "old cover 1001")
+	current := storeTestImage(t, controller, 1001, database.ImageCover, "new cover 1001")
+	removed := storeTestImage(t, controller, 1003, database.ImageThumb, "thumb 1003")
+
+	// Rumours (1003) leaves the collection and is archived
+	if !server.RemoveRelease(5003) {
+		t.Fatal("fixture has no instance 5003")
+	}
+	runTestSync(t, controller, database.SyncModeFull, "test_sync_2")
+
+	if err := controller.SweepImages(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, hash := range map[string]string{"kept": kept, "shared": shared, "current": current} {
+		if !imageStored(t, controller, hash) {
+			t.Errorf("%s image was swept", name)
+		}
+	}
+	for name, hash := range map[string]string{"replaced": replaced, "archived": removed} {
+		if imageStored(t, controller, hash) {
+			t.Errorf("%s image is still stored", name)
+		}
+	}
+
+	image, err := controller.ReleaseImages.GetReleaseImage(1003, database.ImageThumb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image.Hash != "" {
+		t.Errorf("archived release still has cached image %s", image.Hash)
+	}
+}
@@ -0,0 +1,12 @@
+package controller
+
+import "kleio/internal/database"
+
+// GetMasters lists masters with at least minPressings owned pressings
+func (c *Controller) GetMasters(minPressings int, withStats bool) ([]database.Master, error) {
+	return c.Releases.GetMasters(minPressings, withStats)
+}
+
+func (c *Controller) GetMaster(id int, withStats bool) (database.Master, error) {
+	return c.Releases.GetMaster(id, withStats)
+}
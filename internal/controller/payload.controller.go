@@ -9,54 +9,58 @@ import (
 
 type Payload struct {
 	LastSync    time.Time              `json:"lastSync,omitzero"`
+	NextSync    *time.Time             `json:"nextSync,omitempty"`
 	SyncingData bool                   `json:"syncingData"`
 	Releases    []database.Release     `json:"releases"`
 	Stylus      []database.Stylus      `json:"stylus"`
 	PlayHistory []database.PlayHistory `json:"playHistory"`
 	Folders     []database.Folder      `json:"folders"`
 	Token       string                 `json:"token"`
+	AuthMethod  string                 `json:"authMethod,omitempty"` // "token" or "oauth"
 }
 
+// GetLastSync reports when the collection was last synced and when the next
+// scheduled sync is due. Syncs are started by the scheduler, never by reads.
 func (p *Payload) GetLastSync(controller *Controller) error {
-	lastSync, err := controller.DB.GetLatestSync()
+	lastSync, err := controller.Syncs.GetLatestSync()
 	if err != nil {
 		slog.Error("Failed to get last sync", "error", err)
 		return err
 	}
 
-	if lastSync.Status != "complete" {
-		slog.Error("Last sync failed, re-syncing", "error", err)
-		err := controller.DB.CompleteSync(lastSync.ID, false)
-		if err != nil {
-			slog.Error("Failed to complete sync", "error", err)
-		}
-		go controller.AsyncCollection()
-		return nil
+	p.SyncingData = lastSync.Status == "in_progress"
+	if lastSync.Status == "complete" {
+		p.LastSync = lastSync.SyncStart
 	}
 
-	p.LastSync = lastSync.SyncStart
-
-	expectedFolderSync := time.Now().Add(-12 * time.Hour)
-	// expectedFolderSync := time.Now()
-	if lastSync.SyncStart.Before(expectedFolderSync) {
-		slog.Info("Last synced is older than 12 hours, updating folders...")
-		go controller.AsyncCollection()
-		p.SyncingData = true
+	p.NextSync, err = controller.nextScheduledSync(time.Now())
+	if err != nil {
+		slog.Error("Failed to get next scheduled sync", "error", err)
+		return err
 	}
 
 	return nil
 }
 
 func (p *Payload) GetPayload(controller *Controller) (err error) {
-	p.Releases, err = controller.DB.GetAllReleases()
+	// Archived releases are hidden from the collection but their plays still
+	// count towards history and analytics
+	releases, err := controller.Releases.GetAllReleasesWithArchived()
 	if err != nil {
 		slog.Error("Failed to get releases", "error", err)
 		return err
 	}
 
-	p.GetPlayHistory(controller)
+	p.GetPlayHistory(releases)
+
+	p.Releases = make([]database.Release, 0, len(releases))
+	for _, release := range releases {
+		if !release.Archived {
+			p.Releases = append(p.Releases, release)
+		}
+	}
 
-	p.Stylus, err = controller.DB.GetStyluses()
+	p.Stylus, err = controller.Styluses.GetStyluses()
 	if err != nil {
 		slog.Error("Failed to get stylus", "error", err)
 		return err
@@ -78,7 +82,7 @@ func (p *Payload) GetPayload(controller *Controller) (err error) {
 }
 
 func (p *Payload) GetFolders(controller *Controller) (err error) {
-	p.Folders, err = controller.DB.GetFolders()
+	p.Folders, err = controller.Folders.GetFolders()
 	if err != nil {
 		slog.Error("Failed to get folders", "error", err)
 		return err
@@ -87,9 +91,9 @@ func (p *Payload) GetFolders(controller *Controller) (err error) {
 	return nil
 }
 
-func (p *Payload) GetPlayHistory(controller *Controller) {
+func (p *Payload) GetPlayHistory(releases []database.Release) {
 	var playHistory []database.PlayHistory
-	for _, release := range p.Releases {
+	for _, release := range releases {
 		for _, history := range release.PlayHistory {
 			history.Release = release
 			playHistory = append(playHistory, history)
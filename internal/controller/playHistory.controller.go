@@ -11,7 +11,7 @@ func (c *Controller) CreatePlayHistory(history *database.PlayHistory) (payload P
 		history.PlayedAt = time.Now()
 	}
 
-	err = c.DB.CreatePlayHistory(history)
+	err = c.Plays.CreatePlayHistory(history)
 	if err != nil {
 		slog.Error("Failed to create play history", "error", err)
 		return
@@ -26,7 +26,7 @@ func (c *Controller) CreatePlayHistory(history *database.PlayHistory) (payload P
 }
 
 func (c *Controller) UpdatePlayHistory(history *database.PlayHistory) (payload Payload, err error) {
-	err = c.DB.UpdatePlayHistory(history)
+	err = c.Plays.UpdatePlayHistory(history)
 	if err != nil {
 		slog.Error("Failed to update play history", "error", err)
 		return
@@ -41,7 +41,7 @@ func (c *Controller) UpdatePlayHistory(history *database.PlayHistory) (payload P
 }
 
 func (c *Controller) DeletePlayHistory(id int) (payload Payload, err error) {
-	err = c.DB.DeletePlayHistory(id)
+	err = c.Plays.DeletePlayHistory(id)
 	if err != nil {
 		slog.Error("Failed to delete play history", "error", err)
 		return
@@ -56,7 +56,7 @@ func (c *Controller) DeletePlayHistory(id int) (payload Payload, err error) {
 }
 
 func (c *Controller) GetPlayCountByRelease() (map[int]int, error) {
-	playCounts, err := c.DB.GetPlayCountByRelease()
+	playCounts, err := c.Plays.GetPlayCountByRelease()
 	if err != nil {
 		slog.Error("Failed to get play counts", "error", err)
 		return nil, err
@@ -65,8 +65,18 @@ func (c *Controller) GetPlayCountByRelease() (map[int]int, error) {
 	return playCounts, nil
 }
 
+func (c *Controller) GetPlayCountByMaster() (map[int]int, error) {
+	playCounts, err := c.Plays.GetPlayCountByMaster()
+	if err != nil {
+		slog.Error("Failed to get play counts by master", "error", err)
+		return nil, err
+	}
+
+	return playCounts, nil
+}
+
 func (c *Controller) GetRecentPlays(limit int) ([]database.PlayHistory, error) {
-	plays, err := c.DB.GetRecentPlays(limit)
+	plays, err := c.Plays.GetRecentPlays(limit)
 	if err != nil {
 		slog.Error("Failed to get recent plays", "error", err)
 		return nil, err
@@ -76,7 +86,7 @@ func (c *Controller) GetRecentPlays(limit int) ([]database.PlayHistory, error) {
 }
 
 func (c *Controller) GetPlaysByTimeRange(start, end time.Time) ([]database.PlayHistory, error) {
-	plays, err := c.DB.GetPlaysByTimeRange(start, end)
+	plays, err := c.Plays.GetPlaysByTimeRange(start, end)
 	if err != nil {
 		slog.Error("Failed to get plays by time range", "error", err)
 		return nil, err
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"kleio/internal/database"
+	"log/slog"
+	"time"
+)
+
+const (
+	// EstimatedDurationRefreshInterval is how long a release whose duration
+	// had to be estimated is left before it is fetched again, in case its
+	// tracklist has gained durations on Discogs
+	EstimatedDurationRefreshInterval = 30 * 24 * time.Hour
+
+	// estimatedDurationRefreshBatch caps the releases refreshed per sync, so a
+	// large collection is worked through over several syncs
+	estimatedDurationRefreshBatch = 25
+)
+
+// RefreshRelease fetches a release from Discogs again and replaces its
+// summary, details and tracks, recalculating its duration. It doesn't run
+// alongside a sync and returns ErrSyncInProgress instead.
+func (c *Controller) RefreshRelease(releaseID int) (database.Release, error) {
+	if !c.syncMutex.TryLock() {
+		return database.Release{}, ErrSyncInProgress
+	}
+	defer c.syncMutex.Unlock()
+
+	user, err := c.Auth.GetUser()
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Release{}, ErrNotSignedIn
+		}
+		slog.Error("Failed to get user", "error", err)
+		return database.Release{}, err
+	}
+
+	if err := c.refreshRelease(releaseID, user); err != nil {
+		return database.Release{}, err
+	}
+
+	// The artwork may have moved
+	c.signalImageCache()
+
+	return c.Releases.GetRelease(releaseID)
+}
+
+func (c *Controller) refreshRelease(releaseID int, user database.User) error {
+	release, err := c.Releases.GetRelease(releaseID)
+	if err != nil {
+		return err
+	}
+
+	details, err := c.fetchRelease(release, user)
+	if err != nil {
+		return err
+	}
+
+	// The summary goes first as an estimated duration is worked out from the
+	// formats in it
+	info := details.DiscogsBasicInfo
+	info.CoverImage = details.Cover()
+	if err := c.Releases.SaveReleaseBasicInfo(release.ID, info); err != nil {
+		slog.Error("Failed to save refreshed release", "error", err, "releaseID", release.ID)
+		return err
+	}
+
+	err = c.saveReleaseTracks(release, releaseMetadata(release.ID, details), releaseTracks(release, details))
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Refreshed release", "releaseID", release.ID, "title", info.Title)
+	return nil
+}
+
+// refreshEstimatedDurations refreshes the releases whose estimated duration
+// is due another look. It runs at the end of a sync, which holds the sync lock.
+func (c *Controller) refreshEstimatedDurations() error {
+	releaseIDs, err := c.Releases.GetReleasesDueForRefresh(
+		EstimatedDurationRefreshInterval,
+		estimatedDurationRefreshBatch,
+	)
+	if err != nil {
+		return err
+	}
+	if len(releaseIDs) == 0 {
+		return nil
+	}
+
+	user, err := c.Auth.GetUser()
+	if err != nil {
+		slog.Error("Failed to get user", "error", err)
+		return err
+	}
+
+	slog.Info("Refreshing releases with estimated durations", "releases", len(releaseIDs))
+
+	failed := 0
+	for _, releaseID := range releaseIDs {
+		if err := c.refreshRelease(releaseID, user); err != nil {
+			slog.Warn("Failed to refresh release", "error", err, "releaseID", releaseID)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to refresh %d of %d releases", failed, len(releaseIDs))
+	}
+
+	return nil
+}
@@ -1,38 +1,43 @@
 package controller
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"kleio/internal/database"
 	. "kleio/internal/database"
+	"kleio/internal/discogs"
 	"log/slog"
-	"net/http"
 	"time"
 )
 
-func (c *Controller) SyncReleases() error {
-	user, err := c.DB.GetUser()
+// SyncReleases fetches every page of every folder, resuming from the folder
+// and page in the job's checkpoint, then archives releases Discogs no longer has
+func (c *Controller) SyncReleases(job *Sync) error {
+	user, err := c.Auth.GetUser()
 	if err != nil {
 		slog.Error("Failed to get user from database", "error", err)
 		return err
 	}
 
-	folders, err := c.DB.GetFolders()
+	folders, err := c.Folders.GetFolders()
 	if err != nil {
 		slog.Error("Failed to get user folders from database", "error", err)
 		return err
 	}
 
+	sessionID := job.SessionID
+	startFolder := resumeFolderIndex(folders, job)
+
 	slog.Info("Starting release sync", 
 		"username", user.Username,
-		"folderCount", len(folders))
+		"folderCount", len(folders),
+		"sessionID", sessionID,
+		"startFolder", startFolder,
+		"startPage", job.Page)
 
-	totalReleases := 0
 	totalPages := 0
-	failedFolders := 0
 
-	for folderIdx, folder := range folders {
+	for folderIdx := startFolder; folderIdx < len(folders); folderIdx++ {
+		folder := folders[folderIdx]
 		slog.Info("Syncing folder", 
 			"folderID", folder.ID, 
 			"folderName", folder.Name,
@@ -42,20 +47,24 @@ func (c *Controller) SyncReleases() error {
 		page := 1
 		perPage := 100
 
+		if job.FolderID != nil && *job.FolderID == folder.ID {
+			page = max(job.Page, 1)
+		}
+
 		for {
 			slog.Debug("Fetching releases page", 
 				"folderID", folder.ID,
 				"page", page,
 				"perPage", perPage)
 
-			response, err := fetchReleasesPage(user, folder.ID, page, perPage)
+			response, err := c.fetchReleasesPage(user, folder.ID, page, perPage, nil)
 			if err != nil {
 				slog.Error("Failed to fetch releases page", 
 					"error", err,
 					"folderID", folder.ID,
 					"folderName", folder.Name,
 					"page", page)
-				failedFolders++
+				job.FailedFolders++
 				break // Move to next folder instead of failing completely
 			}
 
@@ -66,7 +75,7 @@ func (c *Controller) SyncReleases() error {
 				break
 			}
 
-			err = c.DB.SaveReleases(response)
+			err = c.Releases.SaveReleases(response, job.ID, sessionID)
 			if err != nil {
 				slog.Error("Failed to save releases", 
 					"error", err,
@@ -77,7 +86,6 @@ func (c *Controller) SyncReleases() error {
 			}
 
 			folderReleases += len(response.Releases)
-			totalReleases += len(response.Releases)
 			totalPages++
 
 			slog.Debug("Saved releases page", 
@@ -88,11 +96,36 @@ func (c *Controller) SyncReleases() error {
 
 			page++
 
+			job.FolderID = &folder.ID
+			job.Page = page
+			job.ReleasesProcessed += len(response.Releases)
+			if err := c.Syncs.SaveSyncCheckpoint(*job); err != nil {
+				return err
+			}
+
+			c.Events.Publish(SyncEvent{
+				Type:       SyncEventProgress,
+				SyncID:     job.ID,
+				Phase:      SyncPhaseReleases,
+				FolderID:   &folder.ID,
+				FolderName: folder.Name,
+				Page:       page - 1,
+				Pages:      response.Pagination.Pages,
+				Processed:  job.ReleasesProcessed,
+			})
+
 			if page > response.Pagination.Pages {
 				break
 			}
+		}
 
-			time.Sleep(1 * time.Second)
+		// Point the checkpoint at the start of the next folder
+		if folderIdx+1 < len(folders) {
+			job.FolderID = &folders[folderIdx+1].ID
+			job.Page = 1
+			if err := c.Syncs.SaveSyncCheckpoint(*job); err != nil {
+				return err
+			}
 		}
 
 		slog.Info("Completed folder sync", 
@@ -101,98 +134,167 @@ func (c *Controller) SyncReleases() error {
 			"releasesInFolder", folderReleases)
 	}
 
+	failedFolders := job.FailedFolders
+
 	slog.Info("Release sync completed", 
-		"totalReleases", totalReleases,
+		"totalReleases", job.ReleasesProcessed,
 		"totalPages", totalPages,
 		"successfulFolders", len(folders)-failedFolders,
 		"failedFolders", failedFolders)
 
-	if failedFolders > 0 && totalReleases == 0 {
+	if failedFolders > 0 && job.ReleasesProcessed == 0 {
 		return fmt.Errorf("failed to sync any folders: %d failures", failedFolders)
 	}
 
+	// Only archive when every folder was fetched, otherwise releases in a
+	// failed folder would look like they were removed from Discogs
+	if failedFolders > 0 {
+		slog.Warn("Skipping archive of removed releases due to failed folders",
+			"failedFolders", failedFolders,
+			"sessionID", sessionID)
+		return nil
+	}
+
+	archived, err := c.Releases.ArchiveStaleReleases(job.ID, sessionID, ArchiveReasonSyncRemoved)
+	if err != nil {
+		slog.Error("Failed to archive releases removed from Discogs", "error", err)
+		return err
+	}
+
+	slog.Info("Archived releases removed from Discogs", "archived", archived, "sessionID", sessionID)
 	return nil
 }
 
-func fetchReleasesPage(user database.User, folderID, page, perPage int) (DiscogsResponse, error) {
-	var response DiscogsResponse
+// resumeFolderIndex finds the folder a resumed job was working on. A folder
+// that has since gone away means starting over from the first one.
+func resumeFolderIndex(folders []Folder, job *Sync) int {
+	if job.FolderID == nil {
+		return 0
+	}
 
-	// Build the URL for the folder releases endpoint with pagination
-	url := fmt.Sprintf(
-		"%s/users/%s/collection/folders/%d/releases?token=%s&page=%d&per_page=%d",
-		BaseURL,
-		user.Username,
-		folderID,
-		user.Token,
-		page,
-		perPage,
-	)
+	for i, folder := range folders {
+		if folder.ID == *job.FolderID {
+			return i
+		}
+	}
 
-	slog.Debug("Making API request", "url", url)
+	slog.Warn("Checkpoint folder no longer exists, syncing all folders",
+		"folderID", *job.FolderID,
+		"syncID", job.ID)
+	job.FolderID = nil
+	job.Page = 1
+	return 0
+}
 
-	// Create a new request
-	req, err := http.NewRequest("GET", url, nil)
+// SyncReleasesIncremental fetches the "All" folder sorted by date added,
+// newest first, and stops at the first release that is already stored.
+// Removals and changes to existing releases are left to the full sync.
+func (c *Controller) SyncReleasesIncremental(job *Sync) error {
+	user, err := c.Auth.GetUser()
 	if err != nil {
-		slog.Error("Failed to create request", "error", err)
-		return response, err
+		slog.Error("Failed to get user from database", "error", err)
+		return err
 	}
 
-	// Set required User-Agent header
-	req.Header.Set("User-Agent", UserAgent)
+	sessionID := job.SessionID
 
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second, // Longer timeout for pagination requests
-	}
+	// Releases from earlier pages are stored by now, so a resumed job has to
+	// carry on from its checkpoint rather than stop at page 1
+	page := max(job.Page, 1)
+	perPage := 100
+	allFolderID := discogs.AllFolderID
 
-	// Make the request
-	resp, err := client.Do(req)
-	if err != nil {
-		slog.Error("Failed to make request", "error", err, "url", url)
-		return response, err
-	}
-	defer resp.Body.Close()
+	slog.Info("Starting incremental release sync",
+		"username", user.Username,
+		"sessionID", sessionID,
+		"startPage", page)
+
+	for {
+		response, err := c.fetchReleasesPage(user, discogs.AllFolderID, page, perPage, &discogs.NewestFirst)
+		if err != nil {
+			slog.Error("Failed to fetch releases page", "error", err, "page", page)
+			return err
+		}
 
-	// Check for rate limiting
-	if resp.StatusCode == http.StatusTooManyRequests {
-		// Get retry after header if available
-		retryAfter := resp.Header.Get("Retry-After")
-		slog.Warn("Rate limited by Discogs API", 
-			"retryAfter", retryAfter,
-			"folderID", folderID,
-			"page", page,
-			"url", url)
+		var unseen []DiscogsRelease
+		reachedStored := false
+		for _, release := range response.Releases {
+			exists, err := c.Releases.ReleaseInstanceExists(release.InstanceID, sessionID)
+			if err != nil {
+				return err
+			}
 
-		// Default to 60 seconds if no Retry-After header
-		waitTime := 60 * time.Second
-		if retryAfter != "" {
-			if seconds, err := time.ParseDuration(retryAfter + "s"); err == nil {
-				waitTime = seconds
+			if exists {
+				reachedStored = true
+				break
 			}
+
+			unseen = append(unseen, release)
 		}
 
-		// Wait and retry once
-		slog.Info("Waiting before retry due to rate limit", 
-			"waitTime", waitTime,
-			"folderID", folderID,
-			"page", page)
-		time.Sleep(waitTime)
-		return fetchReleasesPage(user, folderID, page, perPage)
-	}
+		if len(unseen) > 0 {
+			response.Releases = unseen
+			if err := c.Releases.SaveReleases(response, job.ID, sessionID); err != nil {
+				slog.Error("Failed to save releases",
+					"error", err,
+					"page", page,
+					"releaseCount", len(unseen))
+				return err
+			}
+		}
+
+		slog.Debug("Processed incremental releases page",
+			"page", page,
+			"newOnPage", len(unseen),
+			"reachedStored", reachedStored)
+
+		page++
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		slog.Error("API returned non-200 status",
-			"status", resp.StatusCode,
-			"body", string(body),
-			"url", url)
-		return response, fmt.Errorf("API returned non-200 status: %d", resp.StatusCode)
+		job.FolderID = &allFolderID
+		job.Page = page
+		job.ReleasesProcessed += len(unseen)
+		if err := c.Syncs.SaveSyncCheckpoint(*job); err != nil {
+			return err
+		}
+
+		c.Events.Publish(SyncEvent{
+			Type:      SyncEventProgress,
+			SyncID:    job.ID,
+			Phase:     SyncPhaseReleases,
+			FolderID:  &allFolderID,
+			Page:      page - 1,
+			Pages:     response.Pagination.Pages,
+			Processed: job.ReleasesProcessed,
+		})
+
+		if reachedStored || page > response.Pagination.Pages {
+			break
+		}
 	}
 
-	// Decode the response
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		slog.Error("Error decoding response", "error", err)
+	slog.Info("Incremental release sync completed",
+		"newReleases", job.ReleasesProcessed,
+		"pages", page-1)
+
+	return nil
+}
+
+func generateSyncSessionID() string {
+	return fmt.Sprintf("sync_%s", time.Now().Format("2006_01_02_150405"))
+}
+
+func (c *Controller) fetchReleasesPage(
+	user database.User,
+	folderID, page, perPage int,
+	sort *discogs.ReleaseSort,
+) (DiscogsResponse, error) {
+	// The client waits on the shared rate limiter and retries 429s itself
+	response, err := c.Discogs.GetReleasesPage(user, folderID, page, perPage, sort)
+	if err != nil {
+		slog.Error("Failed to fetch releases page", 
+			"error", err,
+			"folderID", folderID,
+			"page", page)
 		return response, err
 	}
 
@@ -207,7 +309,7 @@ func fetchReleasesPage(user database.User, folderID, page, perPage int) (Discogs
 }
 
 func (c *Controller) DeleteRelease(releaseID int) (payload Payload, err error) {
-	err = c.DB.DeleteRelease(releaseID)
+	err = c.Releases.DeleteRelease(releaseID)
 	if err != nil {
 		slog.Error("Failed to delete release", "error", err)
 		return
@@ -221,17 +323,55 @@ func (c *Controller) DeleteRelease(releaseID int) (payload Payload, err error) {
 	return
 }
 
-func (c *Controller) ArchiveRelease(releaseID int) (payload Payload, err error) {
-	err = c.DB.ArchiveRelease(releaseID)
+func (c *Controller) ArchiveRelease(
+	releaseID int,
+	reason database.ArchiveReason,
+) (payload Payload, err error) {
+	err = c.Releases.ArchiveRelease(releaseID, reason)
 	if err != nil {
-		slog.Error("Failed to archive release", "error", err)
+		slog.Error("Failed to archive release", "error", err, "releaseID", releaseID)
 		return
 	}
 
 	err = payload.GetPayload(c)
 	if err != nil {
-		slog.Error("Failed to get payload for play history", "error", err)
+		slog.Error("Failed to get payload for archived release", "error", err)
+	}
+
+	return
+}
+
+func (c *Controller) RestoreRelease(releaseID int) (payload Payload, err error) {
+	err = c.Releases.RestoreRelease(releaseID)
+	if err != nil {
+		slog.Error("Failed to restore release", "error", err, "releaseID", releaseID)
+		return
+	}
+
+	err = payload.GetPayload(c)
+	if err != nil {
+		slog.Error("Failed to get payload for restored release", "error", err)
 	}
 
 	return
 }
+
+func (c *Controller) GetArchivedReleases() ([]database.Release, error) {
+	releases, err := c.Releases.GetArchivedReleases()
+	if err != nil {
+		slog.Error("Failed to get archived releases", "error", err)
+		return nil, err
+	}
+
+	return releases, nil
+}
+
+// GetRelease returns a single release with its full metadata
+func (c *Controller) GetRelease(releaseID int) (database.Release, error) {
+	return c.Releases.GetRelease(releaseID)
+}
+
+// ListReleases returns a filtered, sorted page of the active collection
+func (c *Controller) ListReleases(query database.ReleaseQuery) (database.ReleasePage, error) {
+	return c.Releases.ListReleases(query)
+}
@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultSyncInterval is how long after a sync the next one is scheduled
+	DefaultSyncInterval = 12 * time.Hour
+	// FailedSyncRetryDelay is how long after a failed sync it is tried again,
+	// unless the interval is shorter
+	FailedSyncRetryDelay = 30 * time.Minute
+
+	// schedulerIdlePoll is how often the scheduler looks again when there is
+	// nothing to schedule, such as before anyone has signed in
+	schedulerIdlePoll = 5 * time.Minute
+	// schedulerMaxSleep caps a wait for the next sync, so changes such as a
+	// manual sync or the clock moving are picked up
+	schedulerMaxSleep = time.Hour
+)
+
+// QuietHours is a daily window, in local time, when scheduled syncs don't
+// start. It may wrap past midnight, e.g. 22:00-07:00.
+type QuietHours struct {
+	Start int // Minutes after midnight
+	End   int
+}
+
+// ParseQuietHours reads a window written as HH:MM-HH:MM
+func ParseQuietHours(value string) (QuietHours, error) {
+	var startHour, startMinute, endHour, endMinute int
+	_, err := fmt.Sscanf(value, "%d:%d-%d:%d", &startHour, &startMinute, &endHour, &endMinute)
+	if err != nil ||
+		startHour < 0 || startHour > 23 || startMinute < 0 || startMinute > 59 ||
+		endHour < 0 || endHour > 23 || endMinute < 0 || endMinute > 59 {
+		return QuietHours{}, fmt.Errorf("quiet hours must look like 22:00-07:00, got %q", value)
+	}
+
+	quiet := QuietHours{Start: startHour*60 + startMinute, End: endHour*60 + endMinute}
+	if quiet.Start == quiet.End {
+		return QuietHours{}, fmt.Errorf("quiet hours %q are empty", value)
+	}
+
+	return quiet, nil
+}
+
+func (q QuietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.Start/60, q.Start%60, q.End/60, q.End%60)
+}
+
+// Contains reports whether t falls inside the window
+func (q QuietHours) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if q.Start < q.End {
+		return minute >= q.Start && minute < q.End
+	}
+
+	return minute >= q.Start || minute < q.End
+}
+
+// After returns t, or the end of the window when t falls inside it
+func (q QuietHours) After(t time.Time) time.Time {
+	if !q.Contains(t) {
+		return t
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	end := midnight.Add(time.Duration(q.End) * time.Minute)
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return end
+}
+
+// SyncSchedule controls the background syncs. A zero Interval turns them off.
+type SyncSchedule struct {
+	Interval   time.Duration
+	QuietHours *QuietHours
+}
+
+// LoadSyncSchedule reads SYNC_INTERVAL, a duration such as 6h or "off", and
+// SYNC_QUIET_HOURS, such as 22:00-07:00. Invalid values are logged and the
+// defaults used instead.
+func LoadSyncSchedule() SyncSchedule {
+	schedule := SyncSchedule{Interval: DefaultSyncInterval}
+
+	switch value := os.Getenv("SYNC_INTERVAL"); value {
+	case "":
+	case "off", "0":
+		schedule.Interval = 0
+	default:
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < time.Minute {
+			slog.Error("Invalid SYNC_INTERVAL, using the default",
+				"value", value,
+				"default", DefaultSyncInterval)
+		} else {
+			schedule.Interval = interval
+		}
+	}
+
+	if value := os.Getenv("SYNC_QUIET_HOURS"); value != "" {
+		quiet, err := ParseQuietHours(value)
+		if err != nil {
+			slog.Error("Invalid SYNC_QUIET_HOURS, ignoring", "error", err)
+		} else {
+			schedule.QuietHours = &quiet
+		}
+	}
+
+	return schedule
+}
+
+// SyncScheduleStatus describes the scheduler for the API
+type SyncScheduleStatus struct {
+	Enabled         bool       `json:"enabled"`
+	IntervalSeconds int        `json:"intervalSeconds"`
+	QuietHours      string     `json:"quietHours,omitempty"`
+	Running         bool       `json:"running"`
+	NextRunAt       *time.Time `json:"nextRunAt"` // Nil while a sync runs, when disabled or before sign in
+}
+
+func (c *Controller) GetSyncSchedule() (SyncScheduleStatus, error) {
+	status := SyncScheduleStatus{
+		Enabled:         c.Schedule.Interval > 0,
+		IntervalSeconds: int(c.Schedule.Interval.Seconds()),
+	}
+	if c.Schedule.QuietHours != nil {
+		status.QuietHours = c.Schedule.QuietHours.String()
+	}
+
+	latest, err := c.Syncs.GetLatestSync()
+	if err != nil {
+		return status, err
+	}
+	status.Running = latest.Status == "in_progress"
+
+	status.NextRunAt, err = c.nextScheduledSync(time.Now())
+	return status, err
+}
+
+// nextScheduledSync works out when the scheduler will next start a sync. It
+// returns nil when it won't, because scheduling is off, a sync is running or
+// nobody has signed in.
+func (c *Controller) nextScheduledSync(now time.Time) (*time.Time, error) {
+	if c.Schedule.Interval <= 0 {
+		return nil, nil
+	}
+
+	if _, err := c.Auth.GetUser(); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	latest, err := c.Syncs.GetLatestSync()
+	if err != nil {
+		return nil, err
+	}
+
+	next := now
+	switch latest.Status {
+	case "":
+		// Never synced
+	case "in_progress":
+		return nil, nil
+	case "complete":
+		next = latest.SyncStart.Add(c.Schedule.Interval)
+	default:
+		next = latest.SyncStart.Add(min(FailedSyncRetryDelay, c.Schedule.Interval))
+	}
+
+	if next.Before(now) {
+		next = now
+	}
+	if c.Schedule.QuietHours != nil {
+		next = c.Schedule.QuietHours.After(next.In(time.Local))
+	}
+
+	return &next, nil
+}
+
+// signalScheduler makes the scheduler work out its next run again, e.g.
+// after a sign in
+func (c *Controller) signalScheduler() {
+	select {
+	case c.scheduleSignal <- struct{}{}:
+	default:
+	}
+}
+
+// RunSyncScheduler starts syncs on the configured schedule. It never returns.
+func (c *Controller) RunSyncScheduler() {
+	if c.Schedule.Interval <= 0 {
+		slog.Info("Scheduled syncs are turned off")
+		return
+	}
+
+	slog.Info("Sync scheduler started",
+		"interval", c.Schedule.Interval,
+		"quietHours", c.Schedule.QuietHours)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-c.scheduleSignal:
+		}
+
+		now := time.Now()
+		next, err := c.nextScheduledSync(now)
+		if err != nil {
+			slog.Error("Failed to work out the next scheduled sync", "error", err)
+		}
+
+		wait := schedulerIdlePoll
+		if next != nil && !next.After(now) {
+			slog.Info("Starting scheduled sync")
+			if err := c.AsyncCollection(); err != nil {
+				slog.Error("Scheduled sync failed", "error", err)
+			}
+			next, err = c.nextScheduledSync(time.Now())
+			if err != nil {
+				slog.Error("Failed to work out the next scheduled sync", "error", err)
+			}
+		}
+		if next != nil {
+			wait = min(time.Until(*next), schedulerMaxSleep)
+			slog.Info("Next scheduled sync", "at", next.Format(time.RFC3339))
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(max(wait, time.Second))
+	}
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"kleio/internal/database"
+	"testing"
+	"time"
+)
+
+func TestNextScheduledSync(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.Local)
+	quiet := QuietHours{Start: 11 * 60, End: 15 * 60}
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name     string
+		signedIn bool
+		latest   *database.Sync
+		quiet    *QuietHours
+		want     *time.Time
+	}{
+		{
+			name: "nobody signed in",
+		},
+		{
+			name:     "never synced",
+			signedIn: true,
+			want:     &now,
+		},
+		{
+			name:     "sync running",
+			signedIn: true,
+			latest:   &database.Sync{SyncStart: now.Add(-time.Minute), Status: "in_progress"},
+		},
+		{
+			name:     "after a completed sync",
+			signedIn: true,
+			latest:   &database.Sync{SyncStart: now.Add(-2 * time.Hour), Status: "complete"},
+			want:     at(4 * time.Hour),
+		},
+		{
+			name:     "retry after a failed sync",
+			signedIn: true,
+			latest:   &database.Sync{SyncStart: now.Add(-10 * time.Minute), Status: "failed"},
+			want:     at(20 * time.Minute),
+		},
+		{
+			name:     "overdue sync held back by quiet hours",
+			signedIn: true,
+			latest:   &database.Sync{SyncStart: now.Add(-5 * time.Hour), Status: "complete"},
+			quiet:    &quiet,
+			want:     at(3 * time.Hour),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			syncs := &memorySyncStore{}
+			if test.latest != nil {
+				syncs.syncs = append(syncs.syncs, *test.latest)
+			}
+			auth := &memoryAuthStore{}
+			if test.signedIn {
+				auth.user = &database.User{Username: "kleio", Token: "token"}
+			}
+
+			controller := &Controller{
+				Stores:   Stores{Syncs: syncs, Auth: auth},
+				Schedule: SyncSchedule{Interval: 6 * time.Hour, QuietHours: test.quiet},
+			}
+			next, err := controller.nextScheduledSync(now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			switch {
+			case test.want == nil && next != nil:
+				t.Errorf("next sync at %s, want none", next)
+			case test.want != nil && next == nil:
+				t.Errorf("no next sync, want %s", test.want)
+			case test.want != nil && !next.Equal(*test.want):
+				t.Errorf("next sync at %s, want %s", next, test.want)
+			}
+		})
+	}
+}
+
+func TestGetSyncScheduleFollowsSyncs(t *testing.T) {
+	syncs := &memorySyncStore{}
+	controller := &Controller{
+		Stores: Stores{
+			Syncs: syncs,
+			Auth:  &memoryAuthStore{user: &database.User{Username: "kleio", Token: "token"}},
+		},
+		Schedule: SyncSchedule{Interval: time.Hour},
+	}
+
+	id, err := syncs.StartSync(database.SyncModeFull, "test_sync_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := controller.GetSyncSchedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Running || status.NextRunAt != nil {
+		t.Errorf("status while syncing = %+v, want running with no next run", status)
+	}
+
+	if err := syncs.CompleteSync(id, true); err != nil {
+		t.Fatal(err)
+	}
+	status, err = controller.GetSyncSchedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Running || status.NextRunAt == nil || time.Until(*status.NextRunAt) < 59*time.Minute {
+		t.Errorf("status after a sync = %+v, want the next run an hour on", status)
+	}
+}
@@ -0,0 +1,8 @@
+package controller
+
+import "kleio/internal/database"
+
+// Search finds releases matching query, up to limit of each kind of hit
+func (c *Controller) Search(query string, limit int) (database.SearchResults, error) {
+	return c.SearchIndex.Search(query, limit)
+}
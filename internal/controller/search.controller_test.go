@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"kleio/internal/database"
+	"kleio/internal/discogs/fake"
+	"testing"
+)
+
+func hitTexts(hits []database.SearchHit) []string {
+	texts := make([]string, len(hits))
+	for i, hit := range hits {
+		texts[i] = hit.Text
+	}
+
+	return texts
+}
+
+func TestSearch(t *testing.T) {
+	server, err := fake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.RateLimit = 600
+	controller, _ := newFakeDiscogsController(t, server)
+	runTestSync(t, controller, database.SyncModeFull, "test_sync_1")
+
+	t.Run("words match the start of words in one title", func(t *testing.T) {
+		results, err := controller.Search("the cha", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(results.Tracks) != 1 || results.Tracks[0].Text != "The Chain" || results.Tracks[0].ReleaseID != 1003 {
+			t.Fatalf("tracks = %q, want only The Chain from Rumours", hitTexts(results.Tracks))
+		}
+		if results.Tracks[0].ReleaseTitle != "Rumours" {
+			t.Errorf("hit release title = %q, want Rumours", results.Tracks[0].ReleaseTitle)
+		}
+		if len(results.Releases) != 0 || len(results.Artists) != 0 {
+			t.Errorf("unexpected hits: releases %q, artists %q", hitTexts(results.Releases), hitTexts(results.Artists))
+		}
+	})
+
+	t.Run("hits are grouped and ranked", func(t *testing.T) {
+		results, err := controller.Search("blue", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(results.Releases) == 0 || len(results.Tracks) == 0 {
+			t.Fatalf("releases %q and tracks %q, want hits in both", hitTexts(results.Releases), hitTexts(results.Tracks))
+		}
+		for _, group := range [][]database.SearchHit{results.Releases, results.Tracks} {
+			for i := 1; i < len(group); i++ {
+				if group[i].Score > group[i-1].Score {
+					t.Errorf("%q ranked below %q with a better score", group[i].Text, group[i-1].Text)
+				}
+			}
+		}
+
+		tracks := make(map[string]bool)
+		for _, hit := range results.Tracks {
+			tracks[hit.Text] = true
+		}
+		for _, title := range []string{"Blue In Green", "All Blues", "Blue Monday"} {
+			if !tracks[title] {
+				t.Errorf("tracks %q are missing %s", hitTexts(results.Tracks), title)
+			}
+		}
+
+		limited, err := controller.Search("blue", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(limited.Releases) != 1 || len(limited.Tracks) != 1 {
+			t.Errorf("limit of 1 gave %d releases and %d tracks", len(limited.Releases), len(limited.Tracks))
+		}
+		if limited.Tracks[0].ID != results.Tracks[0].ID {
+			t.Errorf("limited search's best track %q, want %q", limited.Tracks[0].Text, results.Tracks[0].Text)
+		}
+	})
+
+	t.Run("archived releases are left out", func(t *testing.T) {
+		if !server.RemoveRelease(5003) {
+			t.Fatal("fixture has no instance 5003")
+		}
+		runTestSync(t, controller, database.SyncModeFull, "test_sync_2")
+
+		results, err := controller.Search("the chain", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results.Tracks) != 0 {
+			t.Errorf("archived release still found: %q", hitTexts(results.Tracks))
+		}
+	})
+}
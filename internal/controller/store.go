@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"kleio/internal/database"
+	"time"
+)
+
+// ReleaseStore holds the collection: releases, their details, masters and
+// collection fields
+type ReleaseStore interface {
+	GetRelease(id int) (database.Release, error)
+	GetReleaseByID(id int) (*database.Release, error)
+	GetAllReleasesWithArchived() ([]database.Release, error)
+	GetArchivedReleases() ([]database.Release, error)
+	ListReleases(query database.ReleaseQuery) (database.ReleasePage, error)
+	GetReleasesByNote(filter database.NoteFilter) ([]database.Release, error)
+	GetReleasesWithoutDetails() ([]database.Release, error)
+	GetReleasesDueForRefresh(maxAge time.Duration, limit int) ([]int, error)
+	ReleaseInstanceExists(instanceID int, sessionID string) (bool, error)
+	SaveReleases(response database.DiscogsResponse, syncID int64, sessionID string) error
+	SaveReleaseBasicInfo(releaseID int, info database.DiscogsBasicInfo) error
+	SaveReleaseDetails(releaseID int, metadata database.ReleaseMetadata, tracks []database.Track) error
+	UpdateReleaseWithDetails(releaseID int, totalDuration int, estimated bool) error
+	UpdateReleaseRating(releaseID, rating int) error
+	MoveRelease(releaseID, folderID int) error
+	ArchiveRelease(id int, reason database.ArchiveReason) error
+	ArchiveStaleReleases(syncID int64, sessionID string, reason database.ArchiveReason) (int64, error)
+	RestoreRelease(id int) error
+	DeleteRelease(id int) error
+
+	GetMaster(id int, withStats bool) (database.Master, error)
+	GetMasters(minPressings int, withStats bool) ([]database.Master, error)
+
+	GetCollectionFields() ([]database.CollectionField, error)
+	SaveCollectionFields(fields []database.DiscogsCollectionField) error
+}
+
+type FolderStore interface {
+	GetFolders() ([]database.Folder, error)
+	ReconcileFolders(syncID int64, folders []database.Folder) ([]database.FolderChange, error)
+}
+
+type PlayHistoryStore interface {
+	GetAllPlayHistory() ([]database.PlayHistory, error)
+	GetRecentPlays(limit int) ([]database.PlayHistory, error)
+	GetPlaysByTimeRange(start, end time.Time) ([]database.PlayHistory, error)
+	GetPlayCountByRelease() (map[int]int, error)
+	GetPlayCountByMaster() (map[int]int, error)
+	CreatePlayHistory(history *database.PlayHistory) error
+	UpdatePlayHistory(history *database.PlayHistory) error
+	DeletePlayHistory(id int) error
+}
+
+type CleaningStore interface {
+	GetAllCleaningHistory() ([]database.CleaningHistory, error)
+	GetCleaningsByTimeRange(start, end time.Time) ([]database.CleaningHistory, error)
+	CountCleaningsByRelease() (map[int]int, error)
+	CreateCleaningHistory(history *database.CleaningHistory) error
+	UpdateCleaningHistory(history *database.CleaningHistory) error
+	DeleteCleaningHistory(id int) error
+}
+
+type StylusStore interface {
+	GetStyluses() ([]database.Stylus, error)
+	CreateStylus(stylus *database.Stylus) error
+	UpdateStylus(stylus *database.Stylus) error
+	DeleteStylus(id int) error
+}
+
+// SyncStore records sync jobs, their checkpoints and what they changed
+type SyncStore interface {
+	StartSync(mode database.SyncMode, sessionID string) (int64, error)
+	SaveSyncCheckpoint(sync database.Sync) error
+	CompleteSync(id int64, success bool) error
+	CleanupAbandonedSyncs() error
+	GetLatestSync() (database.Sync, error)
+	GetLatestCompletedSync(mode database.SyncMode) (database.Sync, error)
+	GetInterruptedSyncs() ([]database.Sync, error)
+	GetSyncHistory(limit int) ([]database.SyncHistory, error)
+	GetSyncChanges(syncID int64) ([]database.SyncChange, error)
+	GetFolderChanges(syncID int64) ([]database.FolderChange, error)
+}
+
+// AuthStore keeps the Discogs credentials
+type AuthStore interface {
+	GetUser() (database.User, error)
+	GetToken() (string, error)
+	SaveToken(token string, username string) error
+	SaveOAuthCredentials(username, accessToken, accessTokenSecret string) error
+	SaveOAuthRequestToken(token, secret string) error
+	TakeOAuthRequestToken(token string) (string, error)
+}
+
+type WantlistStore interface {
+	GetWantlist(acquired *bool) ([]database.WantlistItem, error)
+	SaveWantlist(syncID int64, wants []database.DiscogsWant) ([]database.WantlistItem, error)
+	DeleteWantlistItem(releaseID int) error
+}
+
+// DiscogsWriteStore is the queue of changes waiting to be pushed to Discogs
+type DiscogsWriteStore interface {
+	GetDiscogsWrites(limit int) ([]database.DiscogsWrite, error)
+	GetDueDiscogsWrites() ([]database.DiscogsWrite, error)
+	CompleteDiscogsWrite(write database.DiscogsWrite) error
+	FailDiscogsWrite(write database.DiscogsWrite, lastError string) error
+	RetryDiscogsWrite(write database.DiscogsWrite, lastError string, delaySeconds int) error
+}
+
+// ReleaseImageStore tracks which artwork has been downloaded
+type ReleaseImageStore interface {
+	GetImagesToCache(limit int) ([]database.ReleaseImage, error)
+	GetReleaseImage(releaseID int, kind database.ImageKind) (database.ReleaseImage, error)
+	SaveReleaseImage(image database.ReleaseImage) error
+	RetryReleaseImage(image database.ReleaseImage, lastError string, delaySeconds int) error
+	DeleteStaleReleaseImages() (int64, error)
+	GetReleaseImageHashes() (map[string]bool, error)
+}
+
+type CollectionValueStore interface {
+	GetCollectionValueSnapshots(since time.Time) ([]database.CollectionValueSnapshot, error)
+	SaveCollectionValueSnapshot(snapshot database.CollectionValueSnapshot) error
+	GetReleasesDueForPriceCheck(maxAge time.Duration, limit int) ([]int, error)
+	SaveReleasePrice(price database.ReleasePrice) error
+}
+
+// SearchStore finds releases by the text stored about them
+type SearchStore interface {
+	Search(query string, limit int) (database.SearchResults, error)
+}
+
+// BackupStore copies the whole database out to a file and back
+type BackupStore interface {
+	Backup(ctx context.Context, destPath string) error
+	Restore(ctx context.Context, snapshotPath string) error
+}
+
+// Stores is everything the controller persists through. Each can be swapped
+// for another implementation, such as an in-memory one.
+type Stores struct {
+	Releases      ReleaseStore
+	Folders       FolderStore
+	Plays         PlayHistoryStore
+	Cleanings     CleaningStore
+	Styluses      StylusStore
+	Syncs         SyncStore
+	Auth          AuthStore
+	Wantlist      WantlistStore
+	Writes        DiscogsWriteStore
+	ReleaseImages ReleaseImageStore
+	Values        CollectionValueStore
+	SearchIndex   SearchStore
+	BackupSource  BackupStore
+}
+
+// DatabaseStores backs every store with the one database. BackupSource is
+// left nil when the database can't be backed up by Kleio.
+func DatabaseStores(db *database.Database) Stores {
+	stores := Stores{
+		Releases:      db,
+		Folders:       db,
+		Plays:         db,
+		Cleanings:     db,
+		Styluses:      db,
+		Syncs:         db,
+		Auth:          db,
+		Wantlist:      db,
+		Writes:        db,
+		ReleaseImages: db,
+		Values:        db,
+		SearchIndex:   db,
+	}
+	if db.SupportsBackup() {
+		stores.BackupSource = db
+	}
+
+	return stores
+}
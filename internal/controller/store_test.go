@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"database/sql"
+	"kleio/internal/database"
+	"slices"
+	"sync"
+	"time"
+)
+
+// memorySyncStore keeps sync jobs in memory, for testing the controller
+// without a database
+type memorySyncStore struct {
+	mutex   sync.Mutex
+	syncs   []database.Sync
+	changes map[int64][]database.SyncChange
+}
+
+func (s *memorySyncStore) StartSync(mode database.SyncMode, sessionID string) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sync := database.Sync{
+		ID:        int64(len(s.syncs) + 1),
+		SyncStart: time.Now(),
+		Status:    "in_progress",
+		Mode:      mode,
+		Phase:     database.SyncPhaseFolders,
+		SessionID: sessionID,
+		Page:      1,
+	}
+	s.syncs = append(s.syncs, sync)
+
+	return sync.ID, nil
+}
+
+func (s *memorySyncStore) SaveSyncCheckpoint(sync database.Sync) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.syncs {
+		if s.syncs[i].ID == sync.ID {
+			now := time.Now()
+			sync.CheckpointAt = &now
+			s.syncs[i] = sync
+			return nil
+		}
+	}
+
+	return sql.ErrNoRows
+}
+
+func (s *memorySyncStore) CompleteSync(id int64, success bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.syncs {
+		if s.syncs[i].ID == id {
+			now := time.Now()
+			s.syncs[i].SyncEnd = &now
+			s.syncs[i].Status = "failed"
+			if success {
+				s.syncs[i].Status = "complete"
+			}
+			return nil
+		}
+	}
+
+	return sql.ErrNoRows
+}
+
+func (s *memorySyncStore) CleanupAbandonedSyncs() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.syncs {
+		if s.syncs[i].Status == "in_progress" {
+			s.syncs[i].Status = "failed"
+		}
+	}
+
+	return nil
+}
+
+func (s *memorySyncStore) GetLatestSync() (database.Sync, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.syncs) == 0 {
+		return database.Sync{}, nil
+	}
+
+	return s.syncs[len(s.syncs)-1], nil
+}
+
+func (s *memorySyncStore) GetLatestCompletedSync(mode database.SyncMode) (database.Sync, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, sync := range slices.Backward(s.syncs) {
+		if sync.Status == "complete" && sync.Mode == mode {
+			return sync, nil
+		}
+	}
+
+	return database.Sync{}, nil
+}
+
+func (s *memorySyncStore) GetInterruptedSyncs() ([]database.Sync, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var interrupted []database.Sync
+	for _, sync := range s.syncs {
+		if sync.Status == "in_progress" {
+			interrupted = append(interrupted, sync)
+		}
+	}
+
+	return interrupted, nil
+}
+
+func (s *memorySyncStore) GetSyncHistory(limit int) ([]database.SyncHistory, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var history []database.SyncHistory
+	for _, sync := range slices.Backward(s.syncs) {
+		if len(history) == limit {
+			break
+		}
+		history = append(history, database.SyncHistory{Sync: sync})
+	}
+
+	return history, nil
+}
+
+func (s *memorySyncStore) GetSyncChanges(syncID int64) ([]database.SyncChange, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.changes[syncID], nil
+}
+
+func (s *memorySyncStore) GetFolderChanges(syncID int64) ([]database.FolderChange, error) {
+	return nil, nil
+}
+
+// memoryAuthStore keeps the signed in user in memory. user is nil until
+// someone signs in.
+type memoryAuthStore struct {
+	mutex         sync.Mutex
+	user          *database.User
+	requestTokens map[string]string
+}
+
+func (s *memoryAuthStore) GetUser() (database.User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.user == nil {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	return *s.user, nil
+}
+
+func (s *memoryAuthStore) GetToken() (string, error) {
+	user, err := s.GetUser()
+	return user.Token, err
+}
+
+func (s *memoryAuthStore) SaveToken(token string, username string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.user = &database.User{Username: username, Token: token}
+	return nil
+}
+
+func (s *memoryAuthStore) SaveOAuthCredentials(username, accessToken, accessTokenSecret string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.user = &database.User{Username: username, OAuthToken: accessToken, OAuthTokenSecret: accessTokenSecret}
+	return nil
+}
+
+func (s *memoryAuthStore) SaveOAuthRequestToken(token, secret string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.requestTokens == nil {
+		s.requestTokens = make(map[string]string)
+	}
+	s.requestTokens[token] = secret
+	return nil
+}
+
+func (s *memoryAuthStore) TakeOAuthRequestToken(token string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	secret, ok := s.requestTokens[token]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	delete(s.requestTokens, token)
+
+	return secret, nil
+}
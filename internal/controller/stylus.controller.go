@@ -6,7 +6,7 @@ import (
 )
 
 func (c *Controller) GetStyluses() ([]database.Stylus, error) {
-	styluses, err := c.DB.GetStyluses()
+	styluses, err := c.Styluses.GetStyluses()
 	if err != nil {
 		slog.Error("Failed to get styluses", "error", err)
 		return nil, err
@@ -16,7 +16,7 @@ func (c *Controller) GetStyluses() ([]database.Stylus, error) {
 }
 
 func (c *Controller) CreateStylus(stylus *database.Stylus) ([]database.Stylus, error) {
-	err := c.DB.CreateStylus(stylus)
+	err := c.Styluses.CreateStylus(stylus)
 	if err != nil {
 		slog.Error("Failed to create stylus", "error", err)
 		return nil, err
@@ -26,7 +26,7 @@ func (c *Controller) CreateStylus(stylus *database.Stylus) ([]database.Stylus, e
 }
 
 func (c *Controller) UpdateStylus(stylus *database.Stylus) ([]database.Stylus, error) {
-	err := c.DB.UpdateStylus(stylus)
+	err := c.Styluses.UpdateStylus(stylus)
 	if err != nil {
 		slog.Error("Failed to update stylus", "error", err)
 		return nil, err
@@ -36,7 +36,7 @@ func (c *Controller) UpdateStylus(stylus *database.Stylus) ([]database.Stylus, e
 }
 
 func (c *Controller) DeleteStylus(id int) ([]database.Stylus, error) {
-	err := c.DB.DeleteStylus(id)
+	err := c.Styluses.DeleteStylus(id)
 	if err != nil {
 		slog.Error("Failed to delete stylus", "error", err)
 		return nil, err
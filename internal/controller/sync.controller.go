@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"kleio/internal/database"
+	"log/slog"
+)
+
+// DefaultSyncHistoryLimit is how many syncs the history lists when no limit is given
+const DefaultSyncHistoryLimit = 50
+
+// GetLatestSync returns the most recent sync, or a zero Sync when there has
+// never been one
+func (c *Controller) GetLatestSync() (database.Sync, error) {
+	return c.Syncs.GetLatestSync()
+}
+
+func (c *Controller) GetSyncHistory(limit int) ([]database.SyncHistory, error) {
+	if limit <= 0 {
+		limit = DefaultSyncHistoryLimit
+	}
+
+	history, err := c.Syncs.GetSyncHistory(limit)
+	if err != nil {
+		slog.Error("Failed to get sync history", "error", err)
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (c *Controller) GetSyncChanges(syncID int64) ([]database.SyncChange, error) {
+	changes, err := c.Syncs.GetSyncChanges(syncID)
+	if err != nil {
+		slog.Error("Failed to get sync changes", "error", err, "syncID", syncID)
+		return nil, err
+	}
+
+	return changes, nil
+}
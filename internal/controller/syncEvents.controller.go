@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"kleio/internal/database"
+	"sync"
+	"time"
+)
+
+type SyncEventType string
+
+const (
+	SyncEventPhase    SyncEventType = "phase"    // A sync entered a new phase
+	SyncEventProgress SyncEventType = "progress" // A page or release was processed
+	SyncEventThrottle SyncEventType = "throttle" // The sync is pausing for the Discogs rate limit
+	SyncEventSummary  SyncEventType = "summary"  // The sync finished, successfully or not
+)
+
+// SyncEvent is a progress update streamed to clients while a sync runs.
+// Fields that don't apply to the event type are left empty.
+type SyncEvent struct {
+	Type   SyncEventType      `json:"type"`
+	SyncID int64              `json:"syncId"`
+	Time   time.Time          `json:"time"`
+	Mode   database.SyncMode  `json:"mode,omitempty"`
+	Phase  database.SyncPhase `json:"phase,omitempty"`
+
+	// Releases phase position
+	FolderID   *int   `json:"folderId,omitzero"`
+	FolderName string `json:"folderName,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	Pages      int    `json:"pages,omitempty"`
+
+	// Releases handled in the current phase, and the phase total when known
+	Processed int `json:"processed"`
+	Total     int `json:"total,omitempty"`
+	Failed    int `json:"failed,omitempty"`
+
+	// Throttle pause
+	WaitSeconds float64 `json:"waitSeconds,omitempty"`
+	Remaining   *int    `json:"remaining,omitzero"`
+
+	// Summary
+	Status          string  `json:"status,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+}
+
+// SyncEvents fans sync progress out to any number of subscribers. Slow
+// subscribers miss events rather than holding up the sync.
+type SyncEvents struct {
+	mutex       sync.Mutex
+	subscribers map[chan SyncEvent]struct{}
+	syncID      int64
+
+	// Replayed to new subscribers so they start with the current state
+	lastPhase    *SyncEvent
+	lastProgress *SyncEvent
+	lastSummary  *SyncEvent
+}
+
+func NewSyncEvents() *SyncEvents {
+	return &SyncEvents{subscribers: make(map[chan SyncEvent]struct{})}
+}
+
+// Subscribe returns a channel of events, starting with the latest known state,
+// and a function that must be called to stop receiving them
+func (e *SyncEvents) Subscribe() (<-chan SyncEvent, func()) {
+	events := make(chan SyncEvent, 64)
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, event := range []*SyncEvent{e.lastPhase, e.lastProgress, e.lastSummary} {
+		if event != nil {
+			events <- *event
+		}
+	}
+	e.subscribers[events] = struct{}{}
+
+	unsubscribe := func() {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		delete(e.subscribers, events)
+	}
+
+	return events, unsubscribe
+}
+
+// Publish stamps the event with the running sync, its phase and the time and
+// sends it to every subscriber
+func (e *SyncEvents) Publish(event SyncEvent) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if event.SyncID == 0 {
+		event.SyncID = e.syncID
+	}
+	if event.Phase == "" && e.lastPhase != nil && event.Type != SyncEventSummary {
+		event.Phase = e.lastPhase.Phase
+	}
+	event.Time = time.Now()
+
+	switch event.Type {
+	case SyncEventPhase:
+		e.syncID = event.SyncID
+		e.lastPhase = &event
+		e.lastProgress = nil
+		e.lastSummary = nil
+	case SyncEventProgress:
+		e.lastProgress = &event
+	case SyncEventSummary:
+		e.syncID = 0
+		e.lastPhase = nil
+		e.lastProgress = nil
+		e.lastSummary = &event
+	}
+
+	for subscriber := range e.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
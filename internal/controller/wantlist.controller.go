@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"kleio/internal/database"
+	"log/slog"
+)
+
+// SyncWantlist fetches the whole Discogs wantlist and stores it. It runs after
+// the releases phase so wanted releases that have since been added to the
+// collection are flagged acquired. Failing to fetch it is logged and the sync
+// carries on.
+func (c *Controller) SyncWantlist(job *database.Sync) error {
+	user, err := c.Auth.GetUser()
+	if err != nil {
+		slog.Error("Failed to get user", "error", err)
+		return err
+	}
+
+	var wants []database.DiscogsWant
+	perPage := 100
+	for page := 1; ; page++ {
+		response, err := c.Discogs.GetWantlistPage(user, page, perPage)
+		if err != nil {
+			// Saving part of the wantlist would drop the rest, so the stored
+			// one stands until the next sync rather than failing this one
+			slog.Warn("Failed to get wantlist page, skipping the wantlist",
+				"error", err,
+				"page", page,
+				"syncID", job.ID)
+			return nil
+		}
+		wants = append(wants, response.Wants...)
+
+		c.Events.Publish(SyncEvent{
+			Type:      SyncEventProgress,
+			SyncID:    job.ID,
+			Phase:     database.SyncPhaseWantlist,
+			Page:      page,
+			Pages:     response.Pagination.Pages,
+			Processed: len(wants),
+			Total:     response.Pagination.Items,
+		})
+
+		if page >= response.Pagination.Pages {
+			break
+		}
+	}
+
+	acquired, err := c.Wantlist.SaveWantlist(job.ID, wants)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range acquired {
+		slog.Info("Wanted release is now in the collection",
+			"releaseID", item.ReleaseID,
+			"title", item.Title)
+	}
+	slog.Info("Wantlist sync completed", "wants", len(wants), "acquired", len(acquired))
+
+	return nil
+}
+
+func (c *Controller) GetWantlist(acquired *bool) ([]database.WantlistItem, error) {
+	return c.Wantlist.GetWantlist(acquired)
+}
+
+// DeleteWantlistItem removes an item and returns what is left of the wantlist
+func (c *Controller) DeleteWantlistItem(releaseID int) ([]database.WantlistItem, error) {
+	if err := c.Wantlist.DeleteWantlistItem(releaseID); err != nil {
+		return nil, err
+	}
+
+	return c.Wantlist.GetWantlist(nil)
+}
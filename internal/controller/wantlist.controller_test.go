@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"errors"
+	"kleio/internal/database"
+	"kleio/internal/discogs/fake"
+	"testing"
+)
+
+// failingWantlistClient can't fetch the wantlist
+type failingWantlistClient struct {
+	DiscogsClient
+}
+
+func (c failingWantlistClient) GetWantlistPage(user database.User, page, perPage int) (database.DiscogsWantlistResponse, error) {
+	return database.DiscogsWantlistResponse{}, errors.New("wantlist unavailable")
+}
+
+func TestSyncCollectionCarriesOnWithoutWantlist(t *testing.T) {
+	server, err := fake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.RateLimit = 600
+	controller, db := newFakeDiscogsController(t, server)
+	controller.Discogs = failingWantlistClient{DiscogsClient: controller.Discogs}
+
+	runTestSync(t, controller, database.SyncModeFull, "test_sync_1")
+
+	// The tracks phase after the wantlist still ran
+	releases, err := db.GetReleasesWithoutDetails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != 0 {
+		t.Errorf("%d releases left without details", len(releases))
+	}
+
+	wants, err := db.GetWantlist(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wants) != 0 {
+		t.Errorf("wantlist has %d items, want none", len(wants))
+	}
+}
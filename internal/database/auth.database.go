@@ -5,9 +5,96 @@ import (
 	"log/slog"
 )
 
-func (s *Database) SaveToken(token string, username string) error {
+// SaveToken signs in with a personal access token, replacing any OAuth
+// credentials
+func (s *Database) SaveToken(token string, username string) (err error) {
+	sealedToken, err := s.sealSecret(token)
+	if err != nil {
+		slog.Error("Failed to encrypt token", "error", err)
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	if err = saveAuth(tx, sealedToken, username); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM oauth_credentials"); err != nil {
+		slog.Error("Failed to clear OAuth credentials", "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SaveOAuthCredentials signs in with an OAuth access token, replacing any
+// personal access token
+func (s *Database) SaveOAuthCredentials(username, accessToken, accessTokenSecret string) (err error) {
+	sealedToken, err := s.sealSecret(accessToken)
+	if err != nil {
+		slog.Error("Failed to encrypt OAuth access token", "error", err)
+		return err
+	}
+	sealedSecret, err := s.sealSecret(accessTokenSecret)
+	if err != nil {
+		slog.Error("Failed to encrypt OAuth access token secret", "error", err)
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	if err = saveAuth(tx, "", username); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM oauth_credentials"); err != nil {
+		slog.Error("Failed to clear OAuth credentials", "error", err)
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO oauth_credentials (username, access_token, access_token_secret)
+		VALUES (?, ?, ?)`,
+		username,
+		sealedToken,
+		sealedSecret,
+	)
+	if err != nil {
+		slog.Error("Failed to save OAuth credentials", "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func saveAuth(tx *Tx, token string, username string) error {
 	var count int
-	err := s.DB.QueryRow("SELECT COUNT(*) FROM auth").Scan(&count)
+	err := tx.QueryRow("SELECT COUNT(*) FROM auth").Scan(&count)
 	if err != nil {
 		slog.Error("Failed to check existing token", "error", err)
 		return err
@@ -23,7 +110,7 @@ func (s *Database) SaveToken(token string, username string) error {
 	}
 
 	// Execute the query
-	_, err = s.DB.Exec(sqlQuery, token, username)
+	_, err = tx.Exec(sqlQuery, token, username)
 	if err != nil {
 		slog.Error("Failed to save token", "error", err)
 		return err
@@ -32,15 +119,59 @@ func (s *Database) SaveToken(token string, username string) error {
 	return nil
 }
 
+// GetToken returns the personal access token, or the OAuth access token for a
+// user who signed in through Discogs
 func (s *Database) GetToken() (string, error) {
-	var token string
-	err := s.DB.QueryRow("SELECT token FROM auth").Scan(&token)
+	user, err := s.GetUser()
+	if err != nil {
+		return "", err
+	}
+
+	if user.UsesOAuth() {
+		return user.OAuthToken, nil
+	}
+	return user.Token, nil
+}
+
+// SaveOAuthRequestToken keeps a request token until the user comes back from
+// approving it, clearing out ones that were never approved
+func (s *Database) SaveOAuthRequestToken(token, secret string) error {
+	_, err := s.DB.Exec("DELETE FROM oauth_request_tokens WHERE created_at < datetime('now', '-1 hour')")
+	if err != nil {
+		slog.Error("Failed to clear expired OAuth request tokens", "error", err)
+		return err
+	}
+
+	sealedSecret, err := s.sealSecret(secret)
+	if err != nil {
+		slog.Error("Failed to encrypt OAuth request token secret", "error", err)
+		return err
+	}
+
+	_, err = s.DB.Exec("INSERT INTO oauth_request_tokens (token, secret) VALUES (?, ?)", token, sealedSecret)
+	if err != nil {
+		slog.Error("Failed to save OAuth request token", "error", err)
+	}
+
+	return err
+}
+
+// TakeOAuthRequestToken returns a request token's secret and forgets it, so a
+// callback can't be replayed. It returns sql.ErrNoRows for an unknown token.
+func (s *Database) TakeOAuthRequestToken(token string) (string, error) {
+	var secret string
+	err := s.DB.QueryRow(`
+		DELETE FROM oauth_request_tokens
+		WHERE token = ? AND created_at >= datetime('now', '-1 hour')
+		RETURNING secret`,
+		token,
+	).Scan(&secret)
 	if err != nil {
 		if err != sql.ErrNoRows {
-			slog.Error("Database query error", "error", err)
+			slog.Error("Failed to get OAuth request token", "error", err)
 		}
 		return "", err
 	}
 
-	return token, nil
+	return s.openSecret(secret)
 }
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrInvalidSnapshot is returned when a file offered for a restore isn't
+	// a Kleio database this build can run on
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+	// ErrBackupUnsupported is returned for databases other than SQLite, which
+	// are backed up with their own tools
+	ErrBackupUnsupported = errors.New("backups are only supported for SQLite")
+)
+
+// backupRetryDelay is how long a backup step waits when the database is busy
+const backupRetryDelay = 50 * time.Millisecond
+
+// Backup copies the live database to destPath with the SQLite online backup
+// API. The copy is consistent even while requests keep writing.
+func (s *Database) Backup(ctx context.Context, destPath string) error {
+	if !s.SupportsBackup() {
+		return ErrBackupUnsupported
+	}
+
+	dest, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer dest.Close()
+
+	if err := copyDatabase(ctx, dest, s.DB.DB); err != nil {
+		slog.Error("Failed to back up database", "error", err, "destPath", destPath)
+		return err
+	}
+
+	return nil
+}
+
+// Restore replaces the live database with the snapshot at snapshotPath. The
+// snapshot is checked first: it has to pass an integrity check and have a
+// schema version no newer than this build's. Older snapshots are migrated,
+// which changes the file, so callers should pass a copy.
+func (s *Database) Restore(ctx context.Context, snapshotPath string) error {
+	if !s.SupportsBackup() {
+		return ErrBackupUnsupported
+	}
+
+	snapshot, err := sql.Open("sqlite3", snapshotPath)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer snapshot.Close()
+
+	version, err := validateSnapshot(snapshot)
+	if err != nil {
+		slog.Error("Snapshot failed validation", "error", err, "snapshotPath", snapshotPath)
+		return err
+	}
+
+	if err := runMigrations(&DB{DB: snapshot, dialect: sqliteDialect}); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+
+	if err := copyDatabase(ctx, s.DB.DB, snapshot); err != nil {
+		slog.Error("Failed to restore database", "error", err, "snapshotPath", snapshotPath)
+		return err
+	}
+
+	slog.Info("Restored database from snapshot", "snapshotPath", snapshotPath, "schemaVersion", version)
+
+	// The snapshot may predate the secret key
+	return s.encryptStoredSecrets()
+}
+
+// ValidateSnapshot checks the snapshot at path the way Restore will, without
+// changing it, and returns its schema version
+func ValidateSnapshot(path string) (int, error) {
+	snapshot, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer snapshot.Close()
+
+	return validateSnapshot(snapshot)
+}
+
+// SupportsBackup reports whether Backup and Restore work for the database
+func (s *Database) SupportsBackup() bool {
+	return s.dialect.name == sqliteDialect.name
+}
+
+// SchemaVersion is the newest SQLite migration in this build
+func SchemaVersion() (int, error) {
+	migrations, err := loadMigrations(sqliteDialect)
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// validateSnapshot returns the schema version of a snapshot, or
+// ErrInvalidSnapshot if it is damaged, isn't a Kleio database or comes from a
+// newer build
+func validateSnapshot(snapshot *sql.DB) (int, error) {
+	var check string
+	if err := snapshot.QueryRow("PRAGMA quick_check").Scan(&check); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if check != "ok" {
+		return 0, fmt.Errorf("%w: integrity check failed: %s", ErrInvalidSnapshot, check)
+	}
+
+	var version int
+	err := snapshot.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil || version == 0 {
+		return 0, fmt.Errorf("%w: no schema version recorded", ErrInvalidSnapshot)
+	}
+
+	latest, err := SchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+	if version > latest {
+		return 0, fmt.Errorf(
+			"%w: schema version %d is newer than this build's %d",
+			ErrInvalidSnapshot,
+			version,
+			latest,
+		)
+	}
+
+	return version, nil
+}
+
+// copyDatabase copies every page of src over dest in a single backup step,
+// retrying while either side is locked
+func copyDatabase(ctx context.Context, dest, src *sql.DB) error {
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get destination connection: %w", err)
+	}
+	defer destConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get source connection: %w", err)
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			destSQLite, ok := destDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("destination is not a SQLite connection")
+			}
+			srcSQLite, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("source is not a SQLite connection")
+			}
+
+			backup, err := destSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return fmt.Errorf("failed to start backup: %w", err)
+			}
+
+			for {
+				// Copying everything at once keeps the copy consistent, a
+				// write between steps would make SQLite start over
+				done, err := backup.Step(-1)
+				if err != nil {
+					backup.Finish()
+					return fmt.Errorf("failed to copy database: %w", err)
+				}
+				if done {
+					break
+				}
+
+				select {
+				case <-ctx.Done():
+					backup.Finish()
+					return ctx.Err()
+				case <-time.After(backupRetryDelay):
+				}
+			}
+
+			if err := backup.Finish(); err != nil {
+				return fmt.Errorf("failed to finish backup: %w", err)
+			}
+
+			return nil
+		})
+	})
+}
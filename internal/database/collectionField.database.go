@@ -0,0 +1,246 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrFieldNotFound     = errors.New("collection field not found")
+	ErrInvalidNoteFilter = errors.New("invalid note filter")
+)
+
+// SaveCollectionFields replaces the stored field definitions with those from
+// Discogs. Notes are left alone so a deleted field's values are not lost.
+func (s *Database) SaveCollectionFields(fields []DiscogsCollectionField) (err error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	if _, err = tx.Exec("DELETE FROM collection_fields"); err != nil {
+		slog.Error("Failed to clear collection fields", "error", err)
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO collection_fields (id, name, type, position, public, lines, options, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`)
+	if err != nil {
+		slog.Error("Failed to prepare collection field statement", "error", err)
+		return err
+	}
+	defer stmt.Close()
+
+	for _, field := range fields {
+		var lines sql.NullInt64
+		if field.Lines > 0 {
+			lines = sql.NullInt64{Int64: int64(field.Lines), Valid: true}
+		}
+
+		var options sql.NullString
+		if len(field.Options) > 0 {
+			encoded, marshalErr := json.Marshal(field.Options)
+			if marshalErr != nil {
+				err = marshalErr
+				return err
+			}
+			options = sql.NullString{String: string(encoded), Valid: true}
+		}
+
+		_, err = stmt.Exec(field.ID, field.Name, field.Type, field.Position, field.Public, lines, options)
+		if err != nil {
+			slog.Error("Failed to save collection field", "error", err, "fieldID", field.ID)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.Error("Failed to commit collection fields", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *Database) GetCollectionFields() ([]CollectionField, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, name, type, position, public, lines, options
+		FROM collection_fields
+		ORDER BY position, id
+	`)
+	if err != nil {
+		slog.Error("Failed to get collection fields", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	fields := []CollectionField{}
+	for rows.Next() {
+		var field CollectionField
+		var lines sql.NullInt64
+		var options sql.NullString
+		err := rows.Scan(
+			&field.ID,
+			&field.Name,
+			&field.Type,
+			&field.Position,
+			&field.Public,
+			&lines,
+			&options,
+		)
+		if err != nil {
+			slog.Error("Failed to scan collection field", "error", err)
+			return nil, err
+		}
+
+		if lines.Valid {
+			value := int(lines.Int64)
+			field.Lines = &value
+		}
+		if options.Valid {
+			if err := json.Unmarshal([]byte(options.String), &field.Options); err != nil {
+				slog.Error("Failed to decode collection field options", "error", err, "fieldID", field.ID)
+				return nil, err
+			}
+		}
+
+		fields = append(fields, field)
+	}
+
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating collection field rows", "error", err)
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// GetReleasesByNote returns the releases in the collection whose note for the
+// filter's field matches. Releases with no value for the field never match,
+// not even ne.
+func (s *Database) GetReleasesByNote(filter NoteFilter) ([]Release, error) {
+	fields, err := s.GetCollectionFields()
+	if err != nil {
+		return nil, err
+	}
+
+	field, err := findCollectionField(fields, filter.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	condition, args, err := noteCondition(field, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	releases, err := s.getReleases(`
+		WHERE r.archived = FALSE AND r.id IN (
+			SELECT release_id FROM release_notes WHERE field_id = ? AND `+condition+`
+		)`, append([]any{field.ID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	if releases == nil {
+		releases = []Release{}
+	}
+
+	return releases, nil
+}
+
+// findCollectionField looks a field up by ID or, ignoring case, by name
+func findCollectionField(fields []CollectionField, nameOrID string) (CollectionField, error) {
+	id, idErr := strconv.Atoi(nameOrID)
+	for _, field := range fields {
+		if (idErr == nil && field.ID == id) || strings.EqualFold(field.Name, strings.TrimSpace(nameOrID)) {
+			return field, nil
+		}
+	}
+
+	return CollectionField{}, fmt.Errorf("%w: %q", ErrFieldNotFound, nameOrID)
+}
+
+func noteCondition(field CollectionField, filter NoteFilter) (string, []any, error) {
+	if field.Type != CollectionFieldDropdown {
+		switch filter.Op {
+		case NoteFilterEq:
+			return "value = ?", []any{filter.Value}, nil
+		case NoteFilterNe:
+			return "value != ?", []any{filter.Value}, nil
+		case NoteFilterContains:
+			return "instr(lower(value), lower(?)) > 0", []any{filter.Value}, nil
+		}
+		return "", nil, fmt.Errorf("%w: %q is not supported for %s", ErrInvalidNoteFilter, filter.Op, field.Name)
+	}
+
+	index := optionIndex(field.Options, filter.Value)
+	if index < 0 {
+		return "", nil, fmt.Errorf(
+			"%w: %q is not an option for %s", ErrInvalidNoteFilter, filter.Value, field.Name)
+	}
+
+	var options []string
+	switch filter.Op {
+	case NoteFilterEq:
+		return "value = ?", []any{field.Options[index]}, nil
+	case NoteFilterNe:
+		return "value != ?", []any{field.Options[index]}, nil
+	case NoteFilterGte:
+		// Discogs lists grades best first, so "at least" is everything up to
+		// and including the option
+		options = field.Options[:index+1]
+	case NoteFilterLte:
+		options = field.Options[index:]
+	default:
+		return "", nil, fmt.Errorf("%w: %q is not supported for %s", ErrInvalidNoteFilter, filter.Op, field.Name)
+	}
+
+	args := make([]any, len(options))
+	for i, option := range options {
+		args[i] = option
+	}
+
+	return "value IN (" + strings.TrimSuffix(strings.Repeat("?,", len(options)), ",") + ")", args, nil
+}
+
+// optionIndex finds value among a dropdown's options, either in full or by the
+// abbreviation in brackets, so "VG+" and "nm" match "Very Good Plus (VG+)" and
+// "Near Mint (NM or M-)". It returns -1 when nothing matches.
+func optionIndex(options []string, value string) int {
+	value = strings.TrimSpace(value)
+	for i, option := range options {
+		if strings.EqualFold(option, value) {
+			return i
+		}
+	}
+
+	for i, option := range options {
+		start, end := strings.LastIndex(option, "("), strings.LastIndex(option, ")")
+		if start < 0 || end < start {
+			continue
+		}
+		for _, abbreviation := range strings.Split(option[start+1:end], " or ") {
+			if strings.EqualFold(strings.TrimSpace(abbreviation), value) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// SaveCollectionValueSnapshot stores a snapshot along with the number of
+// releases in the collection now
+func (s *Database) SaveCollectionValueSnapshot(snapshot CollectionValueSnapshot) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO collection_value_snapshots (sync_id, minimum, median, maximum, currency, release_count)
+		VALUES (?, ?, ?, ?, ?, (SELECT COUNT(*) FROM releases WHERE archived = FALSE))`,
+		snapshot.SyncID,
+		snapshot.Minimum,
+		snapshot.Median,
+		snapshot.Maximum,
+		snapshot.Currency,
+	)
+	if err != nil {
+		slog.Error("Failed to save collection value snapshot", "error", err)
+	}
+
+	return err
+}
+
+// GetCollectionValueSnapshots returns the snapshots taken since the given
+// time, oldest first
+func (s *Database) GetCollectionValueSnapshots(since time.Time) ([]CollectionValueSnapshot, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, sync_id, minimum, median, maximum, currency, release_count, created_at
+		FROM collection_value_snapshots
+		WHERE created_at >= ?
+		ORDER BY created_at, id`,
+		since.UTC().Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		slog.Error("Failed to get collection value snapshots", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []CollectionValueSnapshot{}
+	for rows.Next() {
+		var snapshot CollectionValueSnapshot
+		err := rows.Scan(
+			&snapshot.ID,
+			&snapshot.SyncID,
+			&snapshot.Minimum,
+			&snapshot.Median,
+			&snapshot.Maximum,
+			&snapshot.Currency,
+			&snapshot.ReleaseCount,
+			&snapshot.CreatedAt,
+		)
+		if err != nil {
+			slog.Error("Failed to scan collection value snapshot", "error", err)
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// GetReleasesDueForPriceCheck returns up to limit active releases whose
+// marketplace data is missing or older than maxAge, least recently checked
+// first
+func (s *Database) GetReleasesDueForPriceCheck(maxAge time.Duration, limit int) ([]int, error) {
+	rows, err := s.DB.Query(`
+		SELECT r.id
+		FROM releases r
+		LEFT JOIN release_prices p ON p.release_id = r.id
+		WHERE r.archived = FALSE
+		AND (p.checked_at IS NULL OR p.checked_at < datetime('now', '-' || ? || ' seconds'))
+		ORDER BY p.checked_at IS NOT NULL, p.checked_at, r.id
+		LIMIT ?`,
+		int(maxAge.Seconds()),
+		limit,
+	)
+	if err != nil {
+		slog.Error("Failed to get releases due for a price check", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var releaseIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			slog.Error("Failed to scan release id", "error", err)
+			return nil, err
+		}
+		releaseIDs = append(releaseIDs, id)
+	}
+
+	return releaseIDs, rows.Err()
+}
+
+func (s *Database) SaveReleasePrice(price ReleasePrice) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO release_prices (release_id, lowest_price, currency, num_for_sale, blocked_from_sale, checked_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(release_id) DO UPDATE SET
+			lowest_price = excluded.lowest_price,
+			currency = excluded.currency,
+			num_for_sale = excluded.num_for_sale,
+			blocked_from_sale = excluded.blocked_from_sale,
+			checked_at = excluded.checked_at`,
+		price.ReleaseID,
+		price.LowestPrice,
+		price.Currency,
+		price.NumForSale,
+		price.BlockedFromSale,
+	)
+	if err != nil {
+		slog.Error("Failed to save release price", "error", err, "releaseID", price.ReleaseID)
+	}
+
+	return err
+}
+
+// getReleasePrice returns nil when the release hasn't been priced yet
+func (s *Database) getReleasePrice(releaseID int) (*ReleasePrice, error) {
+	var price ReleasePrice
+	err := s.DB.QueryRow(`
+		SELECT release_id, lowest_price, currency, num_for_sale, blocked_from_sale, checked_at
+		FROM release_prices
+		WHERE release_id = ?`,
+		releaseID,
+	).Scan(
+		&price.ReleaseID,
+		&price.LowestPrice,
+		&price.Currency,
+		&price.NumForSale,
+		&price.BlockedFromSale,
+		&price.CheckedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Failed to get release price", "error", err, "releaseID", releaseID)
+		return nil, err
+	}
+
+	return &price, nil
+}
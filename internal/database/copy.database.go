@@ -0,0 +1,302 @@
+package database
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// seededTables are filled by migrations, so a new database isn't empty.
+// Their rows are replaced by a copy.
+var seededTables = map[string]bool{"styluses": true}
+
+// derivedTables are filled by triggers as the other tables are copied. They
+// are cleared and copied last, so they match the source exactly.
+var derivedTables = map[string]bool{"search_entries": true}
+
+// CopyTo copies every row into dest, which has to be a new database on the
+// same schema version, such as a Postgres database being moved to. It runs in
+// a single transaction on dest, so a failed copy leaves nothing behind.
+// Returns the number of rows copied per table.
+func (s *Database) CopyTo(dest *Database) (copied map[string]int64, err error) {
+	version, err := s.appliedVersion()
+	if err != nil {
+		return nil, err
+	}
+	destVersion, err := dest.appliedVersion()
+	if err != nil {
+		return nil, err
+	}
+	if version != destVersion {
+		return nil, fmt.Errorf(
+			"schema versions differ: the source is on %d and the destination on %d",
+			version,
+			destVersion,
+		)
+	}
+
+	tables, err := s.tables()
+	if err != nil {
+		return nil, err
+	}
+	destTables, err := dest.tables()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(destTables))
+	for _, table := range destTables {
+		known[table] = true
+
+		if seededTables[table] {
+			continue
+		}
+		var rows int
+		if err := dest.DB.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&rows); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		if rows > 0 {
+			return nil, fmt.Errorf("the destination already has data in %s", table)
+		}
+	}
+	for _, table := range tables {
+		if !known[table] {
+			return nil, fmt.Errorf("the destination has no %s table", table)
+		}
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		return !derivedTables[tables[i]] && derivedTables[tables[j]]
+	})
+
+	tx, err := dest.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin copy: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to roll back copy", "error", rollbackErr)
+			}
+		}
+	}()
+
+	copied = make(map[string]int64, len(tables))
+	for _, table := range tables {
+		if seededTables[table] || derivedTables[table] {
+			if _, err = tx.Exec("DELETE FROM " + table); err != nil {
+				return nil, fmt.Errorf("failed to clear %s: %w", table, err)
+			}
+		}
+
+		if copied[table], err = s.copyTable(tx, dest, table); err != nil {
+			return nil, err
+		}
+		slog.Info("Copied table", "table", table, "rows", copied[table])
+	}
+
+	if dest.dialect.name == postgresDialect.name {
+		if err = resetIdentities(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit copy: %w", err)
+	}
+
+	return copied, nil
+}
+
+// copyTable inserts every row of table into dest through tx. Columns the
+// destination lacks, left behind by old migrations, are skipped. Credentials
+// are decrypted and sealed again with dest's key.
+func (s *Database) copyTable(tx *Tx, dest *Database, table string) (int64, error) {
+	sourceColumns, err := s.columns(table)
+	if err != nil {
+		return 0, err
+	}
+	destColumns, err := dest.columns(table)
+	if err != nil {
+		return 0, err
+	}
+
+	known := make(map[string]bool, len(destColumns))
+	for _, column := range destColumns {
+		known[column] = true
+	}
+
+	secret := make(map[string]bool)
+	for _, column := range secretColumns {
+		if column[0] == table {
+			secret[column[1]] = true
+		}
+	}
+
+	var columns []string
+	var secretIndexes []int
+	for _, column := range sourceColumns {
+		if !known[column] {
+			slog.Warn("Skipping column the destination doesn't have", "table", table, "column", column)
+			continue
+		}
+		if secret[column] {
+			secretIndexes = append(secretIndexes, len(columns))
+		}
+		columns = append(columns, column)
+	}
+
+	rows, err := s.DB.Query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), table))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	insert, err := tx.Prepare(fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		table,
+		strings.Join(columns, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "),
+	))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare insert into %s: %w", table, err)
+	}
+	defer insert.Close()
+
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	var count int64
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return count, fmt.Errorf("failed to read %s: %w", table, err)
+		}
+		for i, value := range values {
+			// SQLite hands back text it doesn't know the type of as bytes,
+			// which Postgres would store as bytea
+			if bytes, ok := value.([]byte); ok {
+				values[i] = string(bytes)
+			}
+		}
+		for _, i := range secretIndexes {
+			stored, ok := values[i].(string)
+			if !ok {
+				continue
+			}
+			plain, err := s.openSecret(stored)
+			if err != nil {
+				return count, fmt.Errorf("failed to copy a credential in %s: %w", table, err)
+			}
+			if values[i], err = dest.sealSecret(plain); err != nil {
+				return count, fmt.Errorf("failed to copy a credential in %s: %w", table, err)
+			}
+		}
+
+		if _, err := insert.Exec(values...); err != nil {
+			return count, fmt.Errorf("failed to copy a row of %s: %w", table, err)
+		}
+		count++
+	}
+
+	return count, rows.Err()
+}
+
+// resetIdentities moves each identity column's sequence past the copied ids,
+// so new rows don't collide with them
+func resetIdentities(tx *Tx) error {
+	rows, err := tx.Query(`
+		SELECT table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND is_identity = 'YES'`)
+	if err != nil {
+		return fmt.Errorf("failed to find identity columns: %w", err)
+	}
+
+	var identities [][2]string
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			rows.Close()
+			return err
+		}
+		identities = append(identities, [2]string{table, column})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, identity := range identities {
+		table, column := identity[0], identity[1]
+		_, err := tx.Exec(fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+			column,
+			table,
+		), table, column)
+		if err != nil {
+			return fmt.Errorf("failed to reset the %s id sequence: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// appliedVersion is the newest migration recorded in the database
+func (s *Database) appliedVersion() (int, error) {
+	var version int
+	err := s.DB.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return version, nil
+}
+
+// tables lists the tables holding Kleio's data, leaving out schema_migrations
+// and the database's own. SQLite's search index is a virtual table, which
+// is rebuilt from search_entries rather than copied.
+func (s *Database) tables() ([]string, error) {
+	query := `
+		SELECT name FROM pragma_table_list
+		WHERE schema = 'main' AND type = 'table' AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		ORDER BY name`
+	if s.dialect.name == postgresDialect.name {
+		query = `
+			SELECT table_name FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+			ORDER BY table_name`
+	}
+
+	rows, err := s.DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		if table != "schema_migrations" {
+			tables = append(tables, table)
+		}
+	}
+
+	return tables, rows.Err()
+}
+
+// columns returns the columns of table in the order they were defined
+func (s *Database) columns(table string) ([]string, error) {
+	rows, err := s.DB.Query("SELECT * FROM " + table + " LIMIT 0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	return rows.Columns()
+}
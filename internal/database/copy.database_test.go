@@ -0,0 +1,163 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestPostgres migrates a new schema in the Postgres database at
+// KLEIO_TEST_POSTGRES_DSN, which is dropped again after the test. The test is
+// skipped when KLEIO_TEST_POSTGRES_DSN isn't set.
+func openTestPostgres(t *testing.T) *Database {
+	t.Helper()
+
+	databaseURL := os.Getenv("KLEIO_TEST_POSTGRES_DSN")
+	if databaseURL == "" {
+		t.Skip("KLEIO_TEST_POSTGRES_DSN is not set")
+	}
+	// Keeps the generated secret key out of the source tree
+	t.Setenv("DATA_DIR", t.TempDir())
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	schema := "kleio_test_" + hex.EncodeToString(suffix)
+
+	admin, err := sql.Open(postgresDialect.driverName, databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("failed to drop schema: %v", err)
+		}
+	})
+
+	// lib/pq passes settings it doesn't know on to the server
+	if parsed, err := url.Parse(databaseURL); err == nil && parsed.Scheme != "" {
+		query := parsed.Query()
+		query.Set("search_path", schema)
+		parsed.RawQuery = query.Encode()
+		databaseURL = parsed.String()
+	} else {
+		databaseURL += " search_path=" + schema
+	}
+
+	db, err := OpenPostgres(databaseURL)
+	if err != nil {
+		t.Fatalf("failed to open Postgres: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// openCopySource fills a new SQLite database with a couple of releases and a
+// signed in user
+func openCopySource(t *testing.T) *Database {
+	t.Helper()
+
+	db := openTestDatabase(t)
+	saveTestSync(t, db, "sync_1", []DiscogsRelease{
+		testRelease(100, 5001, 1, "Rumours"),
+		testRelease(200, 5002, 1, "Tusk"),
+	})
+	if err := db.SaveToken("discogs-token", "kleio"); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// checkCopy compares every table of source and dest, and checks the copied
+// credentials and search index work in dest
+func checkCopy(t *testing.T, source, dest *Database, copied map[string]int64) {
+	t.Helper()
+
+	tables, err := source.tables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		var sourceRows, destRows int64
+		if err := source.DB.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&sourceRows); err != nil {
+			t.Fatal(err)
+		}
+		if err := dest.DB.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&destRows); err != nil {
+			t.Fatal(err)
+		}
+		if sourceRows != destRows || copied[table] != sourceRows {
+			t.Errorf("%s has %d rows, copied %d into %d", table, sourceRows, copied[table], destRows)
+		}
+	}
+
+	// Credentials are sealed with the destination's key
+	token, err := dest.GetToken()
+	if err != nil {
+		t.Fatalf("failed to read copied token: %v", err)
+	}
+	if token != "discogs-token" {
+		t.Errorf("copied token = %q", token)
+	}
+
+	results, err := dest.Search("rumo", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Releases) != 1 || results.Releases[0].ReleaseID != 100 {
+		t.Errorf("search of the copy found %+v, want Rumours", results.Releases)
+	}
+}
+
+func TestCopyToSQLite(t *testing.T) {
+	t.Setenv("KLEIO_SECRET_KEY", "")
+	t.Setenv("DATA_DIR", "")
+
+	source := openCopySource(t)
+	// A separate directory, so the destination generates its own key
+	dest, err := Open(filepath.Join(t.TempDir(), "copy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dest.Close()
+
+	copied, err := source.CopyTo(dest)
+	if err != nil {
+		t.Fatalf("copy failed: %v", err)
+	}
+	checkCopy(t, source, dest, copied)
+
+	// A second copy would mix the data, so it is refused
+	if _, err := source.CopyTo(dest); err == nil || !strings.Contains(err.Error(), "already has data") {
+		t.Errorf("copy into a database with data returned %v", err)
+	}
+}
+
+func TestCopyToPostgres(t *testing.T) {
+	dest := openTestPostgres(t)
+	source := openCopySource(t)
+
+	copied, err := source.CopyTo(dest)
+	if err != nil {
+		t.Fatalf("copy failed: %v", err)
+	}
+	checkCopy(t, source, dest, copied)
+
+	// Identity sequences continue after the copied rows
+	id, err := dest.StartSync(SyncModeFull, "sync_after_copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id <= 1 {
+		t.Errorf("sync after the copy got id %d, which was copied", id)
+	}
+}
@@ -2,29 +2,129 @@ package database
 
 import (
 	"fmt"
+	"log/slog"
+	"time"
 )
 
-func (s *Database) SaveTracks(
+// SaveReleaseDetails replaces a release's tracklist, credits, identifiers
+// and companies with what the release resource has now
+func (s *Database) SaveReleaseDetails(
 	releaseID int,
+	metadata ReleaseMetadata,
 	tracks []Track,
-) error {
-	_, err := s.DB.Exec("DELETE FROM tracks WHERE release_id = ?", releaseID)
+) (err error) {
+	tx, err := s.DB.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to delete existing tracks: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	_, err = tx.Exec(`
+		UPDATE releases
+		SET country = NULLIF(?, ''),
+			released = NULLIF(?, ''),
+			master_id = COALESCE(?, master_id),
+			details_synced_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		metadata.Country,
+		metadata.Released,
+		metadata.MasterID,
+		releaseID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update release metadata: %w", err)
+	}
+
+	for _, table := range []string{"release_credits", "release_identifiers", "release_companies", "tracks"} {
+		if _, err = tx.Exec("DELETE FROM "+table+" WHERE release_id = ?", releaseID); err != nil {
+			return fmt.Errorf("failed to delete existing %s: %w", table, err)
+		}
 	}
 
 	for _, track := range tracks {
-		_, err := s.DB.Exec(
-			"INSERT INTO tracks (release_id, position, title, duration_text, duration_seconds) VALUES (?, ?, ?, ?, ?)",
+		var trackID int
+		err = tx.QueryRow(
+			"INSERT INTO tracks (release_id, position, title, duration_text, duration_seconds) VALUES (?, ?, ?, ?, ?) RETURNING id",
 			releaseID,
 			track.Position,
 			track.Title,
 			track.DurationText,
 			track.DurationSeconds,
-		)
+		).Scan(&trackID)
 		if err != nil {
 			return fmt.Errorf("failed to insert track: %w", err)
 		}
+
+		for _, credit := range track.Credits {
+			if err = insertCredit(tx, releaseID, &trackID, credit); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, credit := range metadata.Credits {
+		if err = insertCredit(tx, releaseID, nil, credit); err != nil {
+			return err
+		}
+	}
+
+	for _, identifier := range metadata.Identifiers {
+		_, err = tx.Exec(
+			"INSERT INTO release_identifiers (release_id, type, value, description) VALUES (?, ?, ?, ?)",
+			releaseID,
+			identifier.Type,
+			identifier.Value,
+			identifier.Description,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert identifier: %w", err)
+		}
+	}
+
+	for _, company := range metadata.Companies {
+		_, err = tx.Exec(`
+			INSERT INTO release_companies (release_id, company_id, name, entity_type_name, catno, resource_url)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			releaseID,
+			company.CompanyID,
+			company.Name,
+			company.EntityTypeName,
+			company.CatNo,
+			company.ResourceURL,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert company: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit release details: %w", err)
+	}
+
+	return nil
+}
+
+func insertCredit(tx *Tx, releaseID int, trackID *int, credit ReleaseCredit) error {
+	_, err := tx.Exec(`
+		INSERT INTO release_credits (release_id, track_id, artist_id, name, anv, role, tracks)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		releaseID,
+		trackID,
+		credit.ArtistID,
+		credit.Name,
+		credit.ANV,
+		credit.Role,
+		credit.Tracks,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert credit: %w", err)
 	}
 
 	return nil
@@ -47,3 +147,153 @@ func (s *Database) UpdateReleaseWithDetails(
 
 	return nil
 }
+
+// GetReleasesDueForRefresh returns up to limit active releases whose duration
+// was estimated and whose details are older than maxAge, oldest first, in
+// case Discogs has since gained track durations for them
+func (s *Database) GetReleasesDueForRefresh(maxAge time.Duration, limit int) ([]int, error) {
+	rows, err := s.DB.Query(`
+		SELECT id
+		FROM releases
+		WHERE archived = FALSE
+		AND play_duration_estimated = TRUE
+		AND details_synced_at < datetime('now', '-' || ? || ' seconds')
+		ORDER BY details_synced_at, id
+		LIMIT ?`,
+		int(maxAge.Seconds()),
+		limit,
+	)
+	if err != nil {
+		slog.Error("Failed to get releases due for a refresh", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var releaseIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			slog.Error("Failed to scan release id", "error", err)
+			return nil, err
+		}
+		releaseIDs = append(releaseIDs, id)
+	}
+
+	return releaseIDs, rows.Err()
+}
+
+// loadReleaseDetails attaches identifiers, companies and credits to a
+// release. Track credits go on the matching track in release.Tracks.
+func (s *Database) loadReleaseDetails(release *Release) error {
+	rows, err := s.DB.Query(`
+		SELECT id, release_id, type, value, description
+		FROM release_identifiers
+		WHERE release_id = ?
+		ORDER BY id`,
+		release.ID,
+	)
+	if err != nil {
+		slog.Error("Failed to get release identifiers", "error", err, "releaseID", release.ID)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var identifier ReleaseIdentifier
+		err := rows.Scan(
+			&identifier.ID,
+			&identifier.ReleaseID,
+			&identifier.Type,
+			&identifier.Value,
+			&identifier.Description,
+		)
+		if err != nil {
+			slog.Error("Failed to scan release identifier", "error", err)
+			return err
+		}
+		release.Identifiers = append(release.Identifiers, identifier)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.DB.Query(`
+		SELECT id, release_id, company_id, name, entity_type_name, catno, resource_url
+		FROM release_companies
+		WHERE release_id = ?
+		ORDER BY id`,
+		release.ID,
+	)
+	if err != nil {
+		slog.Error("Failed to get release companies", "error", err, "releaseID", release.ID)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var company ReleaseCompany
+		err := rows.Scan(
+			&company.ID,
+			&company.ReleaseID,
+			&company.CompanyID,
+			&company.Name,
+			&company.EntityTypeName,
+			&company.CatNo,
+			&company.ResourceURL,
+		)
+		if err != nil {
+			slog.Error("Failed to scan release company", "error", err)
+			return err
+		}
+		release.Companies = append(release.Companies, company)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.DB.Query(`
+		SELECT id, release_id, track_id, artist_id, name, anv, role, tracks
+		FROM release_credits
+		WHERE release_id = ?
+		ORDER BY id`,
+		release.ID,
+	)
+	if err != nil {
+		slog.Error("Failed to get release credits", "error", err, "releaseID", release.ID)
+		return err
+	}
+	defer rows.Close()
+
+	trackIndex := make(map[int]int, len(release.Tracks))
+	for i, track := range release.Tracks {
+		trackIndex[track.ID] = i
+	}
+
+	for rows.Next() {
+		var credit ReleaseCredit
+		err := rows.Scan(
+			&credit.ID,
+			&credit.ReleaseID,
+			&credit.TrackID,
+			&credit.ArtistID,
+			&credit.Name,
+			&credit.ANV,
+			&credit.Role,
+			&credit.Tracks,
+		)
+		if err != nil {
+			slog.Error("Failed to scan release credit", "error", err)
+			return err
+		}
+
+		if credit.TrackID == nil {
+			release.Credits = append(release.Credits, credit)
+			continue
+		}
+		if i, ok := trackIndex[*credit.TrackID]; ok {
+			release.Tracks[i].Credits = append(release.Tracks[i].Credits, credit)
+		}
+	}
+
+	return rows.Err()
+}
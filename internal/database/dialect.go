@@ -0,0 +1,231 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// dialect is the SQL a database speaks. Queries are written for SQLite and
+// rewritten for the others when they are run, so each is only written once.
+type dialect struct {
+	name       string
+	driverName string
+	// migrations is the embedded directory holding the dialect's schema
+	migrations string
+	rewrite    func(query string) string
+}
+
+var (
+	sqliteDialect = dialect{
+		name:       "sqlite",
+		driverName: "sqlite3",
+		migrations: "migrations",
+		rewrite:    func(query string) string { return query },
+	}
+	postgresDialect = dialect{
+		name:       "postgres",
+		driverName: "postgres",
+		migrations: "migrations/postgres",
+		rewrite:    rewriteForPostgres,
+	}
+)
+
+// DB is a connection pool that rewrites queries for its dialect. Everything
+// other than running queries is left to the embedded sql.DB.
+type DB struct {
+	*sql.DB
+	dialect dialect
+}
+
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.DB.Exec(db.dialect.rewrite(query), args...)
+}
+
+func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.DB.Query(db.dialect.rewrite(query), args...)
+}
+
+func (db *DB) QueryRow(query string, args ...any) *sql.Row {
+	return db.DB.QueryRow(db.dialect.rewrite(query), args...)
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{Tx: tx, dialect: db.dialect}, nil
+}
+
+// Tx is a transaction that rewrites queries for its dialect
+type Tx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.Tx.Exec(tx.dialect.rewrite(query), args...)
+}
+
+func (tx *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.dialect.rewrite(query), args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRow(tx.dialect.rewrite(query), args...)
+}
+
+func (tx *Tx) Prepare(query string) (*sql.Stmt, error) {
+	return tx.Tx.Prepare(tx.dialect.rewrite(query))
+}
+
+// rewriteForPostgres turns the SQLite in a query into Postgres:
+//
+//   - ? placeholders become $1, $2...
+//   - json_group_array becomes json_agg, giving [] rather than NULL for no rows
+//   - json_object becomes json_build_object
+//   - instr becomes strpos
+//   - datetime('now', modifier) becomes CURRENT_TIMESTAMP plus the modifier as
+//     an interval, which covers modifiers like '-1 hour' and '+60 seconds'
+//
+// Anything else has to be valid in both, such as ON CONFLICT upserts and
+// RETURNING. String literals and comments are left alone.
+func rewriteForPostgres(query string) string {
+	r := postgresRewriter{}
+	return r.rewrite(query)
+}
+
+type postgresRewriter struct {
+	params int
+}
+
+func (r *postgresRewriter) rewrite(query string) string {
+	var out strings.Builder
+	out.Grow(len(query) + 16)
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'':
+			end := literalEnd(query, i)
+			out.WriteString(query[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end
+		case c == '?':
+			r.params++
+			out.WriteString("$" + strconv.Itoa(r.params))
+			i++
+		case isIdentByte(c) && (i == 0 || !isIdentByte(query[i-1])):
+			end := i
+			for end < len(query) && isIdentByte(query[end]) {
+				end++
+			}
+			word := query[i:end]
+
+			open := end
+			for open < len(query) && query[open] == ' ' {
+				open++
+			}
+			if open < len(query) && query[open] == '(' {
+				if close := closingParen(query, open); close > 0 {
+					if call, ok := r.function(strings.ToLower(word), query[open+1:close]); ok {
+						out.WriteString(call)
+						i = close + 1
+						continue
+					}
+				}
+			}
+
+			out.WriteString(word)
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.String()
+}
+
+// function rewrites a call to one of the SQLite functions Postgres lacks,
+// reporting false for any other function
+func (r *postgresRewriter) function(name, args string) (string, bool) {
+	switch name {
+	case "json_group_array":
+		return "COALESCE(json_agg(" + r.rewrite(args) + "), '[]')", true
+	case "json_object":
+		return "json_build_object(" + r.rewrite(args) + ")", true
+	case "instr":
+		return "strpos(" + r.rewrite(args) + ")", true
+	case "datetime":
+		timeValue, modifier, _ := strings.Cut(args, ",")
+		if strings.TrimSpace(timeValue) != "'now'" {
+			return "", false
+		}
+		modifier = strings.TrimSpace(modifier)
+		if modifier == "" {
+			return "CURRENT_TIMESTAMP", true
+		}
+		return "(CURRENT_TIMESTAMP + CAST(" + r.rewrite(modifier) + " AS INTERVAL))", true
+	}
+
+	return "", false
+}
+
+// literalEnd returns the index just past the string literal starting at i
+func literalEnd(query string, i int) int {
+	for j := i + 1; j < len(query); j++ {
+		if query[j] != '\'' {
+			continue
+		}
+		// A doubled quote is an escaped one
+		if j+1 < len(query) && query[j+1] == '\'' {
+			j++
+			continue
+		}
+		return j + 1
+	}
+
+	return len(query)
+}
+
+// closingParen returns the index of the parenthesis closing the one at open,
+// or -1 if it isn't closed
+func closingParen(query string, open int) int {
+	depth := 0
+	for i := open; i < len(query); i++ {
+		switch query[i] {
+		case '\'':
+			i = literalEnd(query, i) - 1
+		case '-':
+			if strings.HasPrefix(query[i:], "--") {
+				if end := strings.IndexByte(query[i:], '\n'); end > 0 {
+					i += end
+				} else {
+					return -1
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,80 @@
+package database
+
+import "testing"
+
+func TestRewriteForPostgres(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "placeholders",
+			query: "SELECT * FROM releases WHERE id = ? AND folder_id = ?",
+			want:  "SELECT * FROM releases WHERE id = $1 AND folder_id = $2",
+		},
+		{
+			name:  "question mark in a string literal",
+			query: "SELECT 'why?' || title, 'it''s ?' FROM releases WHERE id = ?",
+			want:  "SELECT 'why?' || title, 'it''s ?' FROM releases WHERE id = $1",
+		},
+		{
+			name:  "comments",
+			query: "SELECT id -- is it archived? instr(x, ?)\nFROM releases WHERE archived = ?",
+			want:  "SELECT id -- is it archived? instr(x, ?)\nFROM releases WHERE archived = $1",
+		},
+		{
+			name:  "comment on the last line",
+			query: "SELECT ? -- json_object(?)",
+			want:  "SELECT $1 -- json_object(?)",
+		},
+		{
+			name:  "datetime now",
+			query: "UPDATE syncs SET sync_end = datetime('now') WHERE id = ?",
+			want:  "UPDATE syncs SET sync_end = CURRENT_TIMESTAMP WHERE id = $1",
+		},
+		{
+			name:  "datetime with a modifier",
+			query: "SELECT * FROM syncs WHERE sync_start < datetime('now', '-1 hour')",
+			want:  "SELECT * FROM syncs WHERE sync_start < (CURRENT_TIMESTAMP + CAST('-1 hour' AS INTERVAL))",
+		},
+		{
+			name:  "datetime with a nested modifier",
+			query: "VALUES (?, datetime('now', '+' || ? || ' seconds'), ?)",
+			want:  "VALUES ($1, (CURRENT_TIMESTAMP + CAST('+' || $2 || ' seconds' AS INTERVAL)), $3)",
+		},
+		{
+			name:  "datetime inside another function",
+			query: "SELECT json_object('due', datetime('now', '+1 day'), 'id', ?)",
+			want:  "SELECT json_build_object('due', (CURRENT_TIMESTAMP + CAST('+1 day' AS INTERVAL)), 'id', $1)",
+		},
+		{
+			name:  "datetime of a column is left alone",
+			query: "SELECT datetime(played_at, 'localtime') FROM play_history WHERE id = ?",
+			want:  "SELECT datetime(played_at, 'localtime') FROM play_history WHERE id = $1",
+		},
+		{
+			name:  "nested json functions",
+			query: "SELECT json_group_array(json_object('name', a.name, 'pos', instr(a.name, ?))) FROM artists a WHERE a.id > ?",
+			want:  "SELECT COALESCE(json_agg(json_build_object('name', a.name, 'pos', strpos(a.name, $1))), '[]') FROM artists a WHERE a.id > $2",
+		},
+		{
+			name:  "function names are matched as whole words",
+			query: "SELECT my_instr(x), json_objects FROM t WHERE y = ?",
+			want:  "SELECT my_instr(x), json_objects FROM t WHERE y = $1",
+		},
+		{
+			name:  "parenthesis in a literal",
+			query: "SELECT json_object('a)', ?)",
+			want:  "SELECT json_build_object('a)', $1)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := rewriteForPostgres(test.query); got != test.want {
+				t.Errorf("rewrite of\n  %s\ngot\n  %s\nwant\n  %s", test.query, got, test.want)
+			}
+		})
+	}
+}
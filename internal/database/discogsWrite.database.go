@@ -0,0 +1,346 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+// ErrFolderNotFound is returned when moving a release to a folder that isn't
+// in the collection
+var ErrFolderNotFound = errors.New("folder not found")
+
+// UpdateReleaseRating sets a release's rating locally and queues the change
+// for Discogs. It returns sql.ErrNoRows when the release doesn't exist.
+func (s *Database) UpdateReleaseRating(releaseID, rating int) (err error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	if err = queueDiscogsWrite(tx, releaseID, &rating, nil); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("UPDATE releases SET rating = ? WHERE id = ?", rating, releaseID); err != nil {
+		slog.Error("Failed to update release rating", "error", err, "releaseID", releaseID)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MoveRelease moves a release to another folder locally and queues the move
+// for Discogs. It returns sql.ErrNoRows when the release doesn't exist and
+// ErrFolderNotFound when the folder doesn't.
+func (s *Database) MoveRelease(releaseID, folderID int) (err error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	// Folder 0 is "All", which every release is in and none can be moved to
+	var exists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM folders WHERE id = ? AND id != 0 AND deleted = FALSE)",
+		folderID,
+	).Scan(&exists)
+	if err != nil {
+		slog.Error("Failed to check folder", "error", err, "folderID", folderID)
+		return err
+	}
+	if !exists {
+		return ErrFolderNotFound
+	}
+
+	if err = queueDiscogsWrite(tx, releaseID, nil, &folderID); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("UPDATE releases SET folder_id = ? WHERE id = ?", folderID, releaseID); err != nil {
+		slog.Error("Failed to move release", "error", err, "releaseID", releaseID)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// queueDiscogsWrite merges a change into the release's pending write, or
+// queues a new one from the folder the release is in now. It must run before
+// the release itself is updated.
+func queueDiscogsWrite(tx *Tx, releaseID int, rating, folderID *int) error {
+	var instanceID, currentFolderID int
+	err := tx.QueryRow(
+		"SELECT instance_id, folder_id FROM releases WHERE id = ?",
+		releaseID,
+	).Scan(&instanceID, &currentFolderID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.Error("Failed to get release for Discogs write", "error", err, "releaseID", releaseID)
+		}
+		return err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE discogs_writes
+		SET rating = COALESCE(?, rating),
+			folder_id = COALESCE(?, folder_id),
+			revision = revision + 1,
+			attempts = 0,
+			last_error = NULL,
+			next_attempt_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE release_id = ? AND status = 'pending'`,
+		rating,
+		folderID,
+		releaseID,
+	)
+	if err != nil {
+		slog.Error("Failed to merge Discogs write", "error", err, "releaseID", releaseID)
+		return err
+	}
+
+	if merged, _ := result.RowsAffected(); merged > 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO discogs_writes (release_id, instance_id, from_folder_id, rating, folder_id)
+		VALUES (?, ?, ?, ?, ?)`,
+		releaseID,
+		instanceID,
+		currentFolderID,
+		rating,
+		folderID,
+	)
+	if err != nil {
+		slog.Error("Failed to queue Discogs write", "error", err, "releaseID", releaseID)
+		return err
+	}
+
+	return nil
+}
+
+const discogsWriteColumns = `
+	id, release_id, instance_id, from_folder_id, rating, folder_id, status,
+	revision, attempts, last_error, next_attempt_at, created_at, updated_at`
+
+func scanDiscogsWrite(row rowScanner, write *DiscogsWrite) error {
+	return row.Scan(
+		&write.ID,
+		&write.ReleaseID,
+		&write.InstanceID,
+		&write.FromFolderID,
+		&write.Rating,
+		&write.FolderID,
+		&write.Status,
+		&write.Revision,
+		&write.Attempts,
+		&write.LastError,
+		&write.NextAttemptAt,
+		&write.CreatedAt,
+		&write.UpdatedAt,
+	)
+}
+
+func (s *Database) queryDiscogsWrites(query string, args ...any) ([]DiscogsWrite, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		slog.Error("Failed to query Discogs writes", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	writes := []DiscogsWrite{}
+	for rows.Next() {
+		var write DiscogsWrite
+		if err := scanDiscogsWrite(rows, &write); err != nil {
+			slog.Error("Failed to scan Discogs write", "error", err)
+			return nil, err
+		}
+		writes = append(writes, write)
+	}
+
+	return writes, rows.Err()
+}
+
+// GetDueDiscogsWrites returns pending writes whose next attempt is due, oldest first
+func (s *Database) GetDueDiscogsWrites() ([]DiscogsWrite, error) {
+	return s.queryDiscogsWrites(`
+		SELECT ` + discogsWriteColumns + `
+		FROM discogs_writes
+		WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+		ORDER BY id`)
+}
+
+// GetDiscogsWrites returns the most recent writes of every status, newest first
+func (s *Database) GetDiscogsWrites(limit int) ([]DiscogsWrite, error) {
+	return s.queryDiscogsWrites(`
+		SELECT `+discogsWriteColumns+`
+		FROM discogs_writes
+		ORDER BY id DESC
+		LIMIT ?`,
+		limit,
+	)
+}
+
+// CompleteDiscogsWrite marks a write as pushed. Discogs now has the pushed
+// values, so the instance is in the pushed folder from here on. A write that
+// has had another change merged in since it was read stays pending with only
+// what was merged in, pushed from that folder.
+func (s *Database) CompleteDiscogsWrite(write DiscogsWrite) (err error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			slog.Error("Failed to complete Discogs write", "error", err, "writeID", write.ID)
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	var current DiscogsWrite
+	err = scanDiscogsWrite(
+		tx.QueryRow("SELECT "+discogsWriteColumns+" FROM discogs_writes WHERE id = ?", write.ID),
+		&current,
+	)
+	if err != nil {
+		return err
+	}
+
+	fromFolderID := current.FromFolderID
+	if write.FolderID != nil {
+		fromFolderID = *write.FolderID
+	}
+
+	// Whatever still differs from what was pushed was merged in meanwhile
+	rating, folderID := current.Rating, current.FolderID
+	if current.Revision != write.Revision {
+		if sameValue(rating, write.Rating) {
+			rating = nil
+		}
+		if sameValue(folderID, write.FolderID) {
+			folderID = nil
+		}
+	}
+
+	if current.Status != DiscogsWritePending || current.Revision == write.Revision || (rating == nil && folderID == nil) {
+		_, err = tx.Exec(`
+			UPDATE discogs_writes
+			SET status = 'done',
+				from_folder_id = ?,
+				attempts = attempts + 1,
+				last_error = NULL,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?`,
+			fromFolderID,
+			write.ID,
+		)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE discogs_writes
+			SET from_folder_id = ?,
+				rating = ?,
+				folder_id = ?,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?`,
+			fromFolderID,
+			rating,
+			folderID,
+			write.ID,
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	// So the next sync doesn't take the pushed values for a change made on
+	// Discogs
+	_, err = tx.Exec(`
+		UPDATE release_instances
+		SET folder_id = COALESCE(?, folder_id),
+			rating = COALESCE(?, rating),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE instance_id = ?`,
+		write.FolderID,
+		write.Rating,
+		write.InstanceID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// sameValue reports whether two optional values are both unset or equal
+func sameValue(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// RetryDiscogsWrite records a failed attempt and schedules the next one
+// delaySeconds from now
+func (s *Database) RetryDiscogsWrite(write DiscogsWrite, lastError string, delaySeconds int) error {
+	_, err := s.DB.Exec(`
+		UPDATE discogs_writes
+		SET attempts = attempts + 1,
+			last_error = ?,
+			next_attempt_at = datetime('now', '+' || ? || ' seconds'),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND revision = ?`,
+		lastError,
+		delaySeconds,
+		write.ID,
+		write.Revision,
+	)
+	if err != nil {
+		slog.Error("Failed to reschedule Discogs write", "error", err, "writeID", write.ID)
+	}
+
+	return err
+}
+
+// FailDiscogsWrite gives up on a write. The next sync puts the Discogs values
+// back on the release.
+func (s *Database) FailDiscogsWrite(write DiscogsWrite, lastError string) error {
+	_, err := s.DB.Exec(`
+		UPDATE discogs_writes
+		SET status = 'failed', attempts = attempts + 1, last_error = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND revision = ?`,
+		lastError,
+		write.ID,
+		write.Revision,
+	)
+	if err != nil {
+		slog.Error("Failed to mark Discogs write failed", "error", err, "writeID", write.ID)
+	}
+
+	return err
+}
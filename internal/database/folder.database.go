@@ -1,12 +1,19 @@
 package database
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 )
 
+// GetFolders returns the folders that still exist on Discogs
 func (s *Database) GetFolders() ([]Folder, error) {
 	var folders []Folder
-	rows, err := s.DB.Query("SELECT * FROM folders")
+	rows, err := s.DB.Query(`
+		SELECT id, name, count, resource_url, created_at, updated_at
+		FROM folders
+		WHERE deleted = FALSE
+		ORDER BY id`)
 	if err != nil {
 		slog.Error("Failed to get folders", "error", err)
 		return nil, err
@@ -32,9 +39,138 @@ func (s *Database) GetFolders() ([]Folder, error) {
 	return folders, nil
 }
 
-func (database *Database) UpdateFolder(folder Folder) error {
-	_, err := dbInstance.DB.Exec(
-		"INSERT OR REPLACE INTO folders (id, name, count, resource_url) VALUES (?, ?, ?, ?)",
+// ReconcileFolders makes the stored folders match the ones on Discogs. New
+// folders are added, renamed ones updated and missing ones flagged deleted,
+// and each of those is recorded against the sync.
+func (s *Database) ReconcileFolders(syncID int64, folders []Folder) (changes []FolderChange, err error) {
+	if len(folders) == 0 {
+		return nil, errors.New("refusing to reconcile against an empty folder list")
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin transaction", "error", err)
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	type storedFolder struct {
+		name    string
+		deleted bool
+	}
+
+	rows, err := tx.Query("SELECT id, name, deleted FROM folders")
+	if err != nil {
+		slog.Error("Failed to get stored folders", "error", err)
+		return nil, err
+	}
+
+	stored := make(map[int]storedFolder)
+	for rows.Next() {
+		var id int
+		var folder storedFolder
+		if err = rows.Scan(&id, &folder.name, &folder.deleted); err != nil {
+			rows.Close()
+			slog.Error("Failed to scan stored folder", "error", err)
+			return nil, err
+		}
+		stored[id] = folder
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool, len(folders))
+	for _, folder := range folders {
+		seen[folder.ID] = true
+		name := folder.Name
+
+		existing, ok := stored[folder.ID]
+		switch {
+		case !ok:
+			changes = append(changes, FolderChange{FolderID: folder.ID, Type: FolderChangeAdded, NewName: &name})
+		case existing.deleted:
+			changes = append(changes, FolderChange{FolderID: folder.ID, Type: FolderChangeRestored, NewName: &name})
+		}
+
+		if ok && existing.name != folder.Name {
+			oldName := existing.name
+			changes = append(changes, FolderChange{
+				FolderID: folder.ID,
+				Type:     FolderChangeRenamed,
+				OldName:  &oldName,
+				NewName:  &name,
+			})
+		}
+
+		if err = upsertFolder(tx, folder); err != nil {
+			return nil, err
+		}
+	}
+
+	for id, folder := range stored {
+		if seen[id] || folder.deleted {
+			continue
+		}
+
+		_, err = tx.Exec(
+			"UPDATE folders SET deleted = TRUE, deleted_at = CURRENT_TIMESTAMP WHERE id = ?",
+			id,
+		)
+		if err != nil {
+			slog.Error("Failed to flag deleted folder", "error", err, "folderID", id)
+			return nil, err
+		}
+
+		oldName := folder.name
+		changes = append(changes, FolderChange{FolderID: id, Type: FolderChangeDeleted, OldName: &oldName})
+	}
+
+	for _, change := range changes {
+		_, err = tx.Exec(`
+			INSERT INTO folder_changes (sync_id, folder_id, change_type, old_name, new_name)
+			VALUES (?, ?, ?, ?, ?)`,
+			syncID,
+			change.FolderID,
+			change.Type,
+			change.OldName,
+			change.NewName,
+		)
+		if err != nil {
+			err = fmt.Errorf("failed to record %s change for folder %d: %w", change.Type, change.FolderID, err)
+			slog.Error("Failed to record folder change", "error", err)
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.Error("Failed to commit folder reconciliation", "error", err)
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// upsertFolder saves a folder in place, unlike INSERT OR REPLACE which would
+// delete and recreate the row
+func upsertFolder(tx *Tx, folder Folder) error {
+	_, err := tx.Exec(`
+		INSERT INTO folders (id, name, count, resource_url)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			count = excluded.count,
+			resource_url = excluded.resource_url,
+			deleted = FALSE,
+			deleted_at = NULL`,
 		folder.ID,
 		folder.Name,
 		folder.Count,
@@ -46,3 +182,40 @@ func (database *Database) UpdateFolder(folder Folder) error {
 
 	return err
 }
+
+// GetFolderChanges returns the folder changes a sync recorded
+func (s *Database) GetFolderChanges(syncID int64) ([]FolderChange, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, sync_id, folder_id, change_type, old_name, new_name, created_at
+		FROM folder_changes
+		WHERE sync_id = ?
+		ORDER BY id`,
+		syncID,
+	)
+	if err != nil {
+		slog.Error("Failed to query folder changes", "error", err, "syncID", syncID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []FolderChange{}
+	for rows.Next() {
+		var change FolderChange
+		err := rows.Scan(
+			&change.ID,
+			&change.SyncID,
+			&change.FolderID,
+			&change.Type,
+			&change.OldName,
+			&change.NewName,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			slog.Error("Failed to scan folder change", "error", err, "syncID", syncID)
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// imageSources lists every release's current artwork URLs, one row per kind
+const imageSources = `
+	SELECT id AS release_id, 'thumb' AS kind, thumb AS url FROM releases
+	WHERE archived = FALSE AND thumb IS NOT NULL AND thumb != ''
+	UNION ALL
+	SELECT id, 'cover', cover_image FROM releases
+	WHERE archived = FALSE AND cover_image IS NOT NULL AND cover_image != ''
+`
+
+// GetImagesToCache returns up to limit images whose current URL has not been
+// downloaded yet, skipping ones still backing off after a failure
+func (s *Database) GetImagesToCache(limit int) ([]ReleaseImage, error) {
+	rows, err := s.DB.Query(`
+		SELECT src.release_id, src.kind, src.url, COALESCE(ri.attempts, 0)
+		FROM (`+imageSources+`) src
+		LEFT JOIN release_images ri ON ri.release_id = src.release_id AND ri.kind = src.kind
+		WHERE ri.release_id IS NULL
+		OR ri.source_url != src.url
+		OR (ri.fetched_at IS NULL AND ri.next_attempt_at <= CURRENT_TIMESTAMP)
+		ORDER BY src.kind = 'cover', src.release_id
+		LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		slog.Error("Failed to get images to cache", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []ReleaseImage
+	for rows.Next() {
+		var image ReleaseImage
+		if err := rows.Scan(&image.ReleaseID, &image.Kind, &image.SourceURL, &image.Attempts); err != nil {
+			slog.Error("Failed to scan image to cache", "error", err)
+			return nil, err
+		}
+		images = append(images, image)
+	}
+
+	return images, rows.Err()
+}
+
+// SaveReleaseImage records a downloaded image
+func (s *Database) SaveReleaseImage(image ReleaseImage) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO release_images (
+			release_id, kind, source_url, hash, content_type, size, fetched_at,
+			attempts, last_error, next_attempt_at
+		) VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, 0, NULL, CURRENT_TIMESTAMP)
+		ON CONFLICT(release_id, kind) DO UPDATE SET
+			source_url = excluded.source_url,
+			hash = excluded.hash,
+			content_type = excluded.content_type,
+			size = excluded.size,
+			fetched_at = excluded.fetched_at,
+			attempts = 0,
+			last_error = NULL,
+			next_attempt_at = excluded.next_attempt_at`,
+		image.ReleaseID,
+		image.Kind,
+		image.SourceURL,
+		image.Hash,
+		image.ContentType,
+		image.Size,
+	)
+	if err != nil {
+		slog.Error("Failed to save release image", "error", err, "releaseID", image.ReleaseID, "kind", image.Kind)
+	}
+
+	return err
+}
+
+// RetryReleaseImage records a failed download of image.SourceURL and when to
+// try it again. Any copy of an earlier URL is kept and still served.
+func (s *Database) RetryReleaseImage(image ReleaseImage, lastError string, delaySeconds int) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO release_images (release_id, kind, source_url, attempts, last_error, next_attempt_at)
+		VALUES (?, ?, ?, 1, ?, datetime('now', '+' || ? || ' seconds'))
+		ON CONFLICT(release_id, kind) DO UPDATE SET
+			attempts = CASE WHEN source_url = excluded.source_url THEN attempts + 1 ELSE 1 END,
+			source_url = excluded.source_url,
+			fetched_at = NULL,
+			last_error = excluded.last_error,
+			next_attempt_at = excluded.next_attempt_at`,
+		image.ReleaseID,
+		image.Kind,
+		image.SourceURL,
+		lastError,
+		delaySeconds,
+	)
+	if err != nil {
+		slog.Error("Failed to reschedule release image", "error", err, "releaseID", image.ReleaseID, "kind", image.Kind)
+	}
+
+	return err
+}
+
+// GetReleaseImage returns a release's current artwork URL of the given kind
+// and whatever copy of it is cached. It returns sql.ErrNoRows when the release
+// doesn't exist.
+func (s *Database) GetReleaseImage(releaseID int, kind ImageKind) (ReleaseImage, error) {
+	column := "thumb"
+	if kind == ImageCover {
+		column = "cover_image"
+	}
+
+	image := ReleaseImage{ReleaseID: releaseID, Kind: kind}
+	var sourceURL, hash, contentType sql.NullString
+	var size sql.NullInt64
+	err := s.DB.QueryRow(`
+		SELECT r.`+column+`, ri.hash, ri.content_type, ri.size, ri.fetched_at, COALESCE(ri.attempts, 0)
+		FROM releases r
+		LEFT JOIN release_images ri ON ri.release_id = r.id AND ri.kind = ?
+		WHERE r.id = ?`,
+		kind,
+		releaseID,
+	).Scan(&sourceURL, &hash, &contentType, &size, &image.FetchedAt, &image.Attempts)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.Error("Failed to get release image", "error", err, "releaseID", releaseID, "kind", kind)
+		}
+		return ReleaseImage{}, err
+	}
+
+	image.SourceURL = sourceURL.String
+	image.Hash = hash.String
+	image.ContentType = contentType.String
+	image.Size = size.Int64
+	return image, nil
+}
+
+// DeleteStaleReleaseImages forgets the artwork of releases that have been
+// archived or deleted, so their files can be swept from the image store
+func (s *Database) DeleteStaleReleaseImages() (int64, error) {
+	result, err := s.DB.Exec(`
+		DELETE FROM release_images
+		WHERE release_id NOT IN (SELECT id FROM releases WHERE archived = FALSE)`)
+	if err != nil {
+		slog.Error("Failed to delete stale release images", "error", err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// GetReleaseImageHashes returns the hash of every cached image still in use
+func (s *Database) GetReleaseImageHashes() (map[string]bool, error) {
+	rows, err := s.DB.Query("SELECT DISTINCT hash FROM release_images WHERE hash IS NOT NULL")
+	if err != nil {
+		slog.Error("Failed to get release image hashes", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			slog.Error("Failed to scan release image hash", "error", err)
+			return nil, err
+		}
+		hashes[hash] = true
+	}
+
+	return hashes, rows.Err()
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// GetMasters returns every master with at least minPressings owned pressings,
+// with play statistics when withStats is set
+func (s *Database) GetMasters(minPressings int, withStats bool) ([]Master, error) {
+	return s.getMasters("WHERE m.pressings >= ?", []any{minPressings}, withStats)
+}
+
+// GetMaster returns one master and its owned pressings. It returns
+// sql.ErrNoRows when no release in the collection belongs to it.
+func (s *Database) GetMaster(id int, withStats bool) (Master, error) {
+	masters, err := s.getMasters("WHERE m.id = ?", []any{id}, withStats)
+	if err != nil {
+		return Master{}, err
+	}
+	if len(masters) == 0 {
+		return Master{}, sql.ErrNoRows
+	}
+
+	return masters[0], nil
+}
+
+func (s *Database) getMasters(where string, args []any, withStats bool) ([]Master, error) {
+	rows, err := s.DB.Query(`
+		SELECT
+			m.id, m.title, m.year,
+			r.id, r.title, r.year, r.country, r.released, r.folder_id, r.thumb
+		FROM masters m
+		JOIN releases r ON r.master_id = m.id AND r.archived = FALSE
+		`+where+`
+		ORDER BY m.title, m.id, r.year, r.id`,
+		args...,
+	)
+	if err != nil {
+		slog.Error("Failed to get masters", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	masters := []Master{}
+	for rows.Next() {
+		var master Master
+		var pressing MasterPressing
+		err := rows.Scan(
+			&master.ID,
+			&master.Title,
+			&master.Year,
+			&pressing.ReleaseID,
+			&pressing.Title,
+			&pressing.Year,
+			&pressing.Country,
+			&pressing.Released,
+			&pressing.FolderID,
+			&pressing.Thumb,
+		)
+		if err != nil {
+			slog.Error("Failed to scan master", "error", err)
+			return nil, err
+		}
+
+		if len(masters) == 0 || masters[len(masters)-1].ID != master.ID {
+			masters = append(masters, master)
+		}
+		last := &masters[len(masters)-1]
+		last.Pressings = append(last.Pressings, pressing)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating master rows", "error", err)
+		return nil, err
+	}
+
+	if withStats && len(masters) > 0 {
+		if err := s.attachMasterStats(masters); err != nil {
+			return nil, err
+		}
+	}
+
+	return masters, nil
+}
+
+// attachMasterStats adds play statistics to each pressing and rolls them up
+// to the master, counting plays of copies that have since been archived
+func (s *Database) attachMasterStats(masters []Master) error {
+	rows, err := s.DB.Query(`
+		SELECT
+			r.id,
+			r.master_id,
+			COUNT(ph.id),
+			MAX(ph.played_at),
+			COUNT(ph.id) * COALESCE(r.play_duration, 0)
+		FROM releases r
+		JOIN play_history ph ON ph.release_id = r.id
+		WHERE r.master_id IS NOT NULL
+		GROUP BY r.id`)
+	if err != nil {
+		slog.Error("Failed to get master play stats", "error", err)
+		return err
+	}
+	defer rows.Close()
+
+	byRelease := make(map[int]PlayStats)
+	byMaster := make(map[int]*PlayStats)
+	for rows.Next() {
+		var releaseID, masterID int
+		var stats PlayStats
+		var lastPlayed sql.NullString
+		err := rows.Scan(&releaseID, &masterID, &stats.PlayCount, &lastPlayed, &stats.PlaySeconds)
+		if err != nil {
+			slog.Error("Failed to scan master play stats", "error", err)
+			return err
+		}
+		if lastPlayed.Valid {
+			playedAt := parseTime(lastPlayed.String)
+			stats.LastPlayedAt = &playedAt
+		}
+		byRelease[releaseID] = stats
+
+		total, ok := byMaster[masterID]
+		if !ok {
+			total = &PlayStats{}
+			byMaster[masterID] = total
+		}
+		total.PlayCount += stats.PlayCount
+		total.PlaySeconds += stats.PlaySeconds
+		if stats.LastPlayedAt != nil &&
+			(total.LastPlayedAt == nil || stats.LastPlayedAt.After(*total.LastPlayedAt)) {
+			total.LastPlayedAt = stats.LastPlayedAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range masters {
+		master := &masters[i]
+		master.Stats = &PlayStats{}
+		if total, ok := byMaster[master.ID]; ok {
+			master.Stats = total
+		}
+
+		for j := range master.Pressings {
+			stats := byRelease[master.Pressings[j].ReleaseID]
+			master.Pressings[j].Stats = &stats
+		}
+	}
+
+	return nil
+}
+
+// GetPlayCountByMaster gets the number of plays for each release counting
+// the plays of every pressing of its master. Releases without a master only
+// count their own plays.
+func (s *Database) GetPlayCountByMaster() (map[int]int, error) {
+	rows, err := s.DB.Query(`
+		SELECT r.id, COUNT(ph.id)
+		FROM releases r
+		JOIN releases p ON p.id = r.id OR p.master_id = r.master_id
+		JOIN play_history ph ON ph.release_id = p.id
+		GROUP BY r.id`)
+	if err != nil {
+		slog.Error("Failed to get play counts by master", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	playCounts := make(map[int]int)
+	for rows.Next() {
+		var releaseID, count int
+		if err := rows.Scan(&releaseID, &count); err != nil {
+			slog.Error("Failed to scan play count", "error", err)
+			return playCounts, err
+		}
+		playCounts[releaseID] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		slog.Error("Error iterating play count rows", "error", err)
+		return playCounts, err
+	}
+
+	return playCounts, nil
+}
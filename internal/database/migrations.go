@@ -0,0 +1,280 @@
+package database
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+// legacyMigrationVersion is the last migration from before schema_migrations
+// existed. The old runner re-executed every file on each boot, so databases
+// created back then already have everything up to this version applied.
+const legacyMigrationVersion = 11
+
+type Migration struct {
+	Version  int
+	Name     string
+	Checksum string
+	SQL      string
+}
+
+// loadMigrations reads a dialect's embedded migration files in version order
+func loadMigrations(d dialect) ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, d.migrations+"/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find migration files: %w", err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, file := range files {
+		name := path.Base(file)
+		prefix, _, found := strings.Cut(name, "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s has no version prefix", name)
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has an invalid version prefix: %w", name, err)
+		}
+
+		if existing, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", existing, name, version)
+		}
+		seen[version] = name
+
+		content, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %w", name, err)
+		}
+
+		sum := sha256.Sum256(content)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     name,
+			Checksum: hex.EncodeToString(sum[:]),
+			SQL:      string(content),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// runMigrations applies every embedded migration for the database's dialect
+// that hasn't been recorded in schema_migrations, each in its own
+// transaction. It fails if an already applied migration no longer matches its
+// recorded checksum.
+//
+// Postgres starts from a single migration creating the schema SQLite has
+// reached by the same version, so versions line up between the two.
+func runMigrations(db *DB) error {
+	migrations, err := loadMigrations(db.dialect)
+	if err != nil {
+		return err
+	}
+
+	return applyMigrations(db, migrations)
+}
+
+// applyMigrations brings db up to date with migrations, which are in version
+// order
+func applyMigrations(db *DB, migrations []Migration) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version INTEGER PRIMARY KEY,
+		  name TEXT NOT NULL,
+		  checksum TEXT NOT NULL,
+		  applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	if db.dialect.name == sqliteDialect.name {
+		if err := baselineLegacyDatabase(db, migrations); err != nil {
+			return err
+		}
+	}
+
+	applied, err := getAppliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int]bool, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = true
+
+		checksum, ok := applied[migration.Version]
+		if ok {
+			if checksum != migration.Checksum {
+				return fmt.Errorf(
+					"migration %s has been modified after it was applied (checksum %s, expected %s)",
+					migration.Name,
+					migration.Checksum,
+					checksum,
+				)
+			}
+			continue
+		}
+
+		slog.Info("Applying migration...", "version", migration.Version, "name", migration.Name)
+		if err := applyMigration(db, migration); err != nil {
+			return err
+		}
+		slog.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+	}
+
+	for version := range applied {
+		if !known[version] {
+			slog.Warn("Database has a migration that is not in this build", "version", version)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(db *DB, migration Migration) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for migration %s: %w", migration.Name, err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback migration", "error", rollbackErr, "name", migration.Name)
+			}
+		}
+	}()
+
+	// Migrations are already in the dialect's SQL
+	if _, err = tx.Tx.Exec(migration.SQL); err != nil {
+		return fmt.Errorf("failed to execute migration %s: %w", migration.Name, err)
+	}
+
+	if err = recordMigration(tx, migration); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", migration.Name, err)
+	}
+
+	return nil
+}
+
+func recordMigration(tx *Tx, migration Migration) error {
+	_, err := tx.Exec(
+		"INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)",
+		migration.Version,
+		migration.Name,
+		migration.Checksum,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+	}
+
+	return nil
+}
+
+func getAppliedMigrations(db *DB) (map[int]string, error) {
+	rows, err := db.Query("SELECT version, checksum FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations row: %w", err)
+		}
+		applied[version] = checksum
+	}
+
+	return applied, rows.Err()
+}
+
+// baselineLegacyDatabase records the pre-versioning migrations as applied for
+// databases that were created by the old run-everything-on-boot runner, so the
+// data fix in 010 doesn't run again.
+func baselineLegacyDatabase(db *DB, migrations []Migration) (err error) {
+	var recorded int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&recorded); err != nil {
+		return fmt.Errorf("failed to count schema_migrations: %w", err)
+	}
+
+	var legacyTables int
+	err = db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'releases'",
+	).Scan(&legacyTables)
+	if err != nil {
+		return fmt.Errorf("failed to check for existing tables: %w", err)
+	}
+
+	if recorded > 0 || legacyTables == 0 {
+		return nil
+	}
+
+	// 012 was briefly applied by the old runner too; it only counts when its
+	// columns are already there.
+	var archiveColumns int
+	err = db.QueryRow(
+		"SELECT COUNT(*) FROM pragma_table_info('releases') WHERE name = 'archived'",
+	).Scan(&archiveColumns)
+	if err != nil {
+		return fmt.Errorf("failed to check for archive columns: %w", err)
+	}
+
+	slog.Info("Baselining database created before versioned migrations",
+		"legacyVersion", legacyMigrationVersion)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin baseline transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback baseline", "error", rollbackErr)
+			}
+		}
+	}()
+
+	for _, migration := range migrations {
+		legacy := migration.Version <= legacyMigrationVersion ||
+			(migration.Version == 12 && archiveColumns > 0)
+		if !legacy {
+			continue
+		}
+
+		if err = recordMigration(tx, migration); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit baseline: %w", err)
+	}
+
+	return nil
+}
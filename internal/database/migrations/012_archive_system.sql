@@ -0,0 +1,8 @@
+-- Soft archive support for releases removed from Discogs or archived by the user.
+-- Play and cleaning history keep referencing archived releases, so nothing is lost.
+ALTER TABLE releases ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE releases ADD COLUMN archived_at TIMESTAMP;
+ALTER TABLE releases ADD COLUMN archive_reason TEXT; -- 'sync_removed', 'user_action', 'duplicate', 'mistake'
+ALTER TABLE releases ADD COLUMN sync_session_id TEXT; -- Last sync session that saw this release on Discogs
+
+CREATE INDEX IF NOT EXISTS idx_releases_archived ON releases(archived);
@@ -0,0 +1,7 @@
+-- When the release was added to the Discogs collection, used to stop incremental syncs
+ALTER TABLE releases ADD COLUMN date_added TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_releases_instance_id ON releases(instance_id);
+
+-- 'full' syncs page through every folder, 'incremental' syncs only fetch newly added releases
+ALTER TABLE syncs ADD COLUMN mode TEXT NOT NULL DEFAULT 'full';
@@ -0,0 +1,14 @@
+-- Sync jobs checkpoint their progress so an interrupted sync resumes on startup
+-- 'folders', 'releases' or 'tracks'
+ALTER TABLE syncs ADD COLUMN phase TEXT NOT NULL DEFAULT 'folders';
+-- Reused on resume so releases saved before the restart still count as seen
+ALTER TABLE syncs ADD COLUMN session_id TEXT;
+-- Folder and page to fetch next during the releases phase
+ALTER TABLE syncs ADD COLUMN folder_id INTEGER;
+ALTER TABLE syncs ADD COLUMN page INTEGER NOT NULL DEFAULT 1;
+-- Releases handled so far in the current phase
+ALTER TABLE syncs ADD COLUMN releases_processed INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE syncs ADD COLUMN failed_folders INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE syncs ADD COLUMN checkpoint_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_syncs_status ON syncs(status);
@@ -0,0 +1,44 @@
+-- What each sync changed in the collection. release_id has no foreign key so
+-- the log outlives releases that are later deleted.
+CREATE TABLE IF NOT EXISTS sync_changes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  sync_id INTEGER NOT NULL,
+  release_id INTEGER NOT NULL,
+  title TEXT,
+  change_type TEXT NOT NULL, -- 'added', 'removed', 'restored', 'folder_moved', 'rating_changed', 'metadata_changed'
+  field TEXT, -- Column that changed for 'metadata_changed'
+  old_value TEXT,
+  new_value TEXT,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (sync_id) REFERENCES syncs(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_changes_sync_id ON sync_changes(sync_id);
+CREATE INDEX IF NOT EXISTS idx_sync_changes_release_id ON sync_changes(release_id);
+
+-- Only move releases.updated_at when the release itself changes, not every
+-- time a sync stamps it with its session
+DROP TRIGGER IF EXISTS releases_updated_at;
+
+CREATE TRIGGER IF NOT EXISTS releases_updated_at
+AFTER UPDATE ON releases
+FOR EACH ROW
+WHEN NEW.updated_at IS OLD.updated_at AND (
+  NEW.instance_id IS NOT OLD.instance_id
+  OR NEW.folder_id IS NOT OLD.folder_id
+  OR NEW.rating IS NOT OLD.rating
+  OR NEW.title IS NOT OLD.title
+  OR NEW.year IS NOT OLD.year
+  OR NEW.resource_url IS NOT OLD.resource_url
+  OR NEW.thumb IS NOT OLD.thumb
+  OR NEW.cover_image IS NOT OLD.cover_image
+  OR NEW.play_duration IS NOT OLD.play_duration
+  OR NEW.play_duration_estimated IS NOT OLD.play_duration_estimated
+  OR NEW.archived IS NOT OLD.archived
+  OR NEW.archive_reason IS NOT OLD.archive_reason
+  OR NEW.date_added IS NOT OLD.date_added
+)
+BEGIN
+  UPDATE releases SET updated_at = CURRENT_TIMESTAMP
+  WHERE id = OLD.id;
+END;
@@ -0,0 +1,18 @@
+-- Folders deleted on Discogs are flagged rather than removed, releases and
+-- their history may still point at them
+ALTER TABLE folders ADD COLUMN deleted BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE folders ADD COLUMN deleted_at TIMESTAMP;
+
+-- Folders added, renamed, deleted or restored by each sync
+CREATE TABLE IF NOT EXISTS folder_changes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  sync_id INTEGER NOT NULL,
+  folder_id INTEGER NOT NULL,
+  change_type TEXT NOT NULL, -- 'added', 'renamed', 'deleted', 'restored'
+  old_name TEXT,
+  new_name TEXT,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (sync_id) REFERENCES syncs(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_folder_changes_sync_id ON folder_changes(sync_id);
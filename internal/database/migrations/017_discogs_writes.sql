@@ -0,0 +1,23 @@
+-- Rating changes and folder moves made in Kleio, waiting to be pushed to the
+-- Discogs collection instance endpoint. A release has at most one pending
+-- write, later changes are merged into it.
+CREATE TABLE IF NOT EXISTS discogs_writes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  release_id INTEGER NOT NULL,
+  instance_id INTEGER NOT NULL,
+  from_folder_id INTEGER NOT NULL, -- Folder the instance is in on Discogs, part of the endpoint path
+  rating INTEGER, -- NULL when the rating isn't being changed
+  folder_id INTEGER, -- NULL when the release isn't being moved
+  status TEXT NOT NULL DEFAULT 'pending', -- 'pending', 'done', 'failed'
+  revision INTEGER NOT NULL DEFAULT 1, -- Bumped when a change is merged in, so an older push can't complete it
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_discogs_writes_pending
+ON discogs_writes(release_id) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_discogs_writes_status ON discogs_writes(status, next_attempt_at);
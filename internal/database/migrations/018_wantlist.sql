@@ -0,0 +1,23 @@
+-- Releases on the user's Discogs wantlist. Entries that turn up in the
+-- collection are flagged acquired and kept after they leave the wantlist.
+CREATE TABLE IF NOT EXISTS wantlist (
+  release_id INTEGER PRIMARY KEY, -- Release ID from Discogs
+  title TEXT NOT NULL,
+  artists TEXT NOT NULL DEFAULT '', -- Artist names as Discogs credits them, e.g. "Miles Davis & John Coltrane"
+  year INTEGER,
+  resource_url TEXT NOT NULL DEFAULT '',
+  thumb TEXT NOT NULL DEFAULT '',
+  cover_image TEXT NOT NULL DEFAULT '',
+  rating INTEGER NOT NULL DEFAULT 0,
+  notes TEXT NOT NULL DEFAULT '',
+  date_added TIMESTAMP,
+  on_discogs BOOLEAN NOT NULL DEFAULT TRUE, -- Still on the Discogs wantlist
+  sync_id INTEGER, -- Last sync that saw it on Discogs
+  acquired BOOLEAN NOT NULL DEFAULT FALSE,
+  acquired_at TIMESTAMP,
+  acquired_sync_id INTEGER, -- Sync that found it in the collection
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_wantlist_acquired ON wantlist(acquired);
@@ -0,0 +1,50 @@
+-- Extended metadata from the Discogs release resource, stored alongside the
+-- tracklist when a release's details are fetched
+ALTER TABLE releases ADD COLUMN country TEXT;
+ALTER TABLE releases ADD COLUMN released TEXT; -- As Discogs gives it: "1959-08-17", "1959-00-00" or "1959"
+ALTER TABLE releases ADD COLUMN master_id INTEGER; -- Master release shared by every pressing, if there is one
+ALTER TABLE releases ADD COLUMN details_synced_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_releases_master_id ON releases(master_id);
+
+-- Barcodes, matrix/runout etchings, rights society codes and the like
+CREATE TABLE IF NOT EXISTS release_identifiers (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  release_id INTEGER NOT NULL,
+  type TEXT NOT NULL, -- e.g. "Barcode", "Matrix / Runout"
+  value TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '', -- e.g. "Side A", "Scanned"
+  FOREIGN KEY (release_id) REFERENCES releases(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_release_identifiers_release_id ON release_identifiers(release_id);
+
+-- Companies credited on the release, e.g. who pressed or distributed it
+CREATE TABLE IF NOT EXISTS release_companies (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  release_id INTEGER NOT NULL,
+  company_id INTEGER NOT NULL, -- Label ID from Discogs, which lists companies as labels
+  name TEXT NOT NULL,
+  entity_type_name TEXT NOT NULL DEFAULT '', -- e.g. "Pressed By", "Phonographic Copyright (p)"
+  catno TEXT NOT NULL DEFAULT '',
+  resource_url TEXT NOT NULL DEFAULT '',
+  FOREIGN KEY (release_id) REFERENCES releases(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_release_companies_release_id ON release_companies(release_id);
+
+-- Credits (Discogs extraartists) for the release as a whole or for one track
+CREATE TABLE IF NOT EXISTS release_credits (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  release_id INTEGER NOT NULL,
+  track_id INTEGER, -- Set for per-track credits
+  artist_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  anv TEXT NOT NULL DEFAULT '', -- Artist name variation
+  role TEXT NOT NULL DEFAULT '', -- e.g. "Producer", "Tenor Saxophone"
+  tracks TEXT NOT NULL DEFAULT '', -- Positions a release credit covers, e.g. "A1 to B2"
+  FOREIGN KEY (release_id) REFERENCES releases(id),
+  FOREIGN KEY (track_id) REFERENCES tracks(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_release_credits_release_id ON release_credits(release_id);
@@ -0,0 +1,11 @@
+-- Pressings grouped by their Discogs master release. Only releases still in
+-- the collection count as pressings.
+CREATE VIEW IF NOT EXISTS masters AS
+SELECT
+  master_id AS id,
+  MIN(title) AS title,
+  MIN(year) AS year, -- Earliest owned pressing
+  COUNT(*) AS pressings
+FROM releases
+WHERE master_id IS NOT NULL AND archived = FALSE
+GROUP BY master_id;
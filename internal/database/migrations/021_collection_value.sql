@@ -0,0 +1,26 @@
+-- Discogs's estimate of the collection's worth, taken after each successful sync
+CREATE TABLE IF NOT EXISTS collection_value_snapshots (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  sync_id INTEGER, -- Sync the snapshot followed
+  minimum REAL NOT NULL,
+  median REAL NOT NULL,
+  maximum REAL NOT NULL,
+  currency TEXT NOT NULL DEFAULT '', -- Symbol Discogs formatted the values with, e.g. "$"
+  release_count INTEGER NOT NULL DEFAULT 0, -- Releases in the collection at the time
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (sync_id) REFERENCES syncs(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_value_snapshots_created_at ON collection_value_snapshots(created_at);
+
+-- Latest marketplace figures per release. lowest_price is NULL when no copies
+-- were for sale.
+CREATE TABLE IF NOT EXISTS release_prices (
+  release_id INTEGER PRIMARY KEY,
+  lowest_price REAL,
+  currency TEXT,
+  num_for_sale INTEGER NOT NULL DEFAULT 0,
+  blocked_from_sale BOOLEAN NOT NULL DEFAULT FALSE,
+  checked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (release_id) REFERENCES releases(id)
+);
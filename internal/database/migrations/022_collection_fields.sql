@@ -0,0 +1,12 @@
+-- Custom collection fields defined on Discogs. release_notes.field_id refers
+-- to these; notes for a field that no longer exists are kept.
+CREATE TABLE IF NOT EXISTS collection_fields (
+  id INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  type TEXT NOT NULL, -- "dropdown" or "textarea"
+  position INTEGER NOT NULL DEFAULT 0,
+  public BOOLEAN NOT NULL DEFAULT FALSE,
+  lines INTEGER,
+  options TEXT, -- JSON array of dropdown choices, in Discogs order
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,17 @@
+-- Local copies of release artwork. hash names the file in the content
+-- addressed image store next to the database; it is NULL until the image at
+-- source_url has been downloaded.
+CREATE TABLE IF NOT EXISTS release_images (
+  release_id INTEGER NOT NULL,
+  kind TEXT NOT NULL, -- 'thumb' or 'cover'
+  source_url TEXT NOT NULL, -- Remote URL the cached copy, or the failed attempt, came from
+  hash TEXT, -- sha256 of the image, kept when source_url changes until the new one is fetched
+  content_type TEXT,
+  size INTEGER,
+  fetched_at TIMESTAMP,
+  attempts INTEGER NOT NULL DEFAULT 0, -- Failed downloads of source_url
+  last_error TEXT,
+  next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (release_id, kind),
+  FOREIGN KEY (release_id) REFERENCES releases(id)
+);
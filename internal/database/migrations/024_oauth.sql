@@ -0,0 +1,15 @@
+-- OAuth access token for a user who signed in through Discogs rather than
+-- pasting a personal access token. Their auth row has an empty token.
+CREATE TABLE IF NOT EXISTS oauth_credentials (
+  username TEXT NOT NULL PRIMARY KEY,
+  access_token TEXT NOT NULL,
+  access_token_secret TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Request tokens waiting for the user to approve them on Discogs
+CREATE TABLE IF NOT EXISTS oauth_request_tokens (
+  token TEXT NOT NULL PRIMARY KEY,
+  secret TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
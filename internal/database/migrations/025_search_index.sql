@@ -0,0 +1,282 @@
+-- Full-text search. search_entries holds each piece of searchable text and
+-- which release it belongs to, kept current by the triggers below, and
+-- search_index is the FTS5 index over it. Entries of a deleted release are
+-- kept with its artists, tracks and history, searches only return releases
+-- that exist.
+CREATE TABLE IF NOT EXISTS search_entries (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  kind TEXT NOT NULL, -- 'release', 'artist', 'label', 'track', 'play' or 'cleaning'
+  release_id INTEGER NOT NULL,
+  ref_id INTEGER NOT NULL, -- The artist, label, track, play or cleaning, or the release itself
+  body TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_entries_ref ON search_entries(kind, ref_id);
+CREATE INDEX IF NOT EXISTS idx_search_entries_release_id ON search_entries(release_id);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+  body,
+  content = 'search_entries',
+  content_rowid = 'id',
+  tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS search_entries_insert
+AFTER INSERT ON search_entries
+BEGIN
+  INSERT INTO search_index (rowid, body) VALUES (NEW.id, NEW.body);
+END;
+
+CREATE TRIGGER IF NOT EXISTS search_entries_delete
+AFTER DELETE ON search_entries
+BEGIN
+  INSERT INTO search_index (search_index, rowid, body) VALUES ('delete', OLD.id, OLD.body);
+END;
+
+CREATE TRIGGER IF NOT EXISTS search_entries_update
+AFTER UPDATE ON search_entries
+BEGIN
+  INSERT INTO search_index (search_index, rowid, body) VALUES ('delete', OLD.id, OLD.body);
+  INSERT INTO search_index (rowid, body) VALUES (NEW.id, NEW.body);
+END;
+
+-- Releases by title
+CREATE TRIGGER IF NOT EXISTS releases_search_insert
+AFTER INSERT ON releases
+BEGIN
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  VALUES ('release', NEW.id, NEW.id, NEW.title);
+END;
+
+CREATE TRIGGER IF NOT EXISTS releases_search_update
+AFTER UPDATE OF title ON releases
+FOR EACH ROW WHEN OLD.title IS NOT NEW.title
+BEGIN
+  UPDATE search_entries SET body = NEW.title WHERE kind = 'release' AND ref_id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS releases_search_delete
+AFTER DELETE ON releases
+BEGIN
+  DELETE FROM search_entries WHERE kind = 'release' AND ref_id = OLD.id;
+END;
+
+-- Artists by name and the names they are credited under (ANV), one entry per
+-- artist on a release
+CREATE TRIGGER IF NOT EXISTS artists_search_insert
+AFTER INSERT ON artists
+BEGIN
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'artist', ra.release_id, ra.artist_id, NEW.name || COALESCE(' ' || group_concat(DISTINCT NULLIF(ra.anv, '')), '')
+  FROM release_artists ra
+  WHERE ra.artist_id = NEW.id
+  GROUP BY ra.release_id, ra.artist_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS artists_search_update
+AFTER UPDATE OF name ON artists
+FOR EACH ROW WHEN OLD.name IS NOT NEW.name
+BEGIN
+  DELETE FROM search_entries WHERE kind = 'artist' AND ref_id = NEW.id;
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'artist', ra.release_id, ra.artist_id, NEW.name || COALESCE(' ' || group_concat(DISTINCT NULLIF(ra.anv, '')), '')
+  FROM release_artists ra
+  WHERE ra.artist_id = NEW.id
+  GROUP BY ra.release_id, ra.artist_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS artists_search_delete
+AFTER DELETE ON artists
+BEGIN
+  DELETE FROM search_entries WHERE kind = 'artist' AND ref_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS release_artists_search_insert
+AFTER INSERT ON release_artists
+BEGIN
+  DELETE FROM search_entries
+  WHERE kind = 'artist' AND ref_id = NEW.artist_id AND release_id = NEW.release_id;
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'artist', ra.release_id, ra.artist_id, a.name || COALESCE(' ' || group_concat(DISTINCT NULLIF(ra.anv, '')), '')
+  FROM release_artists ra
+  JOIN artists a ON a.id = ra.artist_id
+  WHERE ra.artist_id = NEW.artist_id AND ra.release_id = NEW.release_id
+  GROUP BY ra.release_id, ra.artist_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS release_artists_search_update
+AFTER UPDATE OF anv ON release_artists
+FOR EACH ROW WHEN OLD.anv IS NOT NEW.anv
+BEGIN
+  DELETE FROM search_entries
+  WHERE kind = 'artist' AND ref_id = NEW.artist_id AND release_id = NEW.release_id;
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'artist', ra.release_id, ra.artist_id, a.name || COALESCE(' ' || group_concat(DISTINCT NULLIF(ra.anv, '')), '')
+  FROM release_artists ra
+  JOIN artists a ON a.id = ra.artist_id
+  WHERE ra.artist_id = NEW.artist_id AND ra.release_id = NEW.release_id
+  GROUP BY ra.release_id, ra.artist_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS release_artists_search_delete
+AFTER DELETE ON release_artists
+BEGIN
+  DELETE FROM search_entries
+  WHERE kind = 'artist' AND ref_id = OLD.artist_id AND release_id = OLD.release_id;
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'artist', ra.release_id, ra.artist_id, a.name || COALESCE(' ' || group_concat(DISTINCT NULLIF(ra.anv, '')), '')
+  FROM release_artists ra
+  JOIN artists a ON a.id = ra.artist_id
+  WHERE ra.artist_id = OLD.artist_id AND ra.release_id = OLD.release_id
+  GROUP BY ra.release_id, ra.artist_id;
+END;
+
+-- Labels by name and catalog number, one entry per label on a release
+CREATE TRIGGER IF NOT EXISTS labels_search_insert
+AFTER INSERT ON labels
+BEGIN
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'label', rl.release_id, rl.label_id, NEW.name || COALESCE(' ' || group_concat(DISTINCT NULLIF(rl.catno, '')), '')
+  FROM release_labels rl
+  WHERE rl.label_id = NEW.id
+  GROUP BY rl.release_id, rl.label_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS labels_search_update
+AFTER UPDATE OF name ON labels
+FOR EACH ROW WHEN OLD.name IS NOT NEW.name
+BEGIN
+  DELETE FROM search_entries WHERE kind = 'label' AND ref_id = NEW.id;
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'label', rl.release_id, rl.label_id, NEW.name || COALESCE(' ' || group_concat(DISTINCT NULLIF(rl.catno, '')), '')
+  FROM release_labels rl
+  WHERE rl.label_id = NEW.id
+  GROUP BY rl.release_id, rl.label_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS labels_search_delete
+AFTER DELETE ON labels
+BEGIN
+  DELETE FROM search_entries WHERE kind = 'label' AND ref_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS release_labels_search_insert
+AFTER INSERT ON release_labels
+BEGIN
+  DELETE FROM search_entries
+  WHERE kind = 'label' AND ref_id = NEW.label_id AND release_id = NEW.release_id;
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'label', rl.release_id, rl.label_id, l.name || COALESCE(' ' || group_concat(DISTINCT NULLIF(rl.catno, '')), '')
+  FROM release_labels rl
+  JOIN labels l ON l.id = rl.label_id
+  WHERE rl.label_id = NEW.label_id AND rl.release_id = NEW.release_id
+  GROUP BY rl.release_id, rl.label_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS release_labels_search_delete
+AFTER DELETE ON release_labels
+BEGIN
+  DELETE FROM search_entries
+  WHERE kind = 'label' AND ref_id = OLD.label_id AND release_id = OLD.release_id;
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'label', rl.release_id, rl.label_id, l.name || COALESCE(' ' || group_concat(DISTINCT NULLIF(rl.catno, '')), '')
+  FROM release_labels rl
+  JOIN labels l ON l.id = rl.label_id
+  WHERE rl.label_id = OLD.label_id AND rl.release_id = OLD.release_id
+  GROUP BY rl.release_id, rl.label_id;
+END;
+
+-- Track titles
+CREATE TRIGGER IF NOT EXISTS tracks_search_insert
+AFTER INSERT ON tracks
+BEGIN
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  VALUES ('track', NEW.release_id, NEW.id, NEW.title);
+END;
+
+CREATE TRIGGER IF NOT EXISTS tracks_search_update
+AFTER UPDATE OF title ON tracks
+FOR EACH ROW WHEN OLD.title IS NOT NEW.title
+BEGIN
+  UPDATE search_entries SET body = NEW.title WHERE kind = 'track' AND ref_id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS tracks_search_delete
+AFTER DELETE ON tracks
+BEGIN
+  DELETE FROM search_entries WHERE kind = 'track' AND ref_id = OLD.id;
+END;
+
+-- Play and cleaning notes, when there are any
+CREATE TRIGGER IF NOT EXISTS play_history_search_insert
+AFTER INSERT ON play_history
+FOR EACH ROW WHEN TRIM(COALESCE(NEW.notes, '')) != ''
+BEGIN
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  VALUES ('play', NEW.release_id, NEW.id, NEW.notes);
+END;
+
+CREATE TRIGGER IF NOT EXISTS play_history_search_update
+AFTER UPDATE OF notes, release_id ON play_history
+BEGIN
+  DELETE FROM search_entries WHERE kind = 'play' AND ref_id = NEW.id;
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'play', NEW.release_id, NEW.id, NEW.notes
+  WHERE TRIM(COALESCE(NEW.notes, '')) != '';
+END;
+
+CREATE TRIGGER IF NOT EXISTS play_history_search_delete
+AFTER DELETE ON play_history
+BEGIN
+  DELETE FROM search_entries WHERE kind = 'play' AND ref_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS cleaning_history_search_insert
+AFTER INSERT ON cleaning_history
+FOR EACH ROW WHEN TRIM(COALESCE(NEW.notes, '')) != ''
+BEGIN
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  VALUES ('cleaning', NEW.release_id, NEW.id, NEW.notes);
+END;
+
+CREATE TRIGGER IF NOT EXISTS cleaning_history_search_update
+AFTER UPDATE OF notes, release_id ON cleaning_history
+BEGIN
+  DELETE FROM search_entries WHERE kind = 'cleaning' AND ref_id = NEW.id;
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'cleaning', NEW.release_id, NEW.id, NEW.notes
+  WHERE TRIM(COALESCE(NEW.notes, '')) != '';
+END;
+
+CREATE TRIGGER IF NOT EXISTS cleaning_history_search_delete
+AFTER DELETE ON cleaning_history
+BEGIN
+  DELETE FROM search_entries WHERE kind = 'cleaning' AND ref_id = OLD.id;
+END;
+
+-- Index what is already stored
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'release', id, id, title FROM releases;
+
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'artist', ra.release_id, ra.artist_id, a.name || COALESCE(' ' || group_concat(DISTINCT NULLIF(ra.anv, '')), '')
+FROM release_artists ra
+JOIN artists a ON a.id = ra.artist_id
+GROUP BY ra.release_id, ra.artist_id;
+
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'label', rl.release_id, rl.label_id, l.name || COALESCE(' ' || group_concat(DISTINCT NULLIF(rl.catno, '')), '')
+FROM release_labels rl
+JOIN labels l ON l.id = rl.label_id
+GROUP BY rl.release_id, rl.label_id;
+
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'track', release_id, id, title FROM tracks;
+
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'play', release_id, id, notes FROM play_history
+WHERE TRIM(COALESCE(notes, '')) != '';
+
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'cleaning', release_id, id, notes FROM cleaning_history
+WHERE TRIM(COALESCE(notes, '')) != '';
@@ -0,0 +1,16 @@
+-- The folder and rating Discogs last reported for each collection instance.
+-- A release can be in the collection more than once, while releases keeps a
+-- single row for it, so a sync compares each instance with its own row here.
+CREATE TABLE IF NOT EXISTS release_instances (
+  instance_id INTEGER PRIMARY KEY, -- Instance ID from Discogs
+  release_id INTEGER NOT NULL,
+  folder_id INTEGER NOT NULL,
+  rating INTEGER NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_release_instances_release_id ON release_instances(release_id);
+
+-- Only the instance each release was last saved from is known so far
+INSERT OR IGNORE INTO release_instances (instance_id, release_id, folder_id, rating)
+SELECT instance_id, id, folder_id, rating FROM releases;
@@ -0,0 +1,480 @@
+-- The schema SQLite reaches with migrations 001 to 024, in one go. Later
+-- migrations are numbered to match their SQLite counterparts.
+--
+-- SQLite never enforced foreign keys (the pragma is off), and Kleio relies on
+-- that, e.g. play history outlives a deleted release. They are left out here
+-- so both behave the same and orphaned rows copied from SQLite still load.
+
+-- Sets updated_at whenever a row changes, like the SQLite AFTER UPDATE triggers
+CREATE FUNCTION set_updated_at() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at = CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TABLE auth (
+  token TEXT NOT NULL PRIMARY KEY,
+  username TEXT NOT NULL
+);
+
+CREATE TABLE folders (
+  id BIGINT PRIMARY KEY, -- Using the Discogs folder ID as our primary key
+  name TEXT NOT NULL,
+  count INTEGER NOT NULL DEFAULT 0,
+  resource_url TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  deleted BOOLEAN NOT NULL DEFAULT FALSE,
+  deleted_at TIMESTAMPTZ
+);
+
+CREATE TRIGGER folders_updated_at
+BEFORE UPDATE ON folders
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE releases (
+  id BIGINT PRIMARY KEY, -- Release ID from Discogs
+  instance_id BIGINT NOT NULL,
+  folder_id BIGINT NOT NULL,
+  rating INTEGER NOT NULL DEFAULT 0,
+  title TEXT NOT NULL,
+  year INTEGER,
+  resource_url TEXT,
+  thumb TEXT,
+  cover_image TEXT,
+  play_duration INTEGER,
+  play_duration_estimated BOOLEAN DEFAULT FALSE,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  archived BOOLEAN NOT NULL DEFAULT FALSE,
+  archived_at TIMESTAMPTZ,
+  archive_reason TEXT, -- 'sync_removed', 'user_action', 'duplicate', 'mistake'
+  sync_session_id TEXT, -- Last sync session that saw this release on Discogs
+  date_added TIMESTAMPTZ,
+  country TEXT,
+  released TEXT, -- As Discogs gives it: "1959-08-17", "1959-00-00" or "1959"
+  master_id BIGINT,
+  details_synced_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_releases_folder_id ON releases(folder_id);
+CREATE INDEX idx_releases_title ON releases(title);
+CREATE INDEX idx_releases_year ON releases(year);
+CREATE INDEX idx_releases_archived ON releases(archived);
+CREATE INDEX idx_releases_instance_id ON releases(instance_id);
+CREATE INDEX idx_releases_master_id ON releases(master_id);
+
+-- Only move updated_at when the release itself changes, not every time a
+-- sync stamps it with its session
+CREATE TRIGGER releases_updated_at
+BEFORE UPDATE ON releases
+FOR EACH ROW
+WHEN (NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at AND (
+  NEW.instance_id IS DISTINCT FROM OLD.instance_id
+  OR NEW.folder_id IS DISTINCT FROM OLD.folder_id
+  OR NEW.rating IS DISTINCT FROM OLD.rating
+  OR NEW.title IS DISTINCT FROM OLD.title
+  OR NEW.year IS DISTINCT FROM OLD.year
+  OR NEW.resource_url IS DISTINCT FROM OLD.resource_url
+  OR NEW.thumb IS DISTINCT FROM OLD.thumb
+  OR NEW.cover_image IS DISTINCT FROM OLD.cover_image
+  OR NEW.play_duration IS DISTINCT FROM OLD.play_duration
+  OR NEW.play_duration_estimated IS DISTINCT FROM OLD.play_duration_estimated
+  OR NEW.archived IS DISTINCT FROM OLD.archived
+  OR NEW.archive_reason IS DISTINCT FROM OLD.archive_reason
+  OR NEW.date_added IS DISTINCT FROM OLD.date_added
+))
+EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE labels (
+  id BIGINT PRIMARY KEY, -- Label ID from Discogs
+  name TEXT NOT NULL,
+  resource_url TEXT,
+  entity_type TEXT
+);
+
+CREATE TABLE release_labels (
+  release_id BIGINT NOT NULL,
+  label_id BIGINT NOT NULL,
+  catno TEXT NOT NULL DEFAULT '', -- Catalog number
+  PRIMARY KEY (release_id, label_id, catno)
+);
+
+CREATE INDEX idx_release_labels_label_id ON release_labels(label_id);
+CREATE INDEX idx_release_labels_release_id ON release_labels(release_id);
+
+CREATE TABLE artists (
+  id BIGINT PRIMARY KEY, -- Artist ID from Discogs
+  name TEXT NOT NULL,
+  resource_url TEXT
+);
+
+CREATE TABLE release_artists (
+  release_id BIGINT NOT NULL,
+  artist_id BIGINT NOT NULL,
+  join_relation TEXT, -- The "join" field in the API
+  anv TEXT, -- Artist name variation
+  tracks TEXT, -- Which tracks this artist appears on
+  role TEXT NOT NULL DEFAULT '', -- Artist role (e.g., "Producer")
+  PRIMARY KEY (release_id, artist_id, role)
+);
+
+CREATE INDEX idx_release_artists_artist_id ON release_artists(artist_id);
+CREATE INDEX idx_release_artists_release_id ON release_artists(release_id);
+
+CREATE TABLE formats (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  release_id BIGINT NOT NULL,
+  name TEXT NOT NULL, -- Format name (e.g., "CDr", "Vinyl")
+  qty INTEGER NOT NULL DEFAULT 1 -- Quantity of this format
+);
+
+CREATE TABLE format_descriptions (
+  format_id BIGINT NOT NULL,
+  description TEXT NOT NULL,
+  PRIMARY KEY (format_id, description)
+);
+
+CREATE TABLE genres (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  name TEXT UNIQUE NOT NULL
+);
+
+CREATE TABLE release_genres (
+  release_id BIGINT NOT NULL,
+  genre_id BIGINT NOT NULL,
+  PRIMARY KEY (release_id, genre_id)
+);
+
+CREATE INDEX idx_release_genres_genre_id ON release_genres(genre_id);
+CREATE INDEX idx_release_genres_release_id ON release_genres(release_id);
+
+CREATE TABLE styles (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  name TEXT UNIQUE NOT NULL
+);
+
+CREATE TABLE release_styles (
+  release_id BIGINT NOT NULL,
+  style_id BIGINT NOT NULL,
+  PRIMARY KEY (release_id, style_id)
+);
+
+CREATE INDEX idx_release_styles_style_id ON release_styles(style_id);
+CREATE INDEX idx_release_styles_release_id ON release_styles(release_id);
+
+CREATE TABLE release_notes (
+  release_id BIGINT NOT NULL,
+  field_id BIGINT NOT NULL,
+  value TEXT NOT NULL,
+  PRIMARY KEY (release_id, field_id)
+);
+
+CREATE TABLE syncs (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  sync_start TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  sync_end TIMESTAMPTZ,
+  status TEXT DEFAULT 'in_progress', -- 'in_progress', 'complete', 'failed'
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  mode TEXT NOT NULL DEFAULT 'full', -- 'full' or 'incremental'
+  phase TEXT NOT NULL DEFAULT 'folders', -- 'folders', 'releases' or 'tracks'
+  session_id TEXT,
+  folder_id BIGINT,
+  page INTEGER NOT NULL DEFAULT 1,
+  releases_processed INTEGER NOT NULL DEFAULT 0,
+  failed_folders INTEGER NOT NULL DEFAULT 0,
+  checkpoint_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_syncs_sync_start ON syncs(sync_start DESC);
+CREATE INDEX idx_syncs_status ON syncs(status);
+
+CREATE TABLE styluses (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  name TEXT NOT NULL,
+  manufacturer TEXT,
+  expected_lifespan_hours INTEGER,
+  purchase_date TIMESTAMPTZ,
+  active BOOLEAN DEFAULT FALSE,
+  primary_stylus BOOLEAN DEFAULT FALSE,
+  owned BOOLEAN DEFAULT FALSE,
+  base_model BOOLEAN DEFAULT FALSE,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(name, manufacturer, base_model)
+);
+
+CREATE TRIGGER styluses_updated_at
+BEFORE UPDATE ON styluses
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Ensure only one primary stylus
+CREATE FUNCTION ensure_single_primary() RETURNS trigger AS $$
+BEGIN
+  UPDATE styluses SET primary_stylus = FALSE WHERE id != NEW.id AND primary_stylus;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ensure_single_primary
+AFTER UPDATE OF primary_stylus ON styluses
+FOR EACH ROW WHEN (NEW.primary_stylus)
+EXECUTE FUNCTION ensure_single_primary();
+
+INSERT INTO styluses
+(name, manufacturer, expected_lifespan_hours, active, primary_stylus, base_model)
+VALUES
+-- Ortofon
+('2M Red', 'Ortofon', 1000, FALSE, FALSE, TRUE),
+('2M Blue', 'Ortofon', 1500, FALSE, FALSE, TRUE),
+('2M Bronze', 'Ortofon', 2000, FALSE, FALSE, TRUE),
+('2M Black', 'Ortofon', 2000, FALSE, FALSE, TRUE),
+('Super OM 10', 'Ortofon', 1000, FALSE, FALSE, TRUE),
+('Concorde MkII Club', 'Ortofon', 1000, FALSE, FALSE, TRUE),
+-- Audio-Technica
+('AT-VM95E', 'Audio-Technica', 800, FALSE, FALSE, TRUE),
+('AT-VM95EN', 'Audio-Technica', 1000, FALSE, FALSE, TRUE),
+('AT-VM95ML', 'Audio-Technica', 1500, FALSE, FALSE, TRUE),
+('AT-VM95SH', 'Audio-Technica', 2000, FALSE, FALSE, TRUE),
+('AT33PTG/II', 'Audio-Technica', 1500, FALSE, FALSE, TRUE),
+-- Nagaoka
+('MP-110', 'Nagaoka', 1000, FALSE, FALSE, TRUE),
+('MP-150', 'Nagaoka', 1500, FALSE, FALSE, TRUE),
+('MP-200', 'Nagaoka', 2000, FALSE, FALSE, TRUE),
+-- Grado
+('Prestige Black3', 'Grado', 1000, FALSE, FALSE, TRUE),
+('Prestige Gold3', 'Grado', 1500, FALSE, FALSE, TRUE),
+('Timbre Series Platinum3', 'Grado', 2000, FALSE, FALSE, TRUE),
+-- Denon
+('DL-103', 'Denon', 1500, FALSE, FALSE, TRUE),
+('DL-110', 'Denon', 1500, FALSE, FALSE, TRUE)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO styluses
+(name, manufacturer, expected_lifespan_hours, active, primary_stylus, owned, base_model)
+VALUES
+('MP-110', 'Nagaoka', 1000, TRUE, TRUE, TRUE, FALSE)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE play_history (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  release_id BIGINT NOT NULL,
+  stylus_id BIGINT,
+  played_at TIMESTAMPTZ NOT NULL,
+  notes TEXT,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_play_history_release_id ON play_history(release_id);
+CREATE INDEX idx_play_history_stylus_id ON play_history(stylus_id);
+CREATE INDEX idx_play_history_played_at ON play_history(played_at);
+
+CREATE TRIGGER play_history_updated_at
+BEFORE UPDATE ON play_history
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE cleaning_history (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  release_id BIGINT NOT NULL,
+  cleaned_at TIMESTAMPTZ NOT NULL,
+  notes TEXT,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_cleaning_history_release_id ON cleaning_history(release_id);
+CREATE INDEX idx_cleaning_history_cleaned_at ON cleaning_history(cleaned_at);
+
+CREATE TRIGGER cleaning_history_updated_at
+BEFORE UPDATE ON cleaning_history
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE tracks (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  release_id BIGINT NOT NULL,
+  position TEXT NOT NULL,
+  title TEXT NOT NULL,
+  duration_text TEXT,
+  duration_seconds INTEGER
+);
+
+-- What each sync changed in the collection
+CREATE TABLE sync_changes (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  sync_id BIGINT NOT NULL,
+  release_id BIGINT NOT NULL,
+  title TEXT,
+  change_type TEXT NOT NULL, -- 'added', 'removed', 'restored', 'folder_moved', 'rating_changed', 'metadata_changed'
+  field TEXT, -- Column that changed for 'metadata_changed'
+  old_value TEXT,
+  new_value TEXT,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sync_changes_sync_id ON sync_changes(sync_id);
+CREATE INDEX idx_sync_changes_release_id ON sync_changes(release_id);
+
+-- Folders added, renamed, deleted or restored by each sync
+CREATE TABLE folder_changes (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  sync_id BIGINT NOT NULL,
+  folder_id BIGINT NOT NULL,
+  change_type TEXT NOT NULL, -- 'added', 'renamed', 'deleted', 'restored'
+  old_name TEXT,
+  new_name TEXT,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_folder_changes_sync_id ON folder_changes(sync_id);
+
+-- Rating changes and folder moves waiting to be pushed to Discogs
+CREATE TABLE discogs_writes (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  release_id BIGINT NOT NULL,
+  instance_id BIGINT NOT NULL,
+  from_folder_id BIGINT NOT NULL, -- Folder the instance is in on Discogs, part of the endpoint path
+  rating INTEGER, -- NULL when the rating isn't being changed
+  folder_id BIGINT, -- NULL when the release isn't being moved
+  status TEXT NOT NULL DEFAULT 'pending', -- 'pending', 'done', 'failed'
+  revision INTEGER NOT NULL DEFAULT 1, -- Bumped when a change is merged in, so an older push can't complete it
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  next_attempt_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_discogs_writes_pending
+ON discogs_writes(release_id) WHERE status = 'pending';
+
+CREATE INDEX idx_discogs_writes_status ON discogs_writes(status, next_attempt_at);
+
+CREATE TABLE wantlist (
+  release_id BIGINT PRIMARY KEY, -- Release ID from Discogs
+  title TEXT NOT NULL,
+  artists TEXT NOT NULL DEFAULT '', -- Artist names as Discogs credits them
+  year INTEGER,
+  resource_url TEXT NOT NULL DEFAULT '',
+  thumb TEXT NOT NULL DEFAULT '',
+  cover_image TEXT NOT NULL DEFAULT '',
+  rating INTEGER NOT NULL DEFAULT 0,
+  notes TEXT NOT NULL DEFAULT '',
+  date_added TIMESTAMPTZ,
+  on_discogs BOOLEAN NOT NULL DEFAULT TRUE, -- Still on the Discogs wantlist
+  sync_id BIGINT, -- Last sync that saw it on Discogs
+  acquired BOOLEAN NOT NULL DEFAULT FALSE,
+  acquired_at TIMESTAMPTZ,
+  acquired_sync_id BIGINT, -- Sync that found it in the collection
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_wantlist_acquired ON wantlist(acquired);
+
+CREATE TABLE release_identifiers (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  release_id BIGINT NOT NULL,
+  type TEXT NOT NULL, -- e.g. "Barcode", "Matrix / Runout"
+  value TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '' -- e.g. "Side A", "Scanned"
+);
+
+CREATE INDEX idx_release_identifiers_release_id ON release_identifiers(release_id);
+
+CREATE TABLE release_companies (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  release_id BIGINT NOT NULL,
+  company_id BIGINT NOT NULL, -- Label ID from Discogs, which lists companies as labels
+  name TEXT NOT NULL,
+  entity_type_name TEXT NOT NULL DEFAULT '', -- e.g. "Pressed By"
+  catno TEXT NOT NULL DEFAULT '',
+  resource_url TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_release_companies_release_id ON release_companies(release_id);
+
+CREATE TABLE release_credits (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  release_id BIGINT NOT NULL,
+  track_id BIGINT, -- Set for per-track credits
+  artist_id BIGINT NOT NULL,
+  name TEXT NOT NULL,
+  anv TEXT NOT NULL DEFAULT '', -- Artist name variation
+  role TEXT NOT NULL DEFAULT '', -- e.g. "Producer", "Tenor Saxophone"
+  tracks TEXT NOT NULL DEFAULT '' -- Positions a release credit covers, e.g. "A1 to B2"
+);
+
+CREATE INDEX idx_release_credits_release_id ON release_credits(release_id);
+
+-- Pressings grouped by their Discogs master release
+CREATE VIEW masters AS
+SELECT
+  master_id AS id,
+  MIN(title) AS title,
+  MIN(year) AS year, -- Earliest owned pressing
+  COUNT(*) AS pressings
+FROM releases
+WHERE master_id IS NOT NULL AND archived = FALSE
+GROUP BY master_id;
+
+CREATE TABLE collection_value_snapshots (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  sync_id BIGINT, -- Sync the snapshot followed
+  minimum DOUBLE PRECISION NOT NULL,
+  median DOUBLE PRECISION NOT NULL,
+  maximum DOUBLE PRECISION NOT NULL,
+  currency TEXT NOT NULL DEFAULT '', -- Symbol Discogs formatted the values with, e.g. "$"
+  release_count INTEGER NOT NULL DEFAULT 0, -- Releases in the collection at the time
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_collection_value_snapshots_created_at ON collection_value_snapshots(created_at);
+
+CREATE TABLE release_prices (
+  release_id BIGINT PRIMARY KEY,
+  lowest_price DOUBLE PRECISION, -- NULL when no copies were for sale
+  currency TEXT,
+  num_for_sale INTEGER NOT NULL DEFAULT 0,
+  blocked_from_sale BOOLEAN NOT NULL DEFAULT FALSE,
+  checked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE collection_fields (
+  id BIGINT PRIMARY KEY,
+  name TEXT NOT NULL,
+  type TEXT NOT NULL, -- "dropdown" or "textarea"
+  position INTEGER NOT NULL DEFAULT 0,
+  public BOOLEAN NOT NULL DEFAULT FALSE,
+  lines INTEGER,
+  options TEXT, -- JSON array of dropdown choices, in Discogs order
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE release_images (
+  release_id BIGINT NOT NULL,
+  kind TEXT NOT NULL, -- 'thumb' or 'cover'
+  source_url TEXT NOT NULL, -- Remote URL the cached copy, or the failed attempt, came from
+  hash TEXT, -- sha256 of the image, kept when source_url changes until the new one is fetched
+  content_type TEXT,
+  size BIGINT,
+  fetched_at TIMESTAMPTZ,
+  attempts INTEGER NOT NULL DEFAULT 0, -- Failed downloads of source_url
+  last_error TEXT,
+  next_attempt_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (release_id, kind)
+);
+
+CREATE TABLE oauth_credentials (
+  username TEXT NOT NULL PRIMARY KEY,
+  access_token TEXT NOT NULL,
+  access_token_secret TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE oauth_request_tokens (
+  token TEXT NOT NULL PRIMARY KEY,
+  secret TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,221 @@
+-- Full-text search. search_entries holds each piece of searchable text and
+-- which release it belongs to, kept current by the triggers below, with its
+-- tsvector in document. Entries of a deleted release are kept with its
+-- artists, tracks and history, searches only return releases that exist.
+CREATE TABLE search_entries (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  kind TEXT NOT NULL, -- 'release', 'artist', 'label', 'track', 'play' or 'cleaning'
+  release_id BIGINT NOT NULL,
+  ref_id BIGINT NOT NULL, -- The artist, label, track, play or cleaning, or the release itself
+  body TEXT NOT NULL,
+  document TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', body)) STORED
+);
+
+CREATE INDEX idx_search_entries_ref ON search_entries(kind, ref_id);
+CREATE INDEX idx_search_entries_release_id ON search_entries(release_id);
+CREATE INDEX idx_search_entries_document ON search_entries USING GIN (document);
+
+-- Replaces the entries for p_artist_id on p_release_id, or on each of its
+-- releases when p_release_id is NULL
+CREATE FUNCTION index_release_artists(p_release_id BIGINT, p_artist_id BIGINT) RETURNS void AS $$
+BEGIN
+  DELETE FROM search_entries
+  WHERE kind = 'artist' AND ref_id = p_artist_id AND (p_release_id IS NULL OR release_id = p_release_id);
+
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'artist', ra.release_id, ra.artist_id, a.name || COALESCE(' ' || string_agg(DISTINCT NULLIF(ra.anv, ''), ','), '')
+  FROM release_artists ra
+  JOIN artists a ON a.id = ra.artist_id
+  WHERE ra.artist_id = p_artist_id AND (p_release_id IS NULL OR ra.release_id = p_release_id)
+  GROUP BY ra.release_id, ra.artist_id, a.name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Replaces the entries for p_label_id on p_release_id, or on each of its
+-- releases when p_release_id is NULL
+CREATE FUNCTION index_release_labels(p_release_id BIGINT, p_label_id BIGINT) RETURNS void AS $$
+BEGIN
+  DELETE FROM search_entries
+  WHERE kind = 'label' AND ref_id = p_label_id AND (p_release_id IS NULL OR release_id = p_release_id);
+
+  INSERT INTO search_entries (kind, release_id, ref_id, body)
+  SELECT 'label', rl.release_id, rl.label_id, l.name || COALESCE(' ' || string_agg(DISTINCT NULLIF(rl.catno, ''), ','), '')
+  FROM release_labels rl
+  JOIN labels l ON l.id = rl.label_id
+  WHERE rl.label_id = p_label_id AND (p_release_id IS NULL OR rl.release_id = p_release_id)
+  GROUP BY rl.release_id, rl.label_id, l.name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Releases by title
+CREATE FUNCTION releases_search() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    DELETE FROM search_entries WHERE kind = 'release' AND ref_id = OLD.id;
+  END IF;
+  IF TG_OP IN ('INSERT', 'UPDATE') THEN
+    INSERT INTO search_entries (kind, release_id, ref_id, body)
+    VALUES ('release', NEW.id, NEW.id, NEW.title);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER releases_search
+AFTER INSERT OR DELETE ON releases
+FOR EACH ROW EXECUTE FUNCTION releases_search();
+
+CREATE TRIGGER releases_search_update
+AFTER UPDATE OF title ON releases
+FOR EACH ROW WHEN (OLD.title IS DISTINCT FROM NEW.title)
+EXECUTE FUNCTION releases_search();
+
+-- Artists by name and the names they are credited under (ANV), one entry per
+-- artist on a release
+CREATE FUNCTION artists_search() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    DELETE FROM search_entries WHERE kind = 'artist' AND ref_id = OLD.id;
+  ELSE
+    PERFORM index_release_artists(NULL, NEW.id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER artists_search
+AFTER INSERT OR DELETE ON artists
+FOR EACH ROW EXECUTE FUNCTION artists_search();
+
+CREATE TRIGGER artists_search_update
+AFTER UPDATE OF name ON artists
+FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+EXECUTE FUNCTION artists_search();
+
+CREATE FUNCTION release_artists_search() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    PERFORM index_release_artists(OLD.release_id, OLD.artist_id);
+  ELSE
+    PERFORM index_release_artists(NEW.release_id, NEW.artist_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER release_artists_search
+AFTER INSERT OR DELETE ON release_artists
+FOR EACH ROW EXECUTE FUNCTION release_artists_search();
+
+CREATE TRIGGER release_artists_search_update
+AFTER UPDATE OF anv ON release_artists
+FOR EACH ROW WHEN (OLD.anv IS DISTINCT FROM NEW.anv)
+EXECUTE FUNCTION release_artists_search();
+
+-- Labels by name and catalog number, one entry per label on a release
+CREATE FUNCTION labels_search() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    DELETE FROM search_entries WHERE kind = 'label' AND ref_id = OLD.id;
+  ELSE
+    PERFORM index_release_labels(NULL, NEW.id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER labels_search
+AFTER INSERT OR DELETE ON labels
+FOR EACH ROW EXECUTE FUNCTION labels_search();
+
+CREATE TRIGGER labels_search_update
+AFTER UPDATE OF name ON labels
+FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+EXECUTE FUNCTION labels_search();
+
+CREATE FUNCTION release_labels_search() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    PERFORM index_release_labels(OLD.release_id, OLD.label_id);
+  ELSE
+    PERFORM index_release_labels(NEW.release_id, NEW.label_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER release_labels_search
+AFTER INSERT OR DELETE ON release_labels
+FOR EACH ROW EXECUTE FUNCTION release_labels_search();
+
+-- Track titles
+CREATE FUNCTION tracks_search() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    DELETE FROM search_entries WHERE kind = 'track' AND ref_id = OLD.id;
+  END IF;
+  IF TG_OP IN ('INSERT', 'UPDATE') THEN
+    INSERT INTO search_entries (kind, release_id, ref_id, body)
+    VALUES ('track', NEW.release_id, NEW.id, NEW.title);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tracks_search
+AFTER INSERT OR DELETE ON tracks
+FOR EACH ROW EXECUTE FUNCTION tracks_search();
+
+CREATE TRIGGER tracks_search_update
+AFTER UPDATE OF title ON tracks
+FOR EACH ROW WHEN (OLD.title IS DISTINCT FROM NEW.title)
+EXECUTE FUNCTION tracks_search();
+
+-- Play and cleaning notes, when there are any. TG_ARGV[0] is the kind.
+CREATE FUNCTION notes_search() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    DELETE FROM search_entries WHERE kind = TG_ARGV[0] AND ref_id = OLD.id;
+  END IF;
+  IF TG_OP IN ('INSERT', 'UPDATE') AND TRIM(COALESCE(NEW.notes, '')) != '' THEN
+    INSERT INTO search_entries (kind, release_id, ref_id, body)
+    VALUES (TG_ARGV[0], NEW.release_id, NEW.id, NEW.notes);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER play_history_search
+AFTER INSERT OR UPDATE OF notes, release_id OR DELETE ON play_history
+FOR EACH ROW EXECUTE FUNCTION notes_search('play');
+
+CREATE TRIGGER cleaning_history_search
+AFTER INSERT OR UPDATE OF notes, release_id OR DELETE ON cleaning_history
+FOR EACH ROW EXECUTE FUNCTION notes_search('cleaning');
+
+-- Index what is already stored
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'release', id, id, title FROM releases;
+
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'artist', ra.release_id, ra.artist_id, a.name || COALESCE(' ' || string_agg(DISTINCT NULLIF(ra.anv, ''), ','), '')
+FROM release_artists ra
+JOIN artists a ON a.id = ra.artist_id
+GROUP BY ra.release_id, ra.artist_id, a.name;
+
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'label', rl.release_id, rl.label_id, l.name || COALESCE(' ' || string_agg(DISTINCT NULLIF(rl.catno, ''), ','), '')
+FROM release_labels rl
+JOIN labels l ON l.id = rl.label_id
+GROUP BY rl.release_id, rl.label_id, l.name;
+
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'track', release_id, id, title FROM tracks;
+
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'play', release_id, id, notes FROM play_history
+WHERE TRIM(COALESCE(notes, '')) != '';
+
+INSERT INTO search_entries (kind, release_id, ref_id, body)
+SELECT 'cleaning', release_id, id, notes FROM cleaning_history
+WHERE TRIM(COALESCE(notes, '')) != '';
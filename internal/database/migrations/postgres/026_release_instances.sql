@@ -0,0 +1,17 @@
+-- The folder and rating Discogs last reported for each collection instance.
+-- A release can be in the collection more than once, while releases keeps a
+-- single row for it, so a sync compares each instance with its own row here.
+CREATE TABLE release_instances (
+  instance_id BIGINT PRIMARY KEY, -- Instance ID from Discogs
+  release_id BIGINT NOT NULL,
+  folder_id BIGINT NOT NULL,
+  rating INTEGER NOT NULL DEFAULT 0,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_release_instances_release_id ON release_instances(release_id);
+
+-- Only the instance each release was last saved from is known so far
+INSERT INTO release_instances (instance_id, release_id, folder_id, rating)
+SELECT instance_id, id, folder_id, rating FROM releases
+ON CONFLICT (instance_id) DO NOTHING;
@@ -0,0 +1,4 @@
+-- Wanted releases that were already in the collection when they were first
+-- stored. Owning them isn't an acquisition, until they leave the collection
+-- and come back.
+ALTER TABLE wantlist ADD COLUMN already_owned BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,222 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openRawSQLite opens a SQLite database without migrating it
+func openRawSQLite(t *testing.T) *DB {
+	t.Helper()
+
+	raw, err := sql.Open(sqliteDialect.driverName, filepath.Join(t.TempDir(), "kleio.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { raw.Close() })
+
+	return &DB{DB: raw, dialect: sqliteDialect}
+}
+
+func appliedVersions(t *testing.T, db *DB) map[int]string {
+	t.Helper()
+
+	applied, err := getAppliedMigrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return applied
+}
+
+func tableExists(t *testing.T, db *DB, table string) bool {
+	t.Helper()
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return count > 0
+}
+
+func TestRunMigrationsOnFreshDatabase(t *testing.T) {
+	db := openRawSQLite(t)
+	migrations, err := loadMigrations(sqliteDialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	applied := appliedVersions(t, db)
+	if len(applied) != len(migrations) {
+		t.Fatalf("recorded %d migrations, want %d", len(applied), len(migrations))
+	}
+	for _, migration := range migrations {
+		if applied[migration.Version] != migration.Checksum {
+			t.Errorf("migration %s recorded with checksum %q", migration.Name, applied[migration.Version])
+		}
+	}
+
+	// Running again applies nothing; a migration like 012 would fail on its
+	// columns already being there if it ran twice
+	if err := runMigrations(db); err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+	if again := appliedVersions(t, db); len(again) != len(migrations) {
+		t.Errorf("second run recorded %d migrations, want %d", len(again), len(migrations))
+	}
+}
+
+func TestRunMigrationsOnPostgres(t *testing.T) {
+	db := openTestPostgres(t)
+	migrations, err := loadMigrations(postgresDialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// OpenPostgres applied them, so running again applies nothing
+	if err := runMigrations(db.DB); err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+	applied := appliedVersions(t, db.DB)
+	if len(applied) != len(migrations) {
+		t.Fatalf("recorded %d migrations, want %d", len(applied), len(migrations))
+	}
+	for _, migration := range migrations {
+		if applied[migration.Version] != migration.Checksum {
+			t.Errorf("migration %s recorded with checksum %q", migration.Name, applied[migration.Version])
+		}
+	}
+}
+
+func TestRunMigrationsRejectsModifiedMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kleio.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB.Exec("UPDATE schema_migrations SET checksum = 'edited' WHERE version = 5"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	_, err = Open(path)
+	if err == nil || !strings.Contains(err.Error(), "005_sync_table.sql has been modified") {
+		t.Fatalf("opening a database with a modified migration returned %v", err)
+	}
+}
+
+func TestRunMigrationsRollsBackFailedMigration(t *testing.T) {
+	db := openRawSQLite(t)
+	migrations, err := loadMigrations(sqliteDialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broken := Migration{
+		Version:  999,
+		Name:     "999_broken.sql",
+		Checksum: "broken",
+		SQL: `
+			CREATE TABLE half_done (id INTEGER);
+			INSERT INTO missing_table VALUES (1);`,
+	}
+	if err := applyMigrations(db, append(migrations, broken)); err == nil {
+		t.Fatal("broken migration applied without an error")
+	}
+
+	if tableExists(t, db, "half_done") {
+		t.Error("the broken migration's table was left behind")
+	}
+	applied := appliedVersions(t, db)
+	if _, ok := applied[999]; ok {
+		t.Error("the broken migration was recorded")
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("recorded %d migrations, want the %d before the broken one", len(applied), len(migrations))
+	}
+}
+
+// legacyDatabase creates a database the way the old runner left it, with
+// every migration up to through executed and nothing recorded
+func legacyDatabase(t *testing.T, through int) *DB {
+	t.Helper()
+
+	db := openRawSQLite(t)
+	migrations, err := loadMigrations(sqliteDialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range migrations {
+		if migration.Version > through {
+			break
+		}
+		if _, err := db.DB.Exec(migration.SQL); err != nil {
+			t.Fatalf("failed to run %s: %v", migration.Name, err)
+		}
+	}
+
+	// A play at the same time as a cleaning, which 010 would move on a second
+	// if it ran again
+	_, err = db.DB.Exec(`
+		INSERT INTO play_history (release_id, played_at) VALUES (1, '2023-05-01 20:00:00');
+		INSERT INTO cleaning_history (release_id, cleaned_at) VALUES (1, '2023-05-01 20:00:00');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestRunMigrationsBaselinesLegacyDatabase(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		through int
+	}{
+		{"at version 11", 11},
+		// 012 fails on its columns already being there if it runs again
+		{"with 012 already applied", 12},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			db := legacyDatabase(t, test.through)
+			migrations, err := loadMigrations(sqliteDialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := runMigrations(db); err != nil {
+				t.Fatalf("migrating a legacy database failed: %v", err)
+			}
+
+			var playedAt time.Time
+			if err := db.QueryRow("SELECT played_at FROM play_history").Scan(&playedAt); err != nil {
+				t.Fatal(err)
+			}
+			if want := time.Date(2023, 5, 1, 20, 0, 0, 0, time.UTC); !playedAt.Equal(want) {
+				t.Errorf("played_at = %s, 010 ran again", playedAt)
+			}
+
+			var archived int
+			if err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('releases') WHERE name = 'archived'").Scan(&archived); err != nil {
+				t.Fatal(err)
+			}
+			if archived != 1 {
+				t.Error("012's archived column is missing")
+			}
+
+			if applied := appliedVersions(t, db); len(applied) != len(migrations) {
+				t.Errorf("recorded %d migrations, want %d", len(applied), len(migrations))
+			}
+			if !tableExists(t, db, "sync_changes") {
+				t.Error("migrations after the baseline weren't applied")
+			}
+		})
+	}
+}
@@ -17,6 +17,13 @@ type Release struct {
 	CoverImage            string            `json:"coverImage"                db:"cover_image"`
 	PlayDuration          *int              `json:"playDuration"              db:"play_duration"`
 	PlayDurationEstimated *bool             `json:"playDurationEstimated"     db:"play_duration_estimated"`
+	Archived              bool              `json:"archived"                  db:"archived"`
+	ArchivedAt            *time.Time        `json:"archivedAt,omitempty"      db:"archived_at"`
+	ArchiveReason         *ArchiveReason    `json:"archiveReason,omitempty"   db:"archive_reason"`
+	DateAdded             *time.Time        `json:"dateAdded,omitempty"       db:"date_added"`
+	Country               *string           `json:"country,omitempty"         db:"country"`
+	Released              *string           `json:"released,omitempty"        db:"released"`
+	MasterID              *int              `json:"masterId,omitempty"        db:"master_id"`
 	CreatedAt             time.Time         `json:"createdAt"                 db:"created_at"`
 	UpdatedAt             time.Time         `json:"updatedAt"                 db:"updated_at"`
 	Labels                []ReleaseLabel    `json:"labels,omitempty"`
@@ -28,6 +35,31 @@ type Release struct {
 	PlayHistory           []PlayHistory     `json:"playHistory,omitempty"`
 	CleaningHistory       []CleaningHistory `json:"cleaningHistory,omitempty"`
 	Tracks                []Track           `json:"tracks,omitzero"`
+
+	// Only loaded for a single release
+	Identifiers []ReleaseIdentifier `json:"identifiers,omitempty"`
+	Companies   []ReleaseCompany    `json:"companies,omitempty"`
+	Credits     []ReleaseCredit     `json:"credits,omitempty"` // Credits for the release as a whole
+	Price       *ReleasePrice       `json:"price,omitempty"`
+}
+
+// ArchiveReason records why a release was removed from the active collection
+type ArchiveReason string
+
+const (
+	ArchiveReasonSyncRemoved ArchiveReason = "sync_removed" // Auto-archived during sync
+	ArchiveReasonUserAction  ArchiveReason = "user_action"  // Manual user archive
+	ArchiveReasonDuplicate   ArchiveReason = "duplicate"    // Duplicate detection
+	ArchiveReasonMistake     ArchiveReason = "mistake"      // User mistake/mis-click
+)
+
+// IsValid reports whether the reason is one of the known archive reasons
+func (r ArchiveReason) IsValid() bool {
+	switch r {
+	case ArchiveReasonSyncRemoved, ArchiveReasonUserAction, ArchiveReasonDuplicate, ArchiveReasonMistake:
+		return true
+	}
+	return false
 }
 
 // Track represents a track/song on a release
@@ -40,6 +72,8 @@ type Track struct {
 	DurationSeconds int       `json:"durationSeconds" db:"duration_seconds"` // Normalized duration in seconds
 	CreatedAt       time.Time `json:"createdAt"       db:"created_at"`
 	UpdatedAt       time.Time `json:"updatedAt"       db:"updated_at"`
+
+	Credits []ReleaseCredit `json:"credits,omitempty"` // Only loaded for a single release
 }
 
 // DiscogsTrack represents the track format received from Discogs API
@@ -48,6 +82,75 @@ type DiscogsTrack struct {
 	Position string `json:"position"` // "A1", "B2", etc.
 	Title    string `json:"title"`    // Track title
 	Type     string `json:"type_"`    // Usually "track", sometimes "heading" for section labels
+
+	ExtraArtists []DiscogsCredit `json:"extraartists,omitempty"` // Credits for just this track
+}
+
+// DiscogsCredit is an extraartist on a release or track
+type DiscogsCredit struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	ANV         string `json:"anv"`
+	Role        string `json:"role"`
+	Tracks      string `json:"tracks"`
+	ResourceURL string `json:"resource_url"`
+}
+
+type DiscogsIdentifier struct {
+	Type        string `json:"type"`
+	Value       string `json:"value"`
+	Description string `json:"description"`
+}
+
+type DiscogsCompany struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	EntityTypeName string `json:"entity_type_name"`
+	CatNo          string `json:"catno"`
+	ResourceURL    string `json:"resource_url"`
+}
+
+// ReleaseMetadata is what the release resource adds to the collection's
+// summary of a release, apart from the tracklist
+type ReleaseMetadata struct {
+	Country     string
+	Released    string
+	MasterID    *int
+	Identifiers []ReleaseIdentifier
+	Companies   []ReleaseCompany
+	Credits     []ReleaseCredit
+}
+
+// ReleaseIdentifier is a barcode, matrix/runout etching or other identifier
+type ReleaseIdentifier struct {
+	ID          int    `json:"id"          db:"id"`
+	ReleaseID   int    `json:"releaseId"   db:"release_id"`
+	Type        string `json:"type"        db:"type"` // e.g. "Barcode", "Matrix / Runout"
+	Value       string `json:"value"       db:"value"`
+	Description string `json:"description" db:"description"`
+}
+
+// ReleaseCompany is a company credited on a release, e.g. the pressing plant
+type ReleaseCompany struct {
+	ID             int    `json:"id"             db:"id"`
+	ReleaseID      int    `json:"releaseId"      db:"release_id"`
+	CompanyID      int    `json:"companyId"      db:"company_id"`
+	Name           string `json:"name"           db:"name"`
+	EntityTypeName string `json:"entityTypeName" db:"entity_type_name"` // e.g. "Pressed By"
+	CatNo          string `json:"catno"          db:"catno"`
+	ResourceURL    string `json:"resourceUrl"    db:"resource_url"`
+}
+
+// ReleaseCredit is an extraartist credited on a release or on one of its tracks
+type ReleaseCredit struct {
+	ID        int    `json:"id"                db:"id"`
+	ReleaseID int    `json:"releaseId"         db:"release_id"`
+	TrackID   *int   `json:"trackId,omitempty" db:"track_id"`
+	ArtistID  int    `json:"artistId"          db:"artist_id"`
+	Name      string `json:"name"              db:"name"`
+	ANV       string `json:"anv"               db:"anv"`
+	Role      string `json:"role"              db:"role"`
+	Tracks    string `json:"tracks"            db:"tracks"`
 }
 
 // Label represents a record label
@@ -131,9 +234,11 @@ type ReleaseStyle struct {
 
 // ReleaseNote represents a note about a release
 type ReleaseNote struct {
-	ReleaseID int    `json:"releaseId" db:"release_id"`
-	FieldID   int    `json:"fieldId"   db:"field_id"`
-	Value     string `json:"value"     db:"value"`
+	ReleaseID int                 `json:"releaseId"           db:"release_id"`
+	FieldID   int                 `json:"fieldId"             db:"field_id"`
+	FieldName string              `json:"fieldName,omitempty" db:"-"` // Empty if the field was deleted on Discogs
+	FieldType CollectionFieldType `json:"fieldType,omitempty" db:"-"`
+	Value     string              `json:"value"               db:"value"`
 }
 
 // Folder represents a collection folder from Discogs
@@ -146,6 +251,26 @@ type Folder struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// FolderChangeType is what a sync found had happened to a Discogs folder
+type FolderChangeType string
+
+const (
+	FolderChangeAdded    FolderChangeType = "added"
+	FolderChangeRenamed  FolderChangeType = "renamed"
+	FolderChangeDeleted  FolderChangeType = "deleted"
+	FolderChangeRestored FolderChangeType = "restored" // Reappeared after being flagged deleted
+)
+
+type FolderChange struct {
+	ID        int64            `json:"id"                db:"id"`
+	SyncID    int64            `json:"syncId"            db:"sync_id"`
+	FolderID  int              `json:"folderId"          db:"folder_id"`
+	Type      FolderChangeType `json:"type"              db:"change_type"`
+	OldName   *string          `json:"oldName,omitempty" db:"old_name"`
+	NewName   *string          `json:"newName,omitempty" db:"new_name"`
+	CreatedAt time.Time        `json:"createdAt"         db:"created_at"`
+}
+
 // FoldersResponse represents the response from the Discogs API folders endpoint
 type FoldersResponse struct {
 	Folders []Folder `json:"folders"`
@@ -153,65 +278,173 @@ type FoldersResponse struct {
 
 // DiscogsResponse represents the paginated response from the Discogs API
 type DiscogsResponse struct {
-	Pagination struct {
-		PerPage int `json:"per_page"`
-		Pages   int `json:"pages"`
-		Page    int `json:"page"`
-		Items   int `json:"items"`
-		URLs    struct {
-			Next string `json:"next"`
-			Last string `json:"last"`
-		} `json:"urls"`
-	} `json:"pagination"`
-	Releases []DiscogsRelease `json:"releases"`
+	Pagination DiscogsPagination `json:"pagination"`
+	Releases   []DiscogsRelease  `json:"releases"`
+}
+
+type DiscogsPagination struct {
+	PerPage int `json:"per_page"`
+	Pages   int `json:"pages"`
+	Page    int `json:"page"`
+	Items   int `json:"items"`
+	URLs    struct {
+		Next string `json:"next"`
+		Last string `json:"last"`
+	} `json:"urls"`
 }
 
 type DiscogsRelease struct {
-	ID         int `json:"id"`
-	InstanceID int `json:"instance_id"`
-	FolderID   int `json:"folder_id"`
-	Rating     int `json:"rating"`
-	BasicInfo  struct {
-		ID          int    `json:"id"`
-		Title       string `json:"title"`
-		Year        int    `json:"year"`
-		ResourceURL string `json:"resource_url"`
-		Thumb       string `json:"thumb"`
-		CoverImage  string `json:"cover_image"`
-		Formats     []struct {
-			Qty          string   `json:"qty"`
-			Descriptions []string `json:"descriptions"`
-			Name         string   `json:"name"`
-		} `json:"formats"`
-		Labels []struct {
-			ResourceURL string `json:"resource_url"`
-			EntityType  string `json:"entity_type"`
-			CatNo       string `json:"catno"`
-			ID          int    `json:"id"`
-			Name        string `json:"name"`
-		} `json:"labels"`
-		Artists []struct {
-			ID          int    `json:"id"`
-			Name        string `json:"name"`
-			Join        string `json:"join"`
-			ResourceURL string `json:"resource_url"`
-			ANV         string `json:"anv"`
-			Tracks      string `json:"tracks"`
-			Role        string `json:"role"`
-		} `json:"artists"`
-		Genres []string `json:"genres"`
-		Styles []string `json:"styles"`
-	} `json:"basic_information"`
-	Notes []struct {
+	ID         int              `json:"id"`
+	InstanceID int              `json:"instance_id"`
+	FolderID   int              `json:"folder_id"`
+	Rating     int              `json:"rating"`
+	DateAdded  string           `json:"date_added"`
+	BasicInfo  DiscogsBasicInfo `json:"basic_information"`
+	Notes      []struct {
 		FieldID int    `json:"field_id"`
 		Value   string `json:"value"`
 	} `json:"notes"`
 }
+
+// DiscogsBasicInfo is the release summary Discogs embeds in collection and
+// wantlist items
+type DiscogsBasicInfo struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Year        int    `json:"year"`
+	ResourceURL string `json:"resource_url"`
+	Thumb       string `json:"thumb"`
+	CoverImage  string `json:"cover_image"`
+	MasterID    int    `json:"master_id"` // 0 when the release has no master
+	Formats     []struct {
+		Qty          string   `json:"qty"`
+		Descriptions []string `json:"descriptions"`
+		Name         string   `json:"name"`
+	} `json:"formats"`
+	Labels []struct {
+		ResourceURL string `json:"resource_url"`
+		EntityType  string `json:"entity_type"`
+		CatNo       string `json:"catno"`
+		ID          int    `json:"id"`
+		Name        string `json:"name"`
+	} `json:"labels"`
+	Artists []struct {
+		ID          int    `json:"id"`
+		Name        string `json:"name"`
+		Join        string `json:"join"`
+		ResourceURL string `json:"resource_url"`
+		ANV         string `json:"anv"`
+		Tracks      string `json:"tracks"`
+		Role        string `json:"role"`
+	} `json:"artists"`
+	Genres []string `json:"genres"`
+	Styles []string `json:"styles"`
+}
+
+// DiscogsWantlistResponse is a page of the user's Discogs wantlist
+type DiscogsWantlistResponse struct {
+	Pagination DiscogsPagination `json:"pagination"`
+	Wants      []DiscogsWant     `json:"wants"`
+}
+
+type DiscogsWant struct {
+	ID          int              `json:"id"`
+	Rating      int              `json:"rating"`
+	Notes       string           `json:"notes"`
+	DateAdded   string           `json:"date_added"`
+	ResourceURL string           `json:"resource_url"`
+	BasicInfo   DiscogsBasicInfo `json:"basic_information"`
+}
+
+// SyncMode is how much of the Discogs collection a sync fetches
+type SyncMode string
+
+const (
+	SyncModeFull        SyncMode = "full"        // Every page of every folder, archives removed releases
+	SyncModeIncremental SyncMode = "incremental" // Newest additions until a stored release is reached
+)
+
+// SyncPhase is the stage a sync job has reached, in the order they run
+type SyncPhase string
+
+const (
+	SyncPhaseFolders  SyncPhase = "folders"
+	SyncPhaseReleases SyncPhase = "releases"
+	SyncPhaseWantlist SyncPhase = "wantlist"
+	SyncPhaseTracks   SyncPhase = "tracks"
+)
+
 type Sync struct {
 	ID        int64      `json:"id"               db:"id"`
 	SyncStart time.Time  `json:"syncStart"        db:"sync_start"`
 	SyncEnd   *time.Time `json:"syncEnd,omitzero" db:"sync_end"`
 	Status    string     `json:"status"           db:"status"` // "in_progress" or "complete" or "failed"
+	Mode      SyncMode   `json:"mode"             db:"mode"`
+
+	// Checkpoint, saved as the job runs so it can resume after a restart
+	Phase             SyncPhase  `json:"phase"                 db:"phase"`
+	SessionID         string     `json:"sessionId"             db:"session_id"`
+	FolderID          *int       `json:"folderId,omitzero"     db:"folder_id"` // Folder being fetched in the releases phase
+	Page              int        `json:"page"                  db:"page"`      // Next page to fetch from FolderID
+	ReleasesProcessed int        `json:"releasesProcessed"     db:"releases_processed"`
+	FailedFolders     int        `json:"failedFolders"         db:"failed_folders"`
+	CheckpointAt      *time.Time `json:"checkpointAt,omitzero" db:"checkpoint_at"`
+}
+
+type DiscogsWriteStatus string
+
+const (
+	DiscogsWritePending DiscogsWriteStatus = "pending"
+	DiscogsWriteDone    DiscogsWriteStatus = "done"
+	DiscogsWriteFailed  DiscogsWriteStatus = "failed" // Rejected by Discogs or out of attempts
+)
+
+// DiscogsWrite is a local rating change and/or folder move queued to be
+// pushed to Discogs
+type DiscogsWrite struct {
+	ID            int64              `json:"id"                 db:"id"`
+	ReleaseID     int                `json:"releaseId"          db:"release_id"`
+	InstanceID    int                `json:"instanceId"         db:"instance_id"`
+	FromFolderID  int                `json:"fromFolderId"       db:"from_folder_id"`
+	Rating        *int               `json:"rating,omitzero"    db:"rating"`
+	FolderID      *int               `json:"folderId,omitzero"  db:"folder_id"`
+	Status        DiscogsWriteStatus `json:"status"             db:"status"`
+	Revision      int                `json:"revision"           db:"revision"`
+	Attempts      int                `json:"attempts"           db:"attempts"`
+	LastError     *string            `json:"lastError,omitzero" db:"last_error"`
+	NextAttemptAt time.Time          `json:"nextAttemptAt"      db:"next_attempt_at"`
+	CreatedAt     time.Time          `json:"createdAt"          db:"created_at"`
+	UpdatedAt     time.Time          `json:"updatedAt"          db:"updated_at"`
+}
+
+// SyncChangeType is the kind of difference a sync found in a release
+type SyncChangeType string
+
+const (
+	SyncChangeAdded           SyncChangeType = "added"
+	SyncChangeRemoved         SyncChangeType = "removed"  // Gone from Discogs, archived by the sync
+	SyncChangeRestored        SyncChangeType = "restored" // Back on Discogs after being archived by a sync
+	SyncChangeFolderMoved     SyncChangeType = "folder_moved"
+	SyncChangeRatingChanged   SyncChangeType = "rating_changed"
+	SyncChangeMetadataChanged SyncChangeType = "metadata_changed"
+)
+
+type SyncChange struct {
+	ID        int64          `json:"id"                db:"id"`
+	SyncID    int64          `json:"syncId"            db:"sync_id"`
+	ReleaseID int            `json:"releaseId"         db:"release_id"`
+	Title     string         `json:"title"             db:"title"`
+	Type      SyncChangeType `json:"type"              db:"change_type"`
+	Field     *string        `json:"field,omitempty"   db:"field"`
+	OldValue  *string        `json:"oldValue,omitzero" db:"old_value"`
+	NewValue  *string        `json:"newValue,omitzero" db:"new_value"`
+	CreatedAt time.Time      `json:"createdAt"         db:"created_at"`
+}
+
+// SyncHistory is a sync with a count of its changes by type
+type SyncHistory struct {
+	Sync
+	Changes map[SyncChangeType]int `json:"changes"`
 }
 
 type ArtistData struct {
@@ -240,8 +473,10 @@ type FormatData struct {
 }
 
 type NoteData struct {
-	FieldID int    `json:"fieldId"`
-	Value   string `json:"value"`
+	FieldID   int                 `json:"fieldId"`
+	FieldName *string             `json:"fieldName"`
+	FieldType CollectionFieldType `json:"fieldType"`
+	Value     string              `json:"value"`
 }
 
 type Stylus struct {
@@ -269,3 +504,232 @@ type PlayHistory struct {
 	Release   Release   `json:"release"         db:"-"`
 	Stylus    *Stylus   `json:"stylus,omitzero" db:"-"`
 }
+
+// WantlistItem is a release on the Discogs wantlist. Acquired items stay
+// after they leave the wantlist so there is a record of what was bought.
+type WantlistItem struct {
+	ReleaseID      int        `json:"releaseId"                db:"release_id"`
+	Title          string     `json:"title"                    db:"title"`
+	Artists        string     `json:"artists"                  db:"artists"`
+	Year           *int       `json:"year"                     db:"year"`
+	ResourceURL    string     `json:"resourceUrl"              db:"resource_url"`
+	Thumb          string     `json:"thumb"                    db:"thumb"`
+	CoverImage     string     `json:"coverImage"               db:"cover_image"`
+	Rating         int        `json:"rating"                   db:"rating"`
+	Notes          string     `json:"notes"                    db:"notes"`
+	DateAdded      *time.Time `json:"dateAdded,omitempty"      db:"date_added"`
+	OnDiscogs      bool       `json:"onDiscogs"                db:"on_discogs"` // Still on the Discogs wantlist
+	Acquired       bool       `json:"acquired"                 db:"acquired"`
+	AcquiredAt     *time.Time `json:"acquiredAt,omitempty"     db:"acquired_at"`
+	AcquiredSyncID *int64     `json:"acquiredSyncId,omitempty" db:"acquired_sync_id"`
+	CreatedAt      time.Time  `json:"createdAt"                db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt"                db:"updated_at"`
+}
+
+// Master groups the owned pressings of one Discogs master release
+type Master struct {
+	ID        int              `json:"id"              db:"id"`
+	Title     string           `json:"title"           db:"title"`
+	Year      *int             `json:"year"            db:"year"`
+	Pressings []MasterPressing `json:"pressings"`
+	Stats     *PlayStats       `json:"stats,omitempty"` // Plays of every copy, including ones no longer owned
+}
+
+type MasterPressing struct {
+	ReleaseID int        `json:"releaseId"       db:"id"`
+	Title     string     `json:"title"           db:"title"`
+	Year      *int       `json:"year"            db:"year"`
+	Country   *string    `json:"country"         db:"country"`
+	Released  *string    `json:"released"        db:"released"`
+	FolderID  int        `json:"folderId"        db:"folder_id"`
+	Thumb     string     `json:"thumb"           db:"thumb"`
+	Stats     *PlayStats `json:"stats,omitempty"`
+}
+
+// PlayStats summarises the plays of a release or master
+type PlayStats struct {
+	PlayCount    int        `json:"playCount"`
+	LastPlayedAt *time.Time `json:"lastPlayedAt,omitempty"`
+	PlaySeconds  int        `json:"playSeconds"` // Plays times the release's play duration
+}
+
+// CollectionValueSnapshot is Discogs's estimate of the collection's worth at
+// one point in time
+type CollectionValueSnapshot struct {
+	ID           int64     `json:"id"               db:"id"`
+	SyncID       *int64    `json:"syncId,omitempty" db:"sync_id"`
+	Minimum      float64   `json:"minimum"          db:"minimum"`
+	Median       float64   `json:"median"           db:"median"`
+	Maximum      float64   `json:"maximum"          db:"maximum"`
+	Currency     string    `json:"currency"         db:"currency"`
+	ReleaseCount int       `json:"releaseCount"     db:"release_count"`
+	CreatedAt    time.Time `json:"createdAt"        db:"created_at"`
+}
+
+// ReleasePrice is the latest marketplace data for a release
+type ReleasePrice struct {
+	ReleaseID       int       `json:"releaseId"             db:"release_id"`
+	LowestPrice     *float64  `json:"lowestPrice,omitempty" db:"lowest_price"` // Nil when none are for sale
+	Currency        *string   `json:"currency,omitempty"    db:"currency"`
+	NumForSale      int       `json:"numForSale"            db:"num_for_sale"`
+	BlockedFromSale bool      `json:"blockedFromSale"       db:"blocked_from_sale"`
+	CheckedAt       time.Time `json:"checkedAt"             db:"checked_at"`
+}
+
+type CollectionFieldType string
+
+const (
+	CollectionFieldDropdown CollectionFieldType = "dropdown" // One of Options, e.g. Media Condition
+	CollectionFieldTextarea CollectionFieldType = "textarea" // Free text
+)
+
+// CollectionField is a field the user can fill in for each release in their
+// Discogs collection, such as Media Condition or Notes
+type CollectionField struct {
+	ID       int                 `json:"id"                db:"id"`
+	Name     string              `json:"name"              db:"name"`
+	Type     CollectionFieldType `json:"type"              db:"type"`
+	Position int                 `json:"position"          db:"position"`
+	Public   bool                `json:"public"            db:"public"`
+	Lines    *int                `json:"lines,omitempty"   db:"lines"`   // Textarea height
+	Options  []string            `json:"options,omitempty" db:"options"` // Dropdown choices, in Discogs order
+}
+
+type DiscogsCollectionField struct {
+	ID       int      `json:"id"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Position int      `json:"position"`
+	Public   bool     `json:"public"`
+	Lines    int      `json:"lines,omitempty"`
+	Options  []string `json:"options,omitempty"`
+}
+
+type NoteFilterOp string
+
+const (
+	NoteFilterEq       NoteFilterOp = "eq"
+	NoteFilterNe       NoteFilterOp = "ne"
+	NoteFilterContains NoteFilterOp = "contains" // Textarea fields only
+	NoteFilterGte      NoteFilterOp = "gte"      // Dropdown fields only, at or before Value in the options
+	NoteFilterLte      NoteFilterOp = "lte"      // Dropdown fields only, at or after Value in the options
+)
+
+// NoteFilter matches releases by the value of one collection field, e.g.
+// Media Condition gte "VG+". Field is a field name or ID.
+type NoteFilter struct {
+	Field string
+	Op    NoteFilterOp
+	Value string
+}
+
+// ReleaseRelation is nested data a release listing can include, named after
+// its field in the JSON
+type ReleaseRelation string
+
+const (
+	ReleaseArtists         ReleaseRelation = "artists"
+	ReleaseLabels          ReleaseRelation = "labels"
+	ReleaseFormats         ReleaseRelation = "formats"
+	ReleaseGenres          ReleaseRelation = "genres"
+	ReleaseStyles          ReleaseRelation = "styles"
+	ReleaseNotes           ReleaseRelation = "notes"
+	ReleaseTracks          ReleaseRelation = "tracks"
+	ReleasePlayHistory     ReleaseRelation = "playHistory"
+	ReleaseCleaningHistory ReleaseRelation = "cleaningHistory"
+)
+
+var AllReleaseRelations = []ReleaseRelation{
+	ReleaseArtists,
+	ReleaseLabels,
+	ReleaseFormats,
+	ReleaseGenres,
+	ReleaseStyles,
+	ReleaseNotes,
+	ReleaseTracks,
+	ReleasePlayHistory,
+	ReleaseCleaningHistory,
+}
+
+type ReleaseSort string
+
+const (
+	ReleaseSortTitle      ReleaseSort = "title"
+	ReleaseSortArtist     ReleaseSort = "artist" // First artist by name
+	ReleaseSortYear       ReleaseSort = "year"
+	ReleaseSortAdded      ReleaseSort = "added"
+	ReleaseSortRating     ReleaseSort = "rating"
+	ReleaseSortLastPlayed ReleaseSort = "lastPlayed"
+	ReleaseSortPlays      ReleaseSort = "plays"
+)
+
+// ReleaseQuery picks a page of the active collection. Genre, Style, Artist
+// and Label are a name or ID, Artist also matching the name an artist is
+// credited under, and Format is a format name such as Vinyl or one of its
+// descriptions such as LP. Empty and nil fields don't filter.
+type ReleaseQuery struct {
+	FolderID         *int
+	Genre            string
+	Style            string
+	Artist           string
+	Label            string
+	Format           string
+	YearFrom         *int
+	YearTo           *int
+	Played           *bool
+	LastPlayedBefore *time.Time // Releases played, but not since. Never played ones are left out.
+	Sort             ReleaseSort
+	Descending       bool
+	Cursor           string // NextCursor of the previous page
+	Limit            int
+	Include          []ReleaseRelation
+}
+
+// ReleasePage is one page of a release listing. NextCursor is empty on the
+// last page.
+type ReleasePage struct {
+	Releases   []Release `json:"releases"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+}
+
+type ImageKind string
+
+const (
+	ImageThumb ImageKind = "thumb"
+	ImageCover ImageKind = "cover"
+)
+
+// ReleaseImage is a release's artwork. SourceURL is where Discogs has it now
+// and Hash names the cached copy, empty until it has been downloaded.
+type ReleaseImage struct {
+	ReleaseID   int        `json:"releaseId"   db:"release_id"`
+	Kind        ImageKind  `json:"kind"        db:"kind"`
+	SourceURL   string     `json:"sourceUrl"   db:"source_url"`
+	Hash        string     `json:"hash"        db:"hash"`
+	ContentType string     `json:"contentType" db:"content_type"`
+	Size        int64      `json:"size"        db:"size"`
+	FetchedAt   *time.Time `json:"fetchedAt"   db:"fetched_at"`
+	Attempts    int        `json:"attempts"    db:"attempts"`
+}
+
+// SearchResults are the hits for a search, by what matched
+type SearchResults struct {
+	Releases  []SearchHit `json:"releases"`
+	Artists   []SearchHit `json:"artists"`
+	Labels    []SearchHit `json:"labels"`
+	Tracks    []SearchHit `json:"tracks"`
+	Plays     []SearchHit `json:"plays"`     // Play notes
+	Cleanings []SearchHit `json:"cleanings"` // Cleaning notes
+}
+
+// SearchHit is a release found by a search. ID is the artist, label, track,
+// play or cleaning that matched, or the release itself, and Text what it
+// matched on. A higher Score is a better match.
+type SearchHit struct {
+	ID           int     `json:"id"`
+	ReleaseID    int     `json:"releaseId"`
+	ReleaseTitle string  `json:"releaseTitle"`
+	Thumb        *string `json:"thumb"`
+	Text         string  `json:"text"`
+	Score        float64 `json:"score"`
+}
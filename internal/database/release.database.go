@@ -6,29 +6,75 @@ import (
 	"fmt"
 	"log/slog"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// GetAllReleases returns the active collection, excluding archived releases
 func (s *Database) GetAllReleases() ([]Release, error) {
-	// Query to get all releases with their related data as JSON
-	query := `
-SELECT 
-    r.id,
-    r.instance_id,
-    r.folder_id,
-    r.rating,
-    r.title,
-    r.year,
-    r.resource_url,
-    r.thumb,
-    r.cover_image,
-    r.play_duration,
-    r.play_duration_estimated,
-    r.created_at,
-    r.updated_at,
-    
+	return s.getReleases("WHERE r.archived = FALSE")
+}
+
+// GetAllReleasesWithArchived returns every release, including archived ones
+func (s *Database) GetAllReleasesWithArchived() ([]Release, error) {
+	return s.getReleases("")
+}
+
+// GetArchivedReleases returns only archived releases, most recently archived first
+func (s *Database) GetArchivedReleases() ([]Release, error) {
+	releases, err := s.getReleases("WHERE r.archived = TRUE")
+	if err != nil {
+		return releases, err
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		if releases[i].ArchivedAt == nil || releases[j].ArchivedAt == nil {
+			return releases[i].ArchivedAt != nil
+		}
+		return releases[i].ArchivedAt.After(*releases[j].ArchivedAt)
+	})
+
+	return releases, nil
+}
+
+// GetRelease returns one release, archived or not, with its identifiers,
+// companies, credits and marketplace price. It returns sql.ErrNoRows when there is no such release.
+func (s *Database) GetRelease(id int) (Release, error) {
+	releases, err := s.getReleases("WHERE r.id = ?", id)
+	if err != nil {
+		return Release{}, err
+	}
+	if len(releases) == 0 {
+		return Release{}, sql.ErrNoRows
+	}
+
+	release := releases[0]
+	if err := s.loadReleaseDetails(&release); err != nil {
+		return Release{}, err
+	}
+
+	release.Price, err = s.getReleasePrice(release.ID)
+	if err != nil {
+		return Release{}, err
+	}
+
+	return release, nil
+}
+
+func (s *Database) getReleases(where string, args ...any) ([]Release, error) {
+	return s.selectReleases(AllReleaseRelations, where+"\nORDER BY r.title", args...)
+}
+
+// releaseRelationColumns select each relation of a release as a JSON array,
+// in the order selectReleases scans them
+var releaseRelationColumns = []struct {
+	relation ReleaseRelation
+	column   string
+}{
+	{ReleaseArtists, `
     -- Artists (JSON array)
     (
         SELECT json_group_array(
@@ -45,8 +91,8 @@ SELECT
         FROM release_artists ra
         JOIN artists a ON ra.artist_id = a.id
         WHERE ra.release_id = r.id
-    ) AS artists,
-    
+    ) AS artists`},
+	{ReleaseLabels, `
     -- Labels (JSON array)
     (
         SELECT json_group_array(
@@ -61,8 +107,8 @@ SELECT
         FROM release_labels rl
         JOIN labels l ON rl.label_id = l.id
         WHERE rl.release_id = r.id
-    ) AS labels,
-    
+    ) AS labels`},
+	{ReleaseFormats, `
     -- Formats with descriptions (JSON array)
     (
         SELECT json_group_array(
@@ -79,8 +125,8 @@ SELECT
         )
         FROM formats f
         WHERE f.release_id = r.id
-    ) AS formats,
-    
+    ) AS formats`},
+	{ReleaseGenres, `
     -- Genres (JSON array)
     (
         SELECT json_group_array(
@@ -92,8 +138,8 @@ SELECT
         FROM release_genres rg
         JOIN genres g ON rg.genre_id = g.id
         WHERE rg.release_id = r.id
-    ) AS genres,
-    
+    ) AS genres`},
+	{ReleaseStyles, `
     -- Styles (JSON array)
     (
         SELECT json_group_array(
@@ -105,20 +151,23 @@ SELECT
         FROM release_styles rs
         JOIN styles s ON rs.style_id = s.id
         WHERE rs.release_id = r.id
-    ) AS styles,
-    
+    ) AS styles`},
+	{ReleaseNotes, `
     -- Notes (JSON array)
     (
         SELECT json_group_array(
             json_object(
-                'field_id', rn.field_id,
+                'fieldId', rn.field_id,
+                'fieldName', cf.name,
+                'fieldType', cf.type,
                 'value', rn.value
             )
         )
         FROM release_notes rn
+        LEFT JOIN collection_fields cf ON cf.id = rn.field_id
         WHERE rn.release_id = r.id
-    ) AS notes,
-    
+    ) AS notes`},
+	{ReleaseTracks, `
     -- Tracks (JSON array)
     (
         SELECT json_group_array(
@@ -130,12 +179,12 @@ SELECT
                 'duration_text', t.duration_text,
                 'duration_seconds', t.duration_seconds
             )
+            ORDER BY t.id
         )
         FROM tracks t
         WHERE t.release_id = r.id
-        ORDER BY t.id
-    ) AS tracks,
-    
+    ) AS tracks`},
+	{ReleasePlayHistory, `
     -- Play History (JSON array)
     (
         SELECT json_group_array(
@@ -165,12 +214,12 @@ SELECT
                     WHERE s.id = ph.stylus_id
                 ) ELSE NULL END
             )
+            ORDER BY ph.played_at DESC
         )
         FROM play_history ph
         WHERE ph.release_id = r.id
-        ORDER BY ph.played_at DESC
-    ) AS play_history,
-    
+    ) AS play_history`},
+	{ReleaseCleaningHistory, `
     -- Cleaning History (JSON array)
     (
         SELECT json_group_array(
@@ -182,15 +231,53 @@ SELECT
                 'created_at', ch.created_at,
                 'updated_at', ch.updated_at
             )
+            ORDER BY ch.cleaned_at DESC
         )
         FROM cleaning_history ch
         WHERE ch.release_id = r.id
-        ORDER BY ch.cleaned_at DESC
-    ) AS cleaning_history
+    ) AS cleaning_history`},
+}
+
+// selectReleases runs clauses, the WHERE, ORDER BY and LIMIT, against the
+// releases, loading only the relations in include
+func (s *Database) selectReleases(include []ReleaseRelation, clauses string, args ...any) ([]Release, error) {
+	relations := make([]string, len(releaseRelationColumns))
+	for i, relation := range releaseRelationColumns {
+		if slices.Contains(include, relation.relation) {
+			relations[i] = relation.column
+		} else {
+			relations[i] = "\n    NULL"
+		}
+	}
+
+	// Query to get all releases with their related data as JSON
+	query := `
+SELECT 
+    r.id,
+    r.instance_id,
+    r.folder_id,
+    r.rating,
+    r.title,
+    r.year,
+    r.resource_url,
+    r.thumb,
+    r.cover_image,
+    r.play_duration,
+    r.play_duration_estimated,
+    r.archived,
+    r.archived_at,
+    r.archive_reason,
+    r.date_added,
+    r.country,
+    r.released,
+    r.master_id,
+    r.created_at,
+    r.updated_at,
+` + strings.Join(relations, ",") + `
 FROM releases r
-ORDER BY r.title`
+` + clauses
 	// Execute the query
-	rows, err := s.DB.Query(query)
+	rows, err := s.DB.Query(query, args...)
 	if err != nil {
 		slog.Error("Error querying releases", "error", err)
 		return nil, err
@@ -218,6 +305,13 @@ ORDER BY r.title`
 			&release.CoverImage,
 			&release.PlayDuration,
 			&release.PlayDurationEstimated,
+			&release.Archived,
+			&release.ArchivedAt,
+			&release.ArchiveReason,
+			&release.DateAdded,
+			&release.Country,
+			&release.Released,
+			&release.MasterID,
 			&release.CreatedAt,
 			&release.UpdatedAt,
 			&artistsJSON,
@@ -326,8 +420,12 @@ ORDER BY r.title`
 				note := ReleaseNote{
 					ReleaseID: release.ID,
 					FieldID:   n.FieldID,
+					FieldType: n.FieldType,
 					Value:     n.Value,
 				}
+				if n.FieldName != nil {
+					note.FieldName = *n.FieldName
+				}
 				release.Notes = append(release.Notes, note)
 			}
 		}
@@ -358,19 +456,20 @@ ORDER BY r.title`
 
 				// If stylus data is present, unmarshal it
 				if len(ph.Stylus) > 2 { // Check if not null or empty {}
-					// Temporary struct with string fields for dates and int for bools
+					// Temporary struct with string fields for dates and
+					// jsonBool for bools, which SQLite gives as 0 or 1
 					var tempStylus struct {
-						ID               int     `json:"id"`
-						Name             string  `json:"name"`
-						Manufacturer     string  `json:"manufacturer"`
-						ExpectedLifespan int     `json:"expected_lifespan_hours"`
-						PurchaseDate     *string `json:"purchase_date"`
-						Active           int     `json:"active"`
-						Primary          int     `json:"primary_stylus"`
-						CreatedAt        string  `json:"created_at"`
-						UpdatedAt        string  `json:"updated_at"`
-						Owned            int     `json:"owned"`
-						BaseModel        int     `json:"base_model"`
+						ID               int      `json:"id"`
+						Name             string   `json:"name"`
+						Manufacturer     string   `json:"manufacturer"`
+						ExpectedLifespan int      `json:"expected_lifespan_hours"`
+						PurchaseDate     *string  `json:"purchase_date"`
+						Active           jsonBool `json:"active"`
+						Primary          jsonBool `json:"primary_stylus"`
+						CreatedAt        string   `json:"created_at"`
+						UpdatedAt        string   `json:"updated_at"`
+						Owned            jsonBool `json:"owned"`
+						BaseModel        jsonBool `json:"base_model"`
 					}
 
 					if err := json.Unmarshal(ph.Stylus, &tempStylus); err == nil {
@@ -380,7 +479,7 @@ ORDER BY r.title`
 
 						// Parse created_at and updated_at
 						if tempStylus.CreatedAt != "" {
-							t, err := time.Parse("2006-01-02 15:04:05", tempStylus.CreatedAt)
+							t, err := parseStoredTime(tempStylus.CreatedAt)
 							if err != nil {
 								slog.Error(
 									"Failed to parse created_at",
@@ -395,7 +494,7 @@ ORDER BY r.title`
 						}
 
 						if tempStylus.UpdatedAt != "" {
-							t, err := time.Parse("2006-01-02 15:04:05", tempStylus.UpdatedAt)
+							t, err := parseStoredTime(tempStylus.UpdatedAt)
 							if err != nil {
 								slog.Error(
 									"Failed to parse updated_at",
@@ -411,17 +510,7 @@ ORDER BY r.title`
 
 						// Parse purchase_date (which might be null)
 						if tempStylus.PurchaseDate != nil && *tempStylus.PurchaseDate != "" {
-							// Try parsing with timezone first, then without
-							var t time.Time
-							var err error
-							
-							// Try format with timezone first
-							t, err = time.Parse("2006-01-02 15:04:05-07:00", *tempStylus.PurchaseDate)
-							if err != nil {
-								// Fallback to format without timezone
-								t, err = time.Parse("2006-01-02 15:04:05", *tempStylus.PurchaseDate)
-							}
-							
+							t, err := parseStoredTime(*tempStylus.PurchaseDate)
 							if err != nil {
 								slog.Error(
 									"Failed to parse purchase_date",
@@ -442,12 +531,12 @@ ORDER BY r.title`
 							Manufacturer:     tempStylus.Manufacturer,
 							ExpectedLifespan: tempStylus.ExpectedLifespan,
 							PurchaseDate:     purchaseDate,
-							Active:           tempStylus.Active != 0,
-							Primary:          tempStylus.Primary != 0,
+							Active:           bool(tempStylus.Active),
+							Primary:          bool(tempStylus.Primary),
 							CreatedAt:        createdAt,
 							UpdatedAt:        updatedAt,
-							Owned:            tempStylus.Owned != 0,
-							BaseModel:        tempStylus.BaseModel != 0,
+							Owned:            bool(tempStylus.Owned),
+							BaseModel:        bool(tempStylus.BaseModel),
 						}
 						playHistory.Stylus = &stylusData
 					} else {
@@ -529,7 +618,7 @@ ORDER BY r.title`
 		return releases, err
 	}
 
-	slog.Info("Successfully fetched all releases", "count", len(releases))
+	slog.Info("Successfully fetched releases", "count", len(releases), "filter", clauses)
 	return releases, nil
 }
 
@@ -551,6 +640,38 @@ func parseTime(timeStr string) time.Time {
 	return t
 }
 
+// parseStoredTime parses a timestamp as it comes out of JSON built by the
+// database: "2006-01-02 15:04:05" with or without an offset from SQLite, or
+// RFC 3339 from Postgres
+func parseStoredTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04:05-07:00", time.RFC3339} {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// jsonBool reads a boolean from JSON built by the database, where SQLite
+// gives 0 or 1 and Postgres true or false
+type jsonBool bool
+
+func (b *jsonBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", "1":
+		*b = true
+	case "false", "0", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+
+	return nil
+}
+
 func (s *Database) DeleteRelease(id int) error {
 	_, err := s.DB.Exec("DELETE FROM releases WHERE id = ?", id)
 	if err != nil {
@@ -560,17 +681,110 @@ func (s *Database) DeleteRelease(id int) error {
 	return err
 }
 
-func (s *Database) ArchiveRelease(id int) error {
-	_, err := s.DB.Exec("UPDATE releases SET is_archived = true WHERE id = ?", id)
+// ArchiveRelease hides a release from the active collection while keeping its
+// play and cleaning history
+func (s *Database) ArchiveRelease(id int, reason ArchiveReason) error {
+	result, err := s.DB.Exec(`
+		UPDATE releases
+		SET archived = TRUE, archived_at = CURRENT_TIMESTAMP, archive_reason = ?
+		WHERE id = ?`,
+		reason,
+		id,
+	)
 	if err != nil {
-		slog.Error("Failed to archive release", "error", err)
+		slog.Error("Failed to archive release", "error", err, "releaseID", id)
+		return err
 	}
 
-	return err
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
 }
 
-func (s *Database) SaveReleases(response DiscogsResponse) error {
-	tx, err := dbInstance.DB.Begin()
+// RestoreRelease returns an archived release to the active collection
+func (s *Database) RestoreRelease(id int) error {
+	result, err := s.DB.Exec(`
+		UPDATE releases
+		SET archived = FALSE, archived_at = NULL, archive_reason = NULL
+		WHERE id = ?`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Failed to restore release", "error", err, "releaseID", id)
+		return err
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ArchiveStaleReleases archives every active release that was not seen during
+// the given sync session, i.e. releases no longer in any Discogs folder
+func (s *Database) ArchiveStaleReleases(
+	syncID int64,
+	sessionID string,
+	reason ArchiveReason,
+) (archived int64, err error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin transaction", "error", err)
+		return 0, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	_, err = tx.Exec(`
+		INSERT INTO sync_changes (sync_id, release_id, title, change_type)
+		SELECT ?, id, title, ?
+		FROM releases
+		WHERE archived = FALSE
+		AND (sync_session_id IS NULL OR sync_session_id != ?)`,
+		syncID,
+		SyncChangeRemoved,
+		sessionID,
+	)
+	if err != nil {
+		slog.Error("Failed to record removed releases", "error", err, "syncID", syncID)
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE releases
+		SET archived = TRUE, archived_at = CURRENT_TIMESTAMP, archive_reason = ?
+		WHERE archived = FALSE
+		AND (sync_session_id IS NULL OR sync_session_id != ?)`,
+		reason,
+		sessionID,
+	)
+	if err != nil {
+		slog.Error("Failed to archive stale releases", "error", err, "sessionID", sessionID)
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.Error("Failed to commit archive of stale releases", "error", err)
+		return 0, err
+	}
+
+	archived, _ = result.RowsAffected()
+	return archived, nil
+}
+
+// SaveReleases upserts a page of releases, recording what changed in each
+// against the given sync
+func (s *Database) SaveReleases(response DiscogsResponse, syncID int64, sessionID string) error {
+	tx, err := s.DB.Begin()
 	if err != nil {
 		slog.Error("Failed to begin transaction", "error", err)
 		return err
@@ -591,7 +805,7 @@ func (s *Database) SaveReleases(response DiscogsResponse) error {
 	for _, release := range response.Releases {
 		// Map the release to our database schema
 		// 1. Insert/Update the main release
-		err = saveRelease(tx, release)
+		err = saveRelease(tx, release, syncID, sessionID)
 		if err != nil {
 			return err
 		}
@@ -643,25 +857,117 @@ func (s *Database) SaveReleases(response DiscogsResponse) error {
 	return nil
 }
 
-// saveRelease handles inserting or updating a release in the database
-func saveRelease(tx *sql.Tx, release DiscogsRelease) error {
+// SaveReleaseBasicInfo overwrites a release's summary with a fresh copy from
+// its release resource. Collection fields such as the folder and rating are
+// left alone, as are images Discogs didn't send.
+func (s *Database) SaveReleaseBasicInfo(releaseID int, info DiscogsBasicInfo) (err error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	result, err := tx.Exec(`
+		UPDATE releases
+		SET title = ?,
+			year = ?,
+			resource_url = COALESCE(NULLIF(?, ''), resource_url),
+			thumb = COALESCE(NULLIF(?, ''), thumb),
+			cover_image = COALESCE(NULLIF(?, ''), cover_image),
+			master_id = COALESCE(NULLIF(?, 0), master_id)
+		WHERE id = ?`,
+		info.Title,
+		info.Year,
+		info.ResourceURL,
+		info.Thumb,
+		info.CoverImage,
+		info.MasterID,
+		releaseID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update release: %w", err)
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		err = sql.ErrNoRows
+		return err
+	}
+
+	// Labels and artists are only ever added by a sync, so clear them first
+	// to drop any Discogs has since removed
+	for _, table := range []string{"release_labels", "release_artists"} {
+		if _, err = tx.Exec("DELETE FROM "+table+" WHERE release_id = ?", releaseID); err != nil {
+			return fmt.Errorf("failed to delete existing %s: %w", table, err)
+		}
+	}
+
+	release := DiscogsRelease{ID: releaseID, BasicInfo: info}
+	for _, save := range []func(*Tx, DiscogsRelease) error{
+		saveLabels,
+		saveArtists,
+		saveFormats,
+		saveGenres,
+		saveStyles,
+	} {
+		if err = save(tx, release); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit release: %w", err)
+	}
+
+	return nil
+}
+
+// saveRelease handles inserting or updating a release in the database.
+// Releases that were auto-archived by a previous sync are restored when they
+// show up on Discogs again; user archives are left alone.
+func saveRelease(tx *Tx, release DiscogsRelease, syncID int64, sessionID string) error {
+	changes, err := diffRelease(tx, release)
+	if err != nil {
+		return err
+	}
+
+	if err := saveSyncChanges(tx, syncID, changes); err != nil {
+		return err
+	}
+
 	// Prepare statement for release upsert
 	stmt, err := tx.Prepare(`
 		INSERT INTO releases (
 			id, instance_id, folder_id, rating, title, year, 
-			resource_url, thumb, cover_image
+			resource_url, thumb, cover_image, date_added, master_id, sync_session_id
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), ?)
 		ON CONFLICT(id) DO UPDATE SET
+			archived = CASE WHEN releases.archive_reason = 'sync_removed' THEN FALSE ELSE releases.archived END,
+			archived_at = CASE WHEN releases.archive_reason = 'sync_removed' THEN NULL ELSE releases.archived_at END,
+			archive_reason = CASE WHEN releases.archive_reason = 'sync_removed' THEN NULL ELSE releases.archive_reason END,
+			sync_session_id = excluded.sync_session_id,
 			instance_id = excluded.instance_id,
-			folder_id = excluded.folder_id,
-			rating = excluded.rating,
+			folder_id = CASE WHEN EXISTS (
+				SELECT 1 FROM discogs_writes w
+				WHERE w.release_id = releases.id AND w.status = 'pending' AND w.folder_id IS NOT NULL
+			) THEN releases.folder_id ELSE excluded.folder_id END,
+			rating = CASE WHEN EXISTS (
+				SELECT 1 FROM discogs_writes w
+				WHERE w.release_id = releases.id AND w.status = 'pending' AND w.rating IS NOT NULL
+			) THEN releases.rating ELSE excluded.rating END,
 			title = excluded.title,
 			year = excluded.year,
 			resource_url = excluded.resource_url,
 			thumb = excluded.thumb,
 			cover_image = excluded.cover_image,
-			updated_at = CURRENT_TIMESTAMP
+			date_added = COALESCE(excluded.date_added, releases.date_added),
+			master_id = COALESCE(excluded.master_id, releases.master_id)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare release statement: %w", err)
@@ -679,16 +985,187 @@ func saveRelease(tx *sql.Tx, release DiscogsRelease) error {
 		release.BasicInfo.ResourceURL,
 		release.BasicInfo.Thumb,
 		release.BasicInfo.CoverImage,
+		parseDateAdded(release.ID, release.DateAdded),
+		release.BasicInfo.MasterID,
+		sessionID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to execute release statement: %w", err)
 	}
 
+	_, err = tx.Exec(`
+		INSERT INTO release_instances (instance_id, release_id, folder_id, rating)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(instance_id) DO UPDATE SET
+			release_id = excluded.release_id,
+			folder_id = excluded.folder_id,
+			rating = excluded.rating,
+			updated_at = CURRENT_TIMESTAMP`,
+		release.InstanceID,
+		release.ID,
+		release.FolderID,
+		release.Rating,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save release instance: %w", err)
+	}
+
 	return nil
 }
 
+// diffRelease compares a release from Discogs with the stored row and returns
+// the changes a save would make. The folder and rating are compared per
+// instance. Labels, artists and formats aren't compared, nor are a rating or
+// folder with a write to Discogs still pending.
+func diffRelease(tx *Tx, release DiscogsRelease) ([]SyncChange, error) {
+	var stored struct {
+		instanceID    int
+		knownInstance bool
+		folderID      int
+		rating        int
+		title         string
+		year          sql.NullInt64
+		resourceURL   sql.NullString
+		thumb         sql.NullString
+		coverImage    sql.NullString
+		archiveReason sql.NullString
+		pendingRating bool
+		pendingFolder bool
+	}
+
+	// A known instance is compared with what Discogs last said about it, so
+	// several copies of a release don't read as moving between each other
+	err := tx.QueryRow(`
+		SELECT r.instance_id, i.instance_id IS NOT NULL,
+			COALESCE(i.folder_id, r.folder_id), COALESCE(i.rating, r.rating),
+			r.title, r.year, r.resource_url, r.thumb, r.cover_image, r.archive_reason,
+			EXISTS (
+				SELECT 1 FROM discogs_writes w
+				WHERE w.release_id = r.id AND w.status = 'pending' AND w.rating IS NOT NULL
+			),
+			EXISTS (
+				SELECT 1 FROM discogs_writes w
+				WHERE w.release_id = r.id AND w.status = 'pending' AND w.folder_id IS NOT NULL
+			)
+		FROM releases r
+		LEFT JOIN release_instances i ON i.instance_id = ? AND i.release_id = r.id
+		WHERE r.id = ?`,
+		release.InstanceID,
+		release.ID,
+	).Scan(
+		&stored.instanceID,
+		&stored.knownInstance,
+		&stored.folderID,
+		&stored.rating,
+		&stored.title,
+		&stored.year,
+		&stored.resourceURL,
+		&stored.thumb,
+		&stored.coverImage,
+		&stored.archiveReason,
+		&stored.pendingRating,
+		&stored.pendingFolder,
+	)
+	if err == sql.ErrNoRows {
+		return []SyncChange{{
+			ReleaseID: release.ID,
+			Title:     release.BasicInfo.Title,
+			Type:      SyncChangeAdded,
+		}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored release %d: %w", release.ID, err)
+	}
+
+	var changes []SyncChange
+	change := func(changeType SyncChangeType, field string, oldValue, newValue any) {
+		old := fmt.Sprint(oldValue)
+		updated := fmt.Sprint(newValue)
+		if old == updated {
+			return
+		}
+
+		syncChange := SyncChange{
+			ReleaseID: release.ID,
+			Title:     release.BasicInfo.Title,
+			Type:      changeType,
+			OldValue:  &old,
+			NewValue:  &updated,
+		}
+		if changeType == SyncChangeMetadataChanged {
+			syncChange.Field = &field
+		}
+		changes = append(changes, syncChange)
+	}
+
+	if stored.archiveReason.String == string(ArchiveReasonSyncRemoved) {
+		changes = append(changes, SyncChange{
+			ReleaseID: release.ID,
+			Title:     release.BasicInfo.Title,
+			Type:      SyncChangeRestored,
+		})
+	}
+
+	if !stored.pendingFolder {
+		change(SyncChangeFolderMoved, "folder_id", stored.folderID, release.FolderID)
+	}
+	if !stored.pendingRating {
+		change(SyncChangeRatingChanged, "rating", stored.rating, release.Rating)
+	}
+	if !stored.knownInstance {
+		change(SyncChangeMetadataChanged, "instance_id", stored.instanceID, release.InstanceID)
+	}
+	change(SyncChangeMetadataChanged, "title", stored.title, release.BasicInfo.Title)
+	change(SyncChangeMetadataChanged, "year", stored.year.Int64, release.BasicInfo.Year)
+	change(SyncChangeMetadataChanged, "resource_url", stored.resourceURL.String, release.BasicInfo.ResourceURL)
+	change(SyncChangeMetadataChanged, "thumb", stored.thumb.String, release.BasicInfo.Thumb)
+	change(SyncChangeMetadataChanged, "cover_image", stored.coverImage.String, release.BasicInfo.CoverImage)
+
+	return changes, nil
+}
+
+// parseDateAdded converts a Discogs date_added timestamp, returning nil when
+// it is missing or malformed so the stored value is kept
+func parseDateAdded(releaseID int, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+
+	dateAdded, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		slog.Warn("Failed to parse date_added",
+			"error", err,
+			"releaseID", releaseID,
+			"dateAdded", value)
+		return nil
+	}
+
+	return &dateAdded
+}
+
+// ReleaseInstanceExists reports whether a collection instance was stored by
+// a sync other than sessionID, so a resumed sync skips past its own releases
+func (s *Database) ReleaseInstanceExists(instanceID int, sessionID string) (bool, error) {
+	var exists bool
+	err := s.DB.QueryRow(
+		`SELECT EXISTS(
+			SELECT 1 FROM release_instances i
+			JOIN releases r ON r.id = i.release_id
+			WHERE i.instance_id = ? AND COALESCE(r.sync_session_id, '') != ?
+		)`,
+		instanceID,
+		sessionID,
+	).Scan(&exists)
+	if err != nil {
+		slog.Error("Failed to check release instance", "error", err, "instanceID", instanceID)
+		return false, err
+	}
+
+	return exists, nil
+}
+
 // saveLabels handles inserting or updating labels and their relationship to releases
-func saveLabels(tx *sql.Tx, release DiscogsRelease,
+func saveLabels(tx *Tx, release DiscogsRelease,
 ) error {
 	// Prepare statement for label upsert
 	labelStmt, err := tx.Prepare(`
@@ -742,7 +1219,7 @@ func saveLabels(tx *sql.Tx, release DiscogsRelease,
 	return nil
 }
 
-func saveArtists(tx *sql.Tx, release DiscogsRelease) error {
+func saveArtists(tx *Tx, release DiscogsRelease) error {
 	// Prepare statement for artist upsert
 	artistStmt, err := tx.Prepare(`
 		INSERT INTO artists (id, name, resource_url)
@@ -805,7 +1282,7 @@ func saveArtists(tx *sql.Tx, release DiscogsRelease) error {
 }
 
 // saveFormats handles inserting or updating formats and their descriptions
-func saveFormats(tx *sql.Tx, release DiscogsRelease,
+func saveFormats(tx *Tx, release DiscogsRelease,
 ) error {
 	// First, delete any existing formats for this release to avoid duplicates
 	_, err := tx.Exec("DELETE FROM formats WHERE release_id = ?", release.ID)
@@ -875,7 +1352,7 @@ func saveFormats(tx *sql.Tx, release DiscogsRelease,
 }
 
 // saveGenres handles inserting or updating genres and their relationship to releases
-func saveGenres(tx *sql.Tx, release DiscogsRelease,
+func saveGenres(tx *Tx, release DiscogsRelease,
 ) error {
 	// First, delete any existing genre relationships for this release
 	_, err := tx.Exec("DELETE FROM release_genres WHERE release_id = ?", release.ID)
@@ -940,7 +1417,7 @@ func saveGenres(tx *sql.Tx, release DiscogsRelease,
 }
 
 // saveStyles handles inserting or updating styles and their relationship to releases
-func saveStyles(tx *sql.Tx, release DiscogsRelease,
+func saveStyles(tx *Tx, release DiscogsRelease,
 ) error {
 	// First, delete any existing style relationships for this release
 	_, err := tx.Exec("DELETE FROM release_styles WHERE release_id = ?", release.ID)
@@ -1005,7 +1482,7 @@ func saveStyles(tx *sql.Tx, release DiscogsRelease,
 }
 
 // saveNotes handles inserting or updating release notes
-func saveNotes(tx *sql.Tx, release DiscogsRelease,
+func saveNotes(tx *Tx, release DiscogsRelease,
 ) error {
 	// First, delete any existing notes for this release
 	_, err := tx.Exec("DELETE FROM release_notes WHERE release_id = ?", release.ID)
@@ -1045,7 +1522,9 @@ func saveNotes(tx *sql.Tx, release DiscogsRelease,
 	return nil
 }
 
-func (s *Database) GetReleasesWithoutDuration() ([]Release, error) {
+// GetReleasesWithoutDetails returns releases still missing a duration or the
+// metadata from the release resource
+func (s *Database) GetReleasesWithoutDetails() ([]Release, error) {
 	query := `
         SELECT 
             r.id, r.resource_url,
@@ -1066,14 +1545,15 @@ func (s *Database) GetReleasesWithoutDuration() ([]Release, error) {
                 WHERE f.release_id = r.id
             ) AS formats
         FROM releases r
-        WHERE r.play_duration IS NULL
+        WHERE (r.play_duration IS NULL OR r.details_synced_at IS NULL)
+        AND r.archived = FALSE
         ORDER BY RANDOM() -- Randomize to distribute across collection
         
     `
 
 	rows, err := s.DB.Query(query)
 	if err != nil {
-		slog.Error("Failed to query releases without duration", "error", err)
+		slog.Error("Failed to query releases without details", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -1112,6 +1592,6 @@ func (s *Database) GetReleasesWithoutDuration() ([]Release, error) {
 		return releases, err
 	}
 
-	slog.Info("Found releases without duration information", "count", len(releases))
+	slog.Info("Found releases without details", "count", len(releases))
 	return releases, nil
 }
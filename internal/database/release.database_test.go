@@ -0,0 +1,141 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// openTestDatabase migrates a new SQLite database in a temporary directory
+func openTestDatabase(t *testing.T) *Database {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "kleio.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// forEachDatabase runs test against a new SQLite database, and against a new
+// Postgres schema when KLEIO_TEST_POSTGRES_DSN is set
+func forEachDatabase(t *testing.T, test func(t *testing.T, db *Database)) {
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, openTestDatabase(t))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		test(t, openTestPostgres(t))
+	})
+}
+
+func testRelease(id, instanceID, folderID int, title string) DiscogsRelease {
+	release := DiscogsRelease{
+		ID:         id,
+		InstanceID: instanceID,
+		FolderID:   folderID,
+		DateAdded:  "2024-01-01T12:00:00-08:00",
+	}
+	release.BasicInfo.ID = id
+	release.BasicInfo.Title = title
+	release.BasicInfo.Year = 1977
+
+	return release
+}
+
+// saveTestSync saves pages of releases the way a full sync does, "All" first
+// and then each folder, and returns the changes it recorded
+func saveTestSync(t *testing.T, db *Database, sessionID string, pages ...[]DiscogsRelease) []SyncChange {
+	t.Helper()
+
+	syncID, err := db.StartSync(SyncModeFull, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range pages {
+		if err := db.SaveReleases(DiscogsResponse{Releases: page}, syncID, sessionID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes, err := db.GetSyncChanges(syncID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return changes
+}
+
+func TestSaveReleasesDiffsEachInstance(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *Database) {
+
+		// Two copies of the same release in different folders, alongside one
+		// that is only owned once
+		first := testRelease(100, 5001, 1, "Rumours")
+		second := testRelease(100, 5002, 2, "Rumours")
+		other := testRelease(200, 5003, 1, "Tusk")
+		collection := func() [][]DiscogsRelease {
+			return [][]DiscogsRelease{
+				{first, second, other},
+				{first, other},
+				{second},
+			}
+		}
+
+		saveTestSync(t, db, "sync_1", collection()...)
+
+		if changes := saveTestSync(t, db, "sync_2", collection()...); len(changes) != 0 {
+			t.Fatalf("unchanged collection recorded %d changes: %+v", len(changes), changes)
+		}
+
+		// Moving one copy is a single move, and the other copy stays put
+		first.FolderID = 3
+		first.Rating = 4
+		changes := saveTestSync(t, db, "sync_3",
+			[]DiscogsRelease{first, second, other},
+			[]DiscogsRelease{other},
+			[]DiscogsRelease{second},
+			[]DiscogsRelease{first},
+		)
+
+		counts := make(map[SyncChangeType]int)
+		for _, change := range changes {
+			counts[change.Type]++
+			if change.ReleaseID != 100 {
+				t.Errorf("unexpected change to release %d: %+v", change.ReleaseID, change)
+			}
+		}
+		if counts[SyncChangeFolderMoved] != 1 || counts[SyncChangeRatingChanged] != 1 || len(changes) != 2 {
+			t.Fatalf("changes = %v, want one folder move and one rating change", counts)
+		}
+		for _, change := range changes {
+			if change.Type == SyncChangeFolderMoved && (*change.OldValue != "1" || *change.NewValue != "3") {
+				t.Errorf("folder moved from %s to %s, want 1 to 3", *change.OldValue, *change.NewValue)
+			}
+		}
+
+		if changes := saveTestSync(t, db, "sync_4",
+			[]DiscogsRelease{first, second, other},
+			[]DiscogsRelease{other},
+			[]DiscogsRelease{second},
+			[]DiscogsRelease{first},
+		); len(changes) != 0 {
+			t.Fatalf("unchanged collection recorded %d changes after a move: %+v", len(changes), changes)
+		}
+	})
+}
+
+func TestSaveReleasesRecordsNewInstance(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *Database) {
+
+		original := testRelease(100, 5001, 1, "Rumours")
+		saveTestSync(t, db, "sync_1", []DiscogsRelease{original})
+
+		// The copy was removed on Discogs and added again, as a new instance
+		replacement := testRelease(100, 5009, 1, "Rumours")
+		changes := saveTestSync(t, db, "sync_2", []DiscogsRelease{replacement})
+		if len(changes) != 1 || changes[0].Field == nil || *changes[0].Field != "instance_id" {
+			t.Fatalf("changes = %+v, want the instance ID change", changes)
+		}
+	})
+}
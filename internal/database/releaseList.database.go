@@ -0,0 +1,292 @@
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidReleaseQuery = errors.New("invalid release query")
+
+const defaultReleasePageSize = 50
+
+// releaseSortKeys are what each sort orders by, before the ID that breaks
+// ties. None of them are NULL, so a cursor can be compared against them.
+var releaseSortKeys = map[ReleaseSort]string{
+	ReleaseSortTitle: "LOWER(r.title)",
+	ReleaseSortArtist: `COALESCE((
+		SELECT LOWER(MIN(a.name))
+		FROM release_artists ra
+		JOIN artists a ON a.id = ra.artist_id
+		WHERE ra.release_id = r.id
+	), '')`,
+	ReleaseSortYear:   "COALESCE(r.year, 0)",
+	ReleaseSortAdded:  "COALESCE(r.date_added, r.created_at, '0001-01-01')",
+	ReleaseSortRating: "r.rating",
+	ReleaseSortLastPlayed: `COALESCE((
+		SELECT MAX(ph.played_at) FROM play_history ph WHERE ph.release_id = r.id
+	), '0001-01-01')`,
+	ReleaseSortPlays: "(SELECT COUNT(*) FROM play_history ph WHERE ph.release_id = r.id)",
+}
+
+// ListReleases returns a page of the active collection matching query, with
+// only the relations it includes. Pages carry on from query.Cursor, which has
+// to come from a listing with the same sort. A release changing its sort key
+// or leaving the collection while paging doesn't move the next page.
+func (s *Database) ListReleases(query ReleaseQuery) (ReleasePage, error) {
+	if query.Sort == "" {
+		query.Sort = ReleaseSortTitle
+	}
+	sortKey, ok := releaseSortKeys[query.Sort]
+	if !ok {
+		return ReleasePage{}, fmt.Errorf("%w: unknown sort %q", ErrInvalidReleaseQuery, query.Sort)
+	}
+	for _, relation := range query.Include {
+		if !slices.Contains(AllReleaseRelations, relation) {
+			return ReleasePage{}, fmt.Errorf("%w: unknown relation %q", ErrInvalidReleaseQuery, relation)
+		}
+	}
+	if query.YearFrom != nil && query.YearTo != nil && *query.YearFrom > *query.YearTo {
+		return ReleasePage{}, fmt.Errorf("%w: yearFrom is after yearTo", ErrInvalidReleaseQuery)
+	}
+	limit := query.Limit
+	if limit < 1 {
+		limit = defaultReleasePageSize
+	}
+
+	conditions := []string{"r.archived = FALSE"}
+	var args []any
+	where := func(condition string, conditionArgs ...any) {
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+
+	if query.FolderID != nil {
+		where("r.folder_id = ?", *query.FolderID)
+	}
+	if value := strings.TrimSpace(query.Genre); value != "" {
+		condition, conditionArgs := nameOrIDCondition(value, "g.id", "g.name")
+		where(`r.id IN (
+			SELECT rg.release_id FROM release_genres rg
+			JOIN genres g ON g.id = rg.genre_id
+			WHERE `+condition+`
+		)`, conditionArgs...)
+	}
+	if value := strings.TrimSpace(query.Style); value != "" {
+		condition, conditionArgs := nameOrIDCondition(value, "st.id", "st.name")
+		where(`r.id IN (
+			SELECT rs.release_id FROM release_styles rs
+			JOIN styles st ON st.id = rs.style_id
+			WHERE `+condition+`
+		)`, conditionArgs...)
+	}
+	if value := strings.TrimSpace(query.Artist); value != "" {
+		condition, conditionArgs := nameOrIDCondition(value, "a.id", "a.name", "ra.anv")
+		where(`r.id IN (
+			SELECT ra.release_id FROM release_artists ra
+			JOIN artists a ON a.id = ra.artist_id
+			WHERE `+condition+`
+		)`, conditionArgs...)
+	}
+	if value := strings.TrimSpace(query.Label); value != "" {
+		condition, conditionArgs := nameOrIDCondition(value, "l.id", "l.name")
+		where(`r.id IN (
+			SELECT rl.release_id FROM release_labels rl
+			JOIN labels l ON l.id = rl.label_id
+			WHERE `+condition+`
+		)`, conditionArgs...)
+	}
+	if value := strings.TrimSpace(query.Format); value != "" {
+		where(`r.id IN (
+			SELECT f.release_id FROM formats f
+			WHERE LOWER(f.name) = LOWER(?) OR f.id IN (
+				SELECT fd.format_id FROM format_descriptions fd
+				WHERE LOWER(fd.description) = LOWER(?)
+			)
+		)`, value, value)
+	}
+	if query.YearFrom != nil {
+		where("r.year >= ?", *query.YearFrom)
+	}
+	if query.YearTo != nil {
+		where("r.year <= ?", *query.YearTo)
+	}
+	if query.Played != nil {
+		played := "EXISTS (SELECT 1 FROM play_history ph WHERE ph.release_id = r.id)"
+		if !*query.Played {
+			played = "NOT " + played
+		}
+		where(played)
+	}
+	if query.LastPlayedBefore != nil {
+		// Releases never played have no last play, so are left out
+		where(
+			"(SELECT MAX(ph.played_at) FROM play_history ph WHERE ph.release_id = r.id) < ?",
+			*query.LastPlayedBefore,
+		)
+	}
+
+	direction, after := "ASC", ">"
+	if query.Descending {
+		direction, after = "DESC", "<"
+	}
+
+	if query.Cursor != "" {
+		key, id, err := decodeReleaseCursor(query.Cursor, query.Sort, query.Descending)
+		if err != nil {
+			return ReleasePage{}, err
+		}
+
+		// Rows after the last release of the previous page, by its sort key
+		// then, so the release being changed or deleted doesn't move the page
+		where(fmt.Sprintf("(%s, r.id) %s (?, ?)", sortKey, after), key, id)
+	}
+
+	// One more than the page, to tell whether there is another
+	args = append(args, limit+1)
+	releases, err := s.selectReleases(
+		query.Include,
+		fmt.Sprintf(
+			"WHERE %s\nORDER BY %s %s, r.id %s\nLIMIT ?",
+			strings.Join(conditions, "\n  AND "),
+			sortKey,
+			direction,
+			direction,
+		),
+		args...,
+	)
+	if err != nil {
+		return ReleasePage{}, err
+	}
+
+	page := ReleasePage{Releases: releases}
+	if page.Releases == nil {
+		page.Releases = []Release{}
+	}
+	if len(page.Releases) > limit {
+		page.Releases = page.Releases[:limit]
+		last := page.Releases[limit-1]
+
+		var key any
+		err := s.DB.QueryRow("SELECT "+sortKey+" FROM releases r WHERE r.id = ?", last.ID).Scan(&key)
+		if err != nil {
+			return ReleasePage{}, fmt.Errorf("failed to read the cursor's sort key: %w", err)
+		}
+		if page.NextCursor, err = encodeReleaseCursor(query.Sort, query.Descending, key, last.ID); err != nil {
+			return ReleasePage{}, err
+		}
+	}
+
+	return page, nil
+}
+
+// nameOrIDCondition matches idColumn when value is a number, or any of
+// nameColumns ignoring case. Names like 311 can be numbers, so they are
+// tried either way.
+func nameOrIDCondition(value, idColumn string, nameColumns ...string) (string, []any) {
+	var conditions []string
+	var args []any
+	if id, err := strconv.Atoi(value); err == nil {
+		conditions = append(conditions, idColumn+" = ?")
+		args = append(args, id)
+	}
+	for _, column := range nameColumns {
+		conditions = append(conditions, "LOWER("+column+") = LOWER(?)")
+		args = append(args, value)
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// encodeReleaseCursor names the last release of a page and its sort key,
+// along with the sort it was listed in, as sort:direction:key:id. The key is
+// prefixed with its type, i for integers, t for times and s for text.
+func encodeReleaseCursor(sort ReleaseSort, descending bool, key any, releaseID int) (string, error) {
+	direction := "asc"
+	if descending {
+		direction = "desc"
+	}
+
+	var encodedKey string
+	switch value := key.(type) {
+	case int64:
+		encodedKey = "i" + strconv.FormatInt(value, 10)
+	case time.Time:
+		encodedKey = "t" + value.Format(time.RFC3339Nano)
+	case string:
+		encodedKey = "s" + value
+	case []byte:
+		encodedKey = "s" + string(value)
+	default:
+		return "", fmt.Errorf("unexpected %T sort key for a cursor", key)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(
+		fmt.Appendf(nil, "%s:%s:%s:%d", sort, direction, encodedKey, releaseID),
+	), nil
+}
+
+// decodeReleaseCursor returns the sort key and release ID in cursor, checking
+// it is from a listing in the given sort
+func decodeReleaseCursor(cursor string, sort ReleaseSort, descending bool) (any, int, error) {
+	malformed := fmt.Errorf("%w: malformed cursor", ErrInvalidReleaseQuery)
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, malformed
+	}
+
+	// The key may itself hold colons, so it is whatever is between the
+	// direction and the ID
+	parts := strings.SplitN(string(decoded), ":", 3)
+	if len(parts) != 3 {
+		return nil, 0, malformed
+	}
+	encodedKey, encodedID, ok := cutLast(parts[2], ":")
+	if !ok || encodedKey == "" {
+		return nil, 0, malformed
+	}
+	id, err := strconv.Atoi(encodedID)
+	if err != nil {
+		return nil, 0, malformed
+	}
+
+	direction := "asc"
+	if descending {
+		direction = "desc"
+	}
+	if parts[0] != string(sort) || parts[1] != direction {
+		return nil, 0, fmt.Errorf("%w: the cursor is from a listing with a different sort", ErrInvalidReleaseQuery)
+	}
+
+	var key any
+	switch value := encodedKey[1:]; encodedKey[0] {
+	case 'i':
+		if key, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, 0, malformed
+		}
+	case 't':
+		if key, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			return nil, 0, malformed
+		}
+	case 's':
+		key = value
+	default:
+		return nil, 0, malformed
+	}
+
+	return key, id, nil
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+
+	return s, "", false
+}
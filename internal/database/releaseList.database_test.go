@@ -0,0 +1,219 @@
+package database
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// saveListTestReleases saves releases 1 to 6, titled A to F. Their years run
+// backwards, so sorting by year reverses the title order.
+func saveListTestReleases(t *testing.T, db *Database) {
+	t.Helper()
+
+	var releases []DiscogsRelease
+	for i, title := range []string{"A", "B", "C", "D", "E", "F"} {
+		release := testRelease(i+1, 5001+i, 1, title)
+		release.BasicInfo.Year = 1990 - i
+		releases = append(releases, release)
+	}
+	saveTestSync(t, db, "sync_1", releases)
+}
+
+// listAllReleases pages through query two releases at a time, returning the
+// IDs in the order they were listed
+func listAllReleases(t *testing.T, db *Database, query ReleaseQuery) []int {
+	t.Helper()
+
+	query.Limit = 2
+	var ids []int
+	for range 10 {
+		page, err := db.ListReleases(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, release := range page.Releases {
+			ids = append(ids, release.ID)
+		}
+		if page.NextCursor == "" {
+			return ids
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	t.Fatalf("listing didn't end, got %v", ids)
+	return nil
+}
+
+func releaseListIDs(releases []Release) []int {
+	ids := make([]int, len(releases))
+	for i, release := range releases {
+		ids[i] = release.ID
+	}
+
+	return ids
+}
+
+func TestReleaseCursorRoundTrip(t *testing.T) {
+	added := time.Date(2024, 1, 1, 12, 30, 0, 500, time.FixedZone("", -8*60*60))
+	tests := []struct {
+		name string
+		key  any
+		want any
+	}{
+		{"integer", int64(1977), int64(1977)},
+		{"text", "rumours", "rumours"},
+		{"text with colons", "a:b:c", "a:b:c"},
+		{"empty text", "", ""},
+		{"bytes", []byte("2024-01-01 12:00:00"), "2024-01-01 12:00:00"},
+		{"time", added, added},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cursor, err := encodeReleaseCursor(ReleaseSortAdded, true, test.key, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			key, id, err := decodeReleaseCursor(cursor, ReleaseSortAdded, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != 42 {
+				t.Errorf("id = %d, want 42", id)
+			}
+			if want, ok := test.want.(time.Time); ok {
+				if got, ok := key.(time.Time); !ok || !got.Equal(want) {
+					t.Errorf("key = %v, want %v", key, want)
+				}
+			} else if key != test.want {
+				t.Errorf("key = %#v, want %#v", key, test.want)
+			}
+
+			if _, _, err := decodeReleaseCursor(cursor, ReleaseSortAdded, false); !errors.Is(err, ErrInvalidReleaseQuery) {
+				t.Errorf("cursor accepted for the other direction: %v", err)
+			}
+			if _, _, err := decodeReleaseCursor(cursor, ReleaseSortTitle, true); !errors.Is(err, ErrInvalidReleaseQuery) {
+				t.Errorf("cursor accepted for another sort: %v", err)
+			}
+		})
+	}
+
+	for _, cursor := range []string{"", "not base64!", "dGl0bGU6YXNj", "dGl0bGU6YXNjOng6MQ"} {
+		if _, _, err := decodeReleaseCursor(cursor, ReleaseSortTitle, false); !errors.Is(err, ErrInvalidReleaseQuery) {
+			t.Errorf("malformed cursor %q accepted: %v", cursor, err)
+		}
+	}
+}
+
+func TestListReleasesPages(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *Database) {
+		saveListTestReleases(t, db)
+
+		tests := []struct {
+			name  string
+			query ReleaseQuery
+			want  []int
+		}{
+			{"by title", ReleaseQuery{}, []int{1, 2, 3, 4, 5, 6}},
+			{"by title descending", ReleaseQuery{Descending: true}, []int{6, 5, 4, 3, 2, 1}},
+			{"by year", ReleaseQuery{Sort: ReleaseSortYear}, []int{6, 5, 4, 3, 2, 1}},
+			{"by rating, then ID", ReleaseQuery{Sort: ReleaseSortRating}, []int{1, 2, 3, 4, 5, 6}},
+			{"by date added", ReleaseQuery{Sort: ReleaseSortAdded, Descending: true}, []int{6, 5, 4, 3, 2, 1}},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				if ids := listAllReleases(t, db, test.query); !slices.Equal(ids, test.want) {
+					t.Errorf("listed %v, want %v", ids, test.want)
+				}
+			})
+		}
+	})
+}
+
+func TestListReleasesCursorSurvivesChanges(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *Database) {
+		saveListTestReleases(t, db)
+
+		first, err := db.ListReleases(ReleaseQuery{Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The last release of the page is renamed to the end of the listing, and
+		// then leaves the collection
+		if _, err := db.DB.Exec("UPDATE releases SET title = 'Z' WHERE id = 2"); err != nil {
+			t.Fatal(err)
+		}
+		second, err := db.ListReleases(ReleaseQuery{Limit: 2, Cursor: first.NextCursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(second.Releases) != 2 || second.Releases[0].ID != 3 || second.Releases[1].ID != 4 {
+			t.Errorf("page after a rename = %v, want C and D", releaseListIDs(second.Releases))
+		}
+
+		if _, err := db.DB.Exec("DELETE FROM releases WHERE id = 2"); err != nil {
+			t.Fatal(err)
+		}
+		second, err = db.ListReleases(ReleaseQuery{Limit: 2, Cursor: first.NextCursor})
+		if err != nil {
+			t.Fatalf("cursor of a deleted release rejected: %v", err)
+		}
+		if len(second.Releases) != 2 || second.Releases[0].ID != 3 {
+			t.Errorf("page after a delete = %v, want C and D", releaseListIDs(second.Releases))
+		}
+	})
+}
+
+func TestListReleasesFilters(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *Database) {
+		saveListTestReleases(t, db)
+
+		plays := map[int]time.Time{
+			1: time.Date(2024, 1, 10, 20, 0, 0, 0, time.UTC),
+			2: time.Date(2024, 6, 10, 20, 0, 0, 0, time.UTC),
+			3: time.Date(2024, 6, 12, 20, 0, 0, 0, time.UTC),
+		}
+		for releaseID, playedAt := range plays {
+			if err := db.CreatePlayHistory(&PlayHistory{ReleaseID: releaseID, PlayedAt: playedAt}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Release 1 was also played earlier, so only its latest play counts
+		if err := db.CreatePlayHistory(&PlayHistory{ReleaseID: 1, PlayedAt: plays[1].AddDate(0, 0, -5)}); err != nil {
+			t.Fatal(err)
+		}
+
+		yes, no := true, false
+		from, to := 1986, 1988
+		before := time.Date(2024, 6, 11, 0, 0, 0, 0, time.UTC)
+		tests := []struct {
+			name  string
+			query ReleaseQuery
+			want  []int
+		}{
+			{"years", ReleaseQuery{YearFrom: &from, YearTo: &to}, []int{3, 4, 5}},
+			{"played", ReleaseQuery{Played: &yes}, []int{1, 2, 3}},
+			{"never played", ReleaseQuery{Played: &no}, []int{4, 5, 6}},
+			{"last played before, leaving out never played", ReleaseQuery{LastPlayedBefore: &before}, []int{1, 2}},
+			{"by last played", ReleaseQuery{Sort: ReleaseSortLastPlayed, Descending: true, Played: &yes}, []int{3, 2, 1}},
+			{"by plays", ReleaseQuery{Sort: ReleaseSortPlays, Descending: true}, []int{1, 3, 2, 6, 5, 4}},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				if ids := listAllReleases(t, db, test.query); !slices.Equal(ids, test.want) {
+					t.Errorf("listed %v, want %v", ids, test.want)
+				}
+			})
+		}
+
+		if _, err := db.ListReleases(ReleaseQuery{YearFrom: &to, YearTo: &from}); !errors.Is(err, ErrInvalidReleaseQuery) {
+			t.Errorf("yearFrom after yearTo returned %v", err)
+		}
+	})
+}
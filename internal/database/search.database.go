@@ -0,0 +1,134 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+)
+
+// checkFTS5 makes sure SQLite was built with FTS5, which the search index
+// needs. go-sqlite3 only includes it with the sqlite_fts5 build tag.
+func checkFTS5(db *sql.DB) error {
+	var enabled bool
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled); err != nil {
+		return fmt.Errorf("failed to check for FTS5: %w", err)
+	}
+	if !enabled {
+		return errors.New("SQLite was built without FTS5, build Kleio with -tags sqlite_fts5")
+	}
+
+	return nil
+}
+
+// Search finds releases by title, artist (including the names they are
+// credited under), label and catalog number, track title and play and
+// cleaning notes. Every word of query has to be in the same piece of text, as
+// a word or the start of one. Up to limit hits of each kind are returned, best
+// first. Archived releases are left out.
+func (s *Database) Search(query string, limit int) (SearchResults, error) {
+	results := SearchResults{
+		Releases:  []SearchHit{},
+		Artists:   []SearchHit{},
+		Labels:    []SearchHit{},
+		Tracks:    []SearchHit{},
+		Plays:     []SearchHit{},
+		Cleanings: []SearchHit{},
+	}
+
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return results, nil
+	}
+
+	// Words are only letters and numbers, so they can't break out of the
+	// match syntax
+	var from, match, rank string
+	if s.dialect.name == postgresDialect.name {
+		from = "search_entries e, to_tsquery('simple', ?) AS q"
+		match = "e.document @@ q"
+		rank = "ts_rank(e.document, q)"
+		for i, word := range words {
+			words[i] = word + ":*"
+		}
+		query = strings.Join(words, " & ")
+	} else {
+		from = "search_index JOIN search_entries e ON e.id = search_index.rowid"
+		match = "search_index MATCH ?"
+		rank = "-bm25(search_index)"
+		for i, word := range words {
+			words[i] = `"` + word + `"*`
+		}
+		query = strings.Join(words, " ")
+	}
+
+	// bm25 can only be used in the query doing the matching, so the hits are
+	// scored before they are ranked within their kind
+	rows, err := s.DB.Query(`
+		WITH matches AS MATERIALIZED (
+			SELECT e.id, e.kind, e.ref_id, e.release_id, e.body, `+rank+` AS score
+			FROM `+from+`
+			WHERE `+match+`
+		)
+		SELECT kind, ref_id, release_id, title, thumb, body, score
+		FROM (
+			SELECT
+				m.kind, m.ref_id, m.release_id, r.title, r.thumb, m.body, m.score,
+				ROW_NUMBER() OVER (PARTITION BY m.kind ORDER BY m.score DESC, m.id) AS position
+			FROM matches m
+			JOIN releases r ON r.id = m.release_id AND r.archived = FALSE
+		) hits
+		WHERE position <= ?
+		ORDER BY kind, position`,
+		query,
+		limit,
+	)
+	if err != nil {
+		slog.Error("Failed to search", "error", err)
+		return results, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind string
+		var hit SearchHit
+		err := rows.Scan(
+			&kind,
+			&hit.ID,
+			&hit.ReleaseID,
+			&hit.ReleaseTitle,
+			&hit.Thumb,
+			&hit.Text,
+			&hit.Score,
+		)
+		if err != nil {
+			slog.Error("Failed to scan search hit", "error", err)
+			return results, err
+		}
+
+		switch kind {
+		case "release":
+			results.Releases = append(results.Releases, hit)
+		case "artist":
+			results.Artists = append(results.Artists, hit)
+		case "label":
+			results.Labels = append(results.Labels, hit)
+		case "track":
+			results.Tracks = append(results.Tracks, hit)
+		case "play":
+			results.Plays = append(results.Plays, hit)
+		case "cleaning":
+			results.Cleanings = append(results.Cleanings, hit)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating search hits", "error", err)
+		return results, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"kleio/internal/logging"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// secretPrefix marks a value encrypted by sealSecret. Values without it are
+// plain text from before encryption was turned on.
+const secretPrefix = "enc:v1:"
+
+// secretKeyFile is where the generated key is kept in the data directory
+// when KLEIO_SECRET_KEY isn't set
+const secretKeyFile = "secret.key"
+
+// ErrSecretKeyMissing is returned when a stored credential is encrypted but
+// there is no key to decrypt it with
+var ErrSecretKeyMissing = errors.New("stored credentials are encrypted but no secret key is set")
+
+// loadSecretKey reads KLEIO_SECRET_KEY, or when it isn't set the key file in
+// dataDir, generating one on the first run. A base64 encoded 32 byte key is
+// used as it is, anything else is treated as a passphrase and hashed down to
+// one.
+func loadSecretKey(dataDir string) (cipher.AEAD, error) {
+	value := os.Getenv("KLEIO_SECRET_KEY")
+	if value == "" {
+		var err error
+		if value, err = readSecretKeyFile(filepath.Join(dataDir, secretKeyFile)); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(key) != 32 {
+		sum := sha256.Sum256([]byte(value))
+		key = sum[:]
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// readSecretKeyFile reads the key at path, writing a new random one readable
+// only by Kleio's user if there isn't one yet
+func readSecretKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		value := strings.TrimSpace(string(data))
+		if value == "" {
+			return "", fmt.Errorf("secret key file %s is empty", path)
+		}
+		return value, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to read secret key file: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate secret key: %w", err)
+	}
+	value := base64.StdEncoding.EncodeToString(key)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create secret key file: %w", err)
+	}
+	if _, err := file.WriteString(value + "\n"); err != nil {
+		file.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write secret key file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write secret key file: %w", err)
+	}
+
+	slog.Warn("KLEIO_SECRET_KEY is not set, generated a key to encrypt Discogs credentials with. Back it up along with the database.", "path", path)
+	return value, nil
+}
+
+// sealSecret encrypts a credential for storage, or returns it unchanged when
+// there is no key
+func (s *Database) sealSecret(plain string) (string, error) {
+	logging.RegisterSecret(plain)
+	if s.secrets == nil || plain == "" {
+		return plain, nil
+	}
+
+	nonce := make([]byte, s.secrets.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := s.secrets.Seal(nonce, nonce, []byte(plain), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openSecret decrypts a stored credential. Plain text values are returned as
+// they are.
+func (s *Database) openSecret(stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, secretPrefix)
+	if !ok {
+		logging.RegisterSecret(stored)
+		return stored, nil
+	}
+
+	if s.secrets == nil {
+		return "", ErrSecretKeyMissing
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < s.secrets.NonceSize() {
+		return "", errors.New("stored credential is corrupt")
+	}
+
+	nonceSize := s.secrets.NonceSize()
+	plain, err := s.secrets.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt stored credential, check KLEIO_SECRET_KEY or the secret key file")
+	}
+
+	logging.RegisterSecret(string(plain))
+	return string(plain), nil
+}
+
+// secretColumns are the columns holding credentials, as table and column
+var secretColumns = [][2]string{
+	{"auth", "token"},
+	{"oauth_credentials", "access_token"},
+	{"oauth_credentials", "access_token_secret"},
+	{"oauth_request_tokens", "secret"},
+}
+
+// encryptStoredSecrets encrypts credentials saved before a key was set
+func (s *Database) encryptStoredSecrets() error {
+	if s.secrets == nil {
+		return nil
+	}
+
+	for _, column := range secretColumns {
+		table, name := column[0], column[1]
+		rows, err := s.DB.Query(fmt.Sprintf(
+			"SELECT DISTINCT %s FROM %s WHERE %s != '' AND %s NOT LIKE '%s%%'",
+			name, table, name, name, secretPrefix,
+		))
+		if err != nil {
+			return err
+		}
+
+		var plain []string
+		for rows.Next() {
+			var value string
+			if err := rows.Scan(&value); err != nil {
+				rows.Close()
+				return err
+			}
+			plain = append(plain, value)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// Rows are found by the old value, not every table has a rowid
+		for _, value := range plain {
+			sealed, err := s.sealSecret(value)
+			if err != nil {
+				return err
+			}
+			_, err = s.DB.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", table, name, name), sealed, value)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(plain) > 0 {
+			slog.Info("Encrypted stored credentials", "table", table, "column", name, "count", len(plain))
+		}
+	}
+
+	return nil
+}
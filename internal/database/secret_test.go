@@ -0,0 +1,70 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretKeyGeneratedOnFirstRun(t *testing.T) {
+	t.Setenv("KLEIO_SECRET_KEY", "")
+	t.Setenv("DATA_DIR", "")
+	path := filepath.Join(t.TempDir(), "kleio.db")
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveToken("discogs-token", "kleio"); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored string
+	if err := db.DB.QueryRow("SELECT token FROM auth").Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored, secretPrefix) {
+		t.Errorf("token stored as %q, want it encrypted", stored)
+	}
+	db.Close()
+
+	info, err := os.Stat(filepath.Join(filepath.Dir(path), secretKeyFile))
+	if err != nil {
+		t.Fatalf("no key file written: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("key file mode = %o, want 600", mode)
+	}
+
+	// The next start reads the same key back
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	token, err := db.GetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "discogs-token" {
+		t.Errorf("token = %q after reopening", token)
+	}
+}
+
+func TestSecretKeyFromEnvironment(t *testing.T) {
+	t.Setenv("KLEIO_SECRET_KEY", "a passphrase")
+	t.Setenv("DATA_DIR", "")
+	dir := t.TempDir()
+
+	db, err := Open(filepath.Join(dir, "kleio.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, secretKeyFile)); err == nil {
+		t.Error("key file written although KLEIO_SECRET_KEY is set")
+	}
+}
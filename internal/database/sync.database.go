@@ -2,25 +2,47 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
 	"log/slog"
 )
 
+const syncColumns = `
+	id, sync_start, sync_end, status, mode,
+	phase, COALESCE(session_id, ''), folder_id, page,
+	releases_processed, failed_folders, checkpoint_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSync(row rowScanner, sync *Sync) error {
+	return row.Scan(
+		&sync.ID,
+		&sync.SyncStart,
+		&sync.SyncEnd,
+		&sync.Status,
+		&sync.Mode,
+		&sync.Phase,
+		&sync.SessionID,
+		&sync.FolderID,
+		&sync.Page,
+		&sync.ReleasesProcessed,
+		&sync.FailedFolders,
+		&sync.CheckpointAt,
+	)
+}
+
 func (s *Database) GetLatestSync() (Sync, error) {
 	slog.Debug("Retrieving latest sync record")
 
 	var sync Sync
 	query := `
-		SELECT id, sync_start, sync_end, status
+		SELECT ` + syncColumns + `
 		FROM syncs
 		ORDER BY id DESC
 		LIMIT 1`
 
-	err := s.DB.QueryRow(query).Scan(
-		&sync.ID,
-		&sync.SyncStart,
-		&sync.SyncEnd,
-		&sync.Status,
-	)
+	err := scanSync(s.DB.QueryRow(query), &sync)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			slog.Debug("No sync records found in database")
@@ -38,37 +60,109 @@ func (s *Database) GetLatestSync() (Sync, error) {
 	return sync, nil
 }
 
-func (s *Database) StartSync() (int64, error) {
-	slog.Info("Starting new sync operation")
+// GetLatestCompletedSync returns the most recent successful sync of the given
+// mode, or a zero Sync if there hasn't been one
+func (s *Database) GetLatestCompletedSync(mode SyncMode) (Sync, error) {
+	var sync Sync
+	query := `
+		SELECT ` + syncColumns + `
+		FROM syncs
+		WHERE status = 'complete' AND mode = ?
+		ORDER BY id DESC
+		LIMIT 1`
+
+	err := scanSync(s.DB.QueryRow(query, mode), &sync)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Sync{}, nil
+		}
+		slog.Error("Database query error for latest completed sync", "error", err, "mode", mode)
+		return Sync{}, err
+	}
+
+	return sync, nil
+}
+
+func (s *Database) StartSync(mode SyncMode, sessionID string) (int64, error) {
+	slog.Info("Starting new sync operation", "mode", mode, "sessionID", sessionID)
 
 	query := `
-        INSERT INTO syncs (sync_start, status)
-        VALUES (CURRENT_TIMESTAMP, 'in_progress')
+        INSERT INTO syncs (sync_start, status, mode, phase, session_id)
+        VALUES (CURRENT_TIMESTAMP, 'in_progress', ?, 'folders', ?)
+        RETURNING id
     `
-	result, err := s.DB.Exec(query)
-	if err != nil {
+	var id int64
+	if err := s.DB.QueryRow(query, mode, sessionID).Scan(&id); err != nil {
 		slog.Error("Failed to insert new sync record", "error", err, "query", query)
 		return 0, err
 	}
 
-	id, err := result.LastInsertId()
+	slog.Info("Created new sync record", "syncID", id)
+	return id, nil
+}
+
+// SaveSyncCheckpoint records how far a sync job has got
+func (s *Database) SaveSyncCheckpoint(sync Sync) error {
+	query := `
+		UPDATE syncs
+		SET mode = ?,
+			phase = ?,
+			folder_id = ?,
+			page = ?,
+			releases_processed = ?,
+			failed_folders = ?,
+			checkpoint_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	_, err := s.DB.Exec(
+		query,
+		sync.Mode,
+		sync.Phase,
+		sync.FolderID,
+		sync.Page,
+		sync.ReleasesProcessed,
+		sync.FailedFolders,
+		sync.ID,
+	)
 	if err != nil {
-		slog.Error(
-			"Failed to get last insert id for sync",
-			"error",
-			err,
-			"query",
-			query,
-			"result",
-			result,
-			"lastInsertId",
-			id,
-		)
-		return 0, err
+		slog.Error("Failed to save sync checkpoint",
+			"error", err,
+			"syncID", sync.ID,
+			"phase", sync.Phase,
+			"page", sync.Page)
+		return err
 	}
 
-	slog.Info("Created new sync record", "syncID", id)
-	return id, nil
+	return nil
+}
+
+// GetInterruptedSyncs returns syncs still marked in progress, newest first.
+// Called at startup, when nothing can actually be running yet.
+func (s *Database) GetInterruptedSyncs() ([]Sync, error) {
+	query := `
+		SELECT ` + syncColumns + `
+		FROM syncs
+		WHERE status = 'in_progress'
+		ORDER BY id DESC`
+
+	rows, err := s.DB.Query(query)
+	if err != nil {
+		slog.Error("Failed to query interrupted syncs", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var syncs []Sync
+	for rows.Next() {
+		var sync Sync
+		if err := scanSync(rows, &sync); err != nil {
+			slog.Error("Failed to scan interrupted sync", "error", err)
+			return nil, err
+		}
+		syncs = append(syncs, sync)
+	}
+
+	return syncs, rows.Err()
 }
 
 func (s *Database) CompleteSync(id int64, success bool) error {
@@ -165,3 +259,144 @@ func (s *Database) CleanupAbandonedSyncs() error {
 	slog.Info("Successfully marked abandoned syncs as failed", "updated", updated)
 	return nil
 }
+
+func saveSyncChanges(tx *Tx, syncID int64, changes []SyncChange) error {
+	for _, change := range changes {
+		_, err := tx.Exec(`
+			INSERT INTO sync_changes (
+				sync_id, release_id, title, change_type, field, old_value, new_value
+			)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			syncID,
+			change.ReleaseID,
+			change.Title,
+			change.Type,
+			change.Field,
+			change.OldValue,
+			change.NewValue,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record %s change for release %d: %w",
+				change.Type, change.ReleaseID, err)
+		}
+	}
+
+	return nil
+}
+
+// GetSyncHistory returns the most recent syncs, newest first, with a count of
+// their changes by type
+func (s *Database) GetSyncHistory(limit int) ([]SyncHistory, error) {
+	query := `
+		SELECT ` + syncColumns + `
+		FROM syncs
+		ORDER BY id DESC
+		LIMIT ?`
+
+	rows, err := s.DB.Query(query, limit)
+	if err != nil {
+		slog.Error("Failed to query sync history", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []SyncHistory{}
+	index := make(map[int64]int)
+	for rows.Next() {
+		var entry SyncHistory
+		if err := scanSync(rows, &entry.Sync); err != nil {
+			slog.Error("Failed to scan sync history", "error", err)
+			return nil, err
+		}
+		entry.Changes = make(map[SyncChangeType]int)
+		index[entry.ID] = len(history)
+		history = append(history, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(history) == 0 {
+		return history, nil
+	}
+
+	countRows, err := s.DB.Query(`
+		SELECT sync_id, change_type, COUNT(*)
+		FROM sync_changes
+		WHERE sync_id >= ?
+		GROUP BY sync_id, change_type`,
+		history[len(history)-1].ID,
+	)
+	if err != nil {
+		slog.Error("Failed to count sync changes", "error", err)
+		return nil, err
+	}
+	defer countRows.Close()
+
+	for countRows.Next() {
+		var syncID int64
+		var changeType SyncChangeType
+		var count int
+		if err := countRows.Scan(&syncID, &changeType, &count); err != nil {
+			slog.Error("Failed to scan sync change count", "error", err)
+			return nil, err
+		}
+
+		if i, ok := index[syncID]; ok {
+			history[i].Changes[changeType] = count
+		}
+	}
+
+	return history, countRows.Err()
+}
+
+// GetSyncChanges returns everything a sync changed, in the order it happened.
+// It returns sql.ErrNoRows when the sync doesn't exist.
+func (s *Database) GetSyncChanges(syncID int64) ([]SyncChange, error) {
+	var exists bool
+	err := s.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM syncs WHERE id = ?)", syncID).Scan(&exists)
+	if err != nil {
+		slog.Error("Failed to check sync", "error", err, "syncID", syncID)
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT id, sync_id, release_id, COALESCE(title, ''), change_type,
+			field, old_value, new_value, created_at
+		FROM sync_changes
+		WHERE sync_id = ?
+		ORDER BY id`,
+		syncID,
+	)
+	if err != nil {
+		slog.Error("Failed to query sync changes", "error", err, "syncID", syncID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []SyncChange{}
+	for rows.Next() {
+		var change SyncChange
+		err := rows.Scan(
+			&change.ID,
+			&change.SyncID,
+			&change.ReleaseID,
+			&change.Title,
+			&change.Type,
+			&change.Field,
+			&change.OldValue,
+			&change.NewValue,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			slog.Error("Failed to scan sync change", "error", err, "syncID", syncID)
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
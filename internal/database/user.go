@@ -5,14 +5,33 @@ import (
 	"log/slog"
 )
 
+// User is the signed in Discogs account. It has either a personal access
+// Token or, when signed in through OAuth, an OAuth access token and secret.
 type User struct {
-	Username string
-	Token    string
+	Username         string
+	Token            string
+	OAuthToken       string
+	OAuthTokenSecret string
+}
+
+// UsesOAuth reports whether requests for the user are signed with OAuth
+func (u User) UsesOAuth() bool {
+	return u.OAuthToken != ""
+}
+
+// HasCredentials reports whether the user can make authenticated requests
+func (u User) HasCredentials() bool {
+	return u.Token != "" || u.UsesOAuth()
 }
 
 func (s *Database) GetUser() (User, error) {
 	var user User
-	err := s.DB.QueryRow("SELECT username, token FROM auth").Scan(&user.Username, &user.Token)
+	var oauthToken, oauthTokenSecret sql.NullString
+	err := s.DB.QueryRow(`
+		SELECT a.username, a.token, o.access_token, o.access_token_secret
+		FROM auth a
+		LEFT JOIN oauth_credentials o ON o.username = a.username
+	`).Scan(&user.Username, &user.Token, &oauthToken, &oauthTokenSecret)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			slog.Error("Database query error", "error", err)
@@ -20,5 +39,16 @@ func (s *Database) GetUser() (User, error) {
 		return User{}, err
 	}
 
+	// Credentials are encrypted at rest
+	if user.Token, err = s.openSecret(user.Token); err == nil {
+		if user.OAuthToken, err = s.openSecret(oauthToken.String); err == nil {
+			user.OAuthTokenSecret, err = s.openSecret(oauthTokenSecret.String)
+		}
+	}
+	if err != nil {
+		slog.Error("Failed to read stored credentials", "error", err)
+		return User{}, err
+	}
+
 	return user, nil
 }
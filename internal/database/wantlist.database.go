@@ -0,0 +1,259 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// ErrWantStillOnDiscogs is returned when removing a wantlist item that the
+// next sync would only bring back
+var ErrWantStillOnDiscogs = errors.New("release is still on the Discogs wantlist")
+
+// SaveWantlist makes the stored wantlist match Discogs and flags wanted
+// releases that have since been added to the collection as acquired. Ones
+// already in the collection when they were first stored aren't, unless they
+// leave it and come back. Items that left the wantlist are dropped unless they
+// were acquired. It returns the items this sync acquired.
+func (s *Database) SaveWantlist(syncID int64, wants []DiscogsWant) (acquired []WantlistItem, err error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin transaction", "error", err)
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				slog.Error("Failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO wantlist (
+			release_id, title, artists, year, resource_url, thumb, cover_image,
+			rating, notes, date_added, on_discogs, sync_id, already_owned
+		) VALUES (
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, TRUE, ?,
+			EXISTS (SELECT 1 FROM releases WHERE id = ? AND archived = FALSE)
+		)
+		ON CONFLICT(release_id) DO UPDATE SET
+			title = excluded.title,
+			artists = excluded.artists,
+			year = excluded.year,
+			resource_url = excluded.resource_url,
+			thumb = excluded.thumb,
+			cover_image = excluded.cover_image,
+			rating = excluded.rating,
+			notes = excluded.notes,
+			date_added = COALESCE(excluded.date_added, wantlist.date_added),
+			on_discogs = TRUE,
+			sync_id = excluded.sync_id,
+			updated_at = CURRENT_TIMESTAMP`)
+	if err != nil {
+		slog.Error("Failed to prepare wantlist statement", "error", err)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	for _, want := range wants {
+		var year *int
+		if want.BasicInfo.Year > 0 {
+			year = &want.BasicInfo.Year
+		}
+
+		_, err = stmt.Exec(
+			want.ID,
+			want.BasicInfo.Title,
+			artistCredit(want.BasicInfo),
+			year,
+			want.BasicInfo.ResourceURL,
+			want.BasicInfo.Thumb,
+			want.BasicInfo.CoverImage,
+			want.Rating,
+			want.Notes,
+			parseDateAdded(want.ID, want.DateAdded),
+			syncID,
+			want.ID,
+		)
+		if err != nil {
+			slog.Error("Failed to save wantlist item", "error", err, "releaseID", want.ID)
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM wantlist
+		WHERE sync_id IS DISTINCT FROM ? AND acquired = FALSE`,
+		syncID,
+	)
+	if err != nil {
+		slog.Error("Failed to remove wantlist items", "error", err)
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE wantlist
+		SET on_discogs = FALSE, updated_at = CURRENT_TIMESTAMP
+		WHERE sync_id IS DISTINCT FROM ? AND on_discogs = TRUE`,
+		syncID,
+	)
+	if err != nil {
+		slog.Error("Failed to flag wantlist items removed", "error", err)
+		return nil, err
+	}
+
+	// A release that has left the collection is an acquisition again if it
+	// comes back
+	_, err = tx.Exec(`
+		UPDATE wantlist
+		SET already_owned = FALSE
+		WHERE already_owned = TRUE
+		AND release_id NOT IN (SELECT id FROM releases WHERE archived = FALSE)`)
+	if err != nil {
+		slog.Error("Failed to update owned wantlist items", "error", err)
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE wantlist
+		SET acquired = TRUE,
+			acquired_at = CURRENT_TIMESTAMP,
+			acquired_sync_id = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE acquired = FALSE AND already_owned = FALSE
+		AND release_id IN (SELECT id FROM releases WHERE archived = FALSE)`,
+		syncID,
+	)
+	if err != nil {
+		slog.Error("Failed to flag acquired wantlist items", "error", err)
+		return nil, err
+	}
+
+	acquired, err = queryWantlist(tx, `
+		SELECT `+wantlistColumns+`
+		FROM wantlist
+		WHERE acquired_sync_id = ?
+		ORDER BY title`,
+		syncID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		slog.Error("Failed to commit wantlist", "error", err)
+		return nil, err
+	}
+
+	return acquired, nil
+}
+
+// artistCredit joins the release's artists the way Discogs displays them
+func artistCredit(info DiscogsBasicInfo) string {
+	var credit strings.Builder
+	for i, artist := range info.Artists {
+		credit.WriteString(artist.Name)
+		switch {
+		case i == len(info.Artists)-1:
+		case artist.Join == "" || artist.Join == ",":
+			credit.WriteString(", ")
+		default:
+			credit.WriteString(" " + artist.Join + " ")
+		}
+	}
+
+	return credit.String()
+}
+
+const wantlistColumns = `
+	release_id, title, artists, year, resource_url, thumb, cover_image, rating,
+	notes, date_added, on_discogs, acquired, acquired_at, acquired_sync_id,
+	created_at, updated_at`
+
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func queryWantlist(db queryer, query string, args ...any) ([]WantlistItem, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		slog.Error("Failed to query wantlist", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []WantlistItem{}
+	for rows.Next() {
+		var item WantlistItem
+		err := rows.Scan(
+			&item.ReleaseID,
+			&item.Title,
+			&item.Artists,
+			&item.Year,
+			&item.ResourceURL,
+			&item.Thumb,
+			&item.CoverImage,
+			&item.Rating,
+			&item.Notes,
+			&item.DateAdded,
+			&item.OnDiscogs,
+			&item.Acquired,
+			&item.AcquiredAt,
+			&item.AcquiredSyncID,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		)
+		if err != nil {
+			slog.Error("Failed to scan wantlist item", "error", err)
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// GetWantlist returns the wantlist, still-wanted items first. A non-nil
+// acquired limits it to acquired or still-wanted items.
+func (s *Database) GetWantlist(acquired *bool) ([]WantlistItem, error) {
+	query := `
+		SELECT ` + wantlistColumns + `
+		FROM wantlist`
+	var args []any
+	if acquired != nil {
+		query += " WHERE acquired = ?"
+		args = append(args, *acquired)
+	}
+	query += " ORDER BY acquired, acquired_at DESC, date_added DESC, title"
+
+	return queryWantlist(s.DB, query, args...)
+}
+
+// DeleteWantlistItem removes an item that is no longer on the Discogs
+// wantlist, such as an acquired release the user is done with. It returns
+// sql.ErrNoRows when there is no such item and ErrWantStillOnDiscogs when the
+// next sync would add it back.
+func (s *Database) DeleteWantlistItem(releaseID int) error {
+	var onDiscogs bool
+	err := s.DB.QueryRow("SELECT on_discogs FROM wantlist WHERE release_id = ?", releaseID).Scan(&onDiscogs)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.Error("Failed to get wantlist item", "error", err, "releaseID", releaseID)
+		}
+		return err
+	}
+
+	if onDiscogs {
+		return ErrWantStillOnDiscogs
+	}
+
+	if _, err := s.DB.Exec("DELETE FROM wantlist WHERE release_id = ?", releaseID); err != nil {
+		slog.Error("Failed to delete wantlist item", "error", err, "releaseID", releaseID)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import "testing"
+
+func testWant(releaseID int, title string) DiscogsWant {
+	want := DiscogsWant{ID: releaseID, DateAdded: "2024-02-01T12:00:00-08:00"}
+	want.BasicInfo.ID = releaseID
+	want.BasicInfo.Title = title
+
+	return want
+}
+
+func saveTestWantlist(t *testing.T, db *Database, sessionID string, wants ...DiscogsWant) []WantlistItem {
+	t.Helper()
+
+	syncID, err := db.StartSync(SyncModeFull, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acquired, err := db.SaveWantlist(syncID, wants)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return acquired
+}
+
+func TestSaveWantlistFlagsAcquiredReleases(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *Database) {
+		owned := testRelease(100, 5001, 1, "Rumours")
+		saveTestSync(t, db, "sync_1", []DiscogsRelease{owned})
+
+		wants := []DiscogsWant{testWant(100, "Rumours"), testWant(200, "Tusk")}
+
+		// Rumours was in the collection before it was wanted, so the first sync
+		// has nothing acquired
+		if acquired := saveTestWantlist(t, db, "sync_1", wants...); len(acquired) != 0 {
+			t.Fatalf("first sync acquired %+v, want nothing", acquired)
+		}
+
+		// Tusk turns up in the collection on the next sync
+		saveTestSync(t, db, "sync_2", []DiscogsRelease{owned, testRelease(200, 5002, 1, "Tusk")})
+		acquired := saveTestWantlist(t, db, "sync_2", wants...)
+		if len(acquired) != 1 || acquired[0].ReleaseID != 200 {
+			t.Fatalf("acquired %+v, want only Tusk", acquired)
+		}
+
+		// Rumours leaves the collection and is bought again
+		if _, err := db.DB.Exec("UPDATE releases SET archived = TRUE WHERE id = 100"); err != nil {
+			t.Fatal(err)
+		}
+		if acquired := saveTestWantlist(t, db, "sync_3", wants...); len(acquired) != 0 {
+			t.Fatalf("acquired %+v with Rumours gone", acquired)
+		}
+		if _, err := db.DB.Exec("UPDATE releases SET archived = FALSE WHERE id = 100"); err != nil {
+			t.Fatal(err)
+		}
+		acquired = saveTestWantlist(t, db, "sync_4", wants...)
+		if len(acquired) != 1 || acquired[0].ReleaseID != 100 {
+			t.Fatalf("acquired %+v, want Rumours bought again", acquired)
+		}
+	})
+}
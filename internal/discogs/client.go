@@ -0,0 +1,487 @@
+package discogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"kleio/internal/database"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "https://api.discogs.com"
+	UserAgent      = "KleioApp/1.0 +https://github.com/bparsons0904/kleio"
+
+	// AllFolderID is the Discogs folder that contains every release in the collection
+	AllFolderID = 0
+
+	// MaxRetries is how many times a request is retried after a 429 or a
+	// server error before giving up
+	MaxRetries  = 4
+	baseBackoff = 2 * time.Second
+	maxBackoff  = 2 * time.Minute
+)
+
+type Identity struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	ResourceURL string `json:"resource_url"`
+}
+
+// ReleaseDetails is the part of the release resource that Kleio keeps. The
+// resource repeats the summary collection items carry, so a single release
+// can be refreshed without paging through the collection.
+type ReleaseDetails struct {
+	database.DiscogsBasicInfo
+	Country      string                       `json:"country,omitempty"`
+	Released     string                       `json:"released,omitempty"`
+	Identifiers  []database.DiscogsIdentifier `json:"identifiers,omitempty"`
+	Companies    []database.DiscogsCompany    `json:"companies,omitempty"`
+	ExtraArtists []database.DiscogsCredit     `json:"extraartists,omitempty"`
+	Tracklist    []database.DiscogsTrack      `json:"tracklist"`
+	Images       []ReleaseImage               `json:"images,omitempty"`
+}
+
+// ReleaseImage is one of the images on a release resource
+type ReleaseImage struct {
+	Type   string `json:"type"` // "primary" or "secondary"
+	URI    string `json:"uri"`
+	URI150 string `json:"uri150"`
+}
+
+// Cover returns the primary image, falling back to the first one, as the
+// release resource has no cover_image of its own
+func (d ReleaseDetails) Cover() string {
+	if d.CoverImage != "" {
+		return d.CoverImage
+	}
+	for _, image := range d.Images {
+		if image.Type == "primary" {
+			return image.URI
+		}
+	}
+	if len(d.Images) > 0 {
+		return d.Images[0].URI
+	}
+
+	return ""
+}
+
+// ReleaseSort is the ordering requested from the collection releases endpoint
+type ReleaseSort struct {
+	Field string // e.g. "added", "artist", "title"
+	Order string // "asc" or "desc"
+}
+
+// NewestFirst orders collection releases by date added, most recent first
+var NewestFirst = ReleaseSort{Field: "added", Order: "desc"}
+
+// RateLimitError is returned when Discogs still answers with 429 Too Many
+// Requests after MaxRetries
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited: retry after %s", e.RetryAfter)
+}
+
+// StatusError is returned for any other unsuccessful response
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API returned non-200 status: %d", e.StatusCode)
+}
+
+// Client talks to the Discogs API, or anything that speaks it such as the
+// fake server in internal/discogs/fake
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	limiter    *RateLimiter
+
+	// Consumer signs requests for users who signed in with OAuth rather than
+	// a personal access token
+	Consumer OAuthConsumer
+}
+
+// NewClient creates a client for baseURL whose requests all wait on limiter,
+// or on a limiter of its own when it is nil
+func NewClient(baseURL string, limiter *RateLimiter) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	if limiter == nil {
+		limiter = NewRateLimiter(DefaultRateLimit)
+	}
+
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		limiter: limiter,
+	}
+}
+
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+func (c *Client) RateLimit() RateLimitState {
+	return c.limiter.State()
+}
+
+// GetUserIdentity returns who user's credentials belong to, which also checks
+// that they work
+func (c *Client) GetUserIdentity(user database.User) (Identity, error) {
+	var identity Identity
+	err := c.get("/oauth/identity", user, nil, &identity)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	slog.Info("Found user identity", "username", identity.Username, "id", identity.ID)
+	return identity, nil
+}
+
+func (c *Client) GetFolders(user database.User) ([]database.Folder, error) {
+	var foldersResp database.FoldersResponse
+	path := fmt.Sprintf("/users/%s/collection/folders", url.PathEscape(user.Username))
+	if err := c.get(path, user, nil, &foldersResp); err != nil {
+		return nil, err
+	}
+
+	return foldersResp.Folders, nil
+}
+
+// GetCollectionFields fetches the custom fields, such as Media Condition, the
+// user fills in for releases in their collection
+func (c *Client) GetCollectionFields(user database.User) ([]database.DiscogsCollectionField, error) {
+	var response struct {
+		Fields []database.DiscogsCollectionField `json:"fields"`
+	}
+	path := fmt.Sprintf("/users/%s/collection/fields", url.PathEscape(user.Username))
+	if err := c.get(path, user, nil, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Fields, nil
+}
+
+func (c *Client) GetReleasesPage(
+	user database.User,
+	folderID, page, perPage int,
+	sort *ReleaseSort,
+) (database.DiscogsResponse, error) {
+	var response database.DiscogsResponse
+
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(perPage))
+	if sort != nil {
+		query.Set("sort", sort.Field)
+		query.Set("sort_order", sort.Order)
+	}
+
+	path := fmt.Sprintf(
+		"/users/%s/collection/folders/%d/releases",
+		url.PathEscape(user.Username),
+		folderID,
+	)
+	if err := c.get(path, user, query, &response); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// GetWantlistPage fetches one page of the user's wantlist
+func (c *Client) GetWantlistPage(user database.User, page, perPage int) (database.DiscogsWantlistResponse, error) {
+	var response database.DiscogsWantlistResponse
+
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(perPage))
+
+	path := fmt.Sprintf("/users/%s/wants", url.PathEscape(user.Username))
+	if err := c.get(path, user, query, &response); err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+func (c *Client) GetRelease(releaseID int, user database.User) (ReleaseDetails, error) {
+	var details ReleaseDetails
+	if err := c.get(fmt.Sprintf("/releases/%d", releaseID), user, nil, &details); err != nil {
+		return ReleaseDetails{}, err
+	}
+
+	return details, nil
+}
+
+// CollectionValue is Discogs's estimate of the collection's worth, formatted
+// in the user's currency, e.g. "$1,234.56"
+type CollectionValue struct {
+	Minimum string `json:"minimum"`
+	Median  string `json:"median"`
+	Maximum string `json:"maximum"`
+}
+
+func (c *Client) GetCollectionValue(user database.User) (CollectionValue, error) {
+	var value CollectionValue
+	path := fmt.Sprintf("/users/%s/collection/value", url.PathEscape(user.Username))
+	if err := c.get(path, user, nil, &value); err != nil {
+		return CollectionValue{}, err
+	}
+
+	return value, nil
+}
+
+type Price struct {
+	Value    float64 `json:"value"`
+	Currency string  `json:"currency"`
+}
+
+// MarketplaceStats summarises a release's marketplace listings. LowestPrice
+// is nil when no copies are for sale.
+type MarketplaceStats struct {
+	LowestPrice     *Price `json:"lowest_price"`
+	NumForSale      int    `json:"num_for_sale"`
+	BlockedFromSale bool   `json:"blocked_from_sale"`
+}
+
+func (c *Client) GetMarketplaceStats(releaseID int, user database.User) (MarketplaceStats, error) {
+	var stats MarketplaceStats
+	if err := c.get(fmt.Sprintf("/marketplace/stats/%d", releaseID), user, nil, &stats); err != nil {
+		return MarketplaceStats{}, err
+	}
+
+	return stats, nil
+}
+
+// MaxImageSize is the largest image DownloadImage accepts
+const MaxImageSize = 10 << 20
+
+type Image struct {
+	Data        []byte
+	ContentType string
+}
+
+// DownloadImage fetches artwork from the image URLs Discogs hands out. These
+// are served by its CDN rather than the API, so they don't count against the
+// rate limit and aren't retried here.
+func (c *Client) DownloadImage(imageURL string) (Image, error) {
+	req, err := http.NewRequest(http.MethodGet, imageURL, nil)
+	if err != nil {
+		return Image{}, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Image{}, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Image{}, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxImageSize+1))
+	if err != nil {
+		return Image{}, fmt.Errorf("error reading image: %w", err)
+	}
+	if len(data) > MaxImageSize {
+		return Image{}, fmt.Errorf("image is larger than %d bytes", MaxImageSize)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return Image{}, fmt.Errorf("response is %s, not an image", contentType)
+	}
+
+	return Image{Data: data, ContentType: contentType}, nil
+}
+
+// InstanceUpdate changes a release in the collection. Nil fields are left as
+// they are on Discogs.
+type InstanceUpdate struct {
+	Rating   *int `json:"rating,omitempty"`
+	FolderID *int `json:"folder_id,omitempty"`
+}
+
+// UpdateInstance changes the rating of a collection instance and/or moves it
+// out of folderID, the folder it is in on Discogs now
+func (c *Client) UpdateInstance(
+	user database.User,
+	folderID, releaseID, instanceID int,
+	update InstanceUpdate,
+) error {
+	path := fmt.Sprintf(
+		"/users/%s/collection/folders/%d/releases/%d/instances/%d",
+		url.PathEscape(user.Username),
+		folderID,
+		releaseID,
+		instanceID,
+	)
+
+	body, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("error encoding instance update: %w", err)
+	}
+
+	return c.request(http.MethodPost, path, user, nil, body, nil)
+}
+
+// get performs a GET against the API and decodes the JSON body into out
+func (c *Client) get(path string, user database.User, query url.Values, out any) error {
+	return c.request(http.MethodGet, path, user, query, nil, out)
+}
+
+// request calls the API as user and decodes any JSON response body into out.
+// Rate limited and server error responses are retried with backoff.
+func (c *Client) request(method, path string, user database.User, query url.Values, body []byte, out any) error {
+	requestURL := c.baseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.do(method, requestURL, path, user, body, out)
+		if retryAfter == 0 {
+			return err
+		}
+
+		if attempt >= MaxRetries {
+			slog.Error("Giving up on Discogs request",
+				"path", path,
+				"attempts", attempt+1,
+				"error", err)
+			return err
+		}
+
+		wait := max(retryAfter, backoff(attempt))
+
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			// Everyone has to wait out a 429, not just this request
+			c.limiter.Pause(wait)
+		} else {
+			time.Sleep(wait)
+		}
+
+		slog.Info("Retrying Discogs request",
+			"path", path,
+			"attempt", attempt+1,
+			"wait", wait,
+			"error", err)
+	}
+}
+
+// do makes a single request. A non-zero retryAfter means the request may
+// succeed if tried again.
+func (c *Client) do(
+	method, requestURL, path string,
+	user database.User,
+	body []byte,
+	out any,
+) (retryAfter time.Duration, err error) {
+	c.limiter.Wait()
+
+	slog.Debug("Making API request", "method", method, "url", requestURL)
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, requestURL, reqBody)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", UserAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// Credentials go in a header so they never appear in a URL. OAuth
+	// requests are signed per attempt, as each needs a fresh nonce.
+	if user.UsesOAuth() {
+		c.Consumer.Sign(req, OAuthToken{Token: user.OAuthToken, Secret: user.OAuthTokenSecret}, nil)
+	} else {
+		req.Header.Set("Authorization", "Discogs token="+user.Token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	c.limiter.Observe(resp.Header)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		slog.Warn("Rate limited by Discogs API", "path", path, "retryAfter", retryAfter)
+		// A Retry-After of 0 still means try again
+		return max(retryAfter, time.Second), &RateLimitError{RetryAfter: retryAfter}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(resp.Body)
+		slog.Error("API returned non-200 status",
+			"status", resp.StatusCode,
+			"body", string(body),
+			"path", path)
+
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return baseBackoff, statusErr
+		}
+		return 0, statusErr
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return 0, nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return 0, nil
+}
+
+// backoff doubles from baseBackoff with each attempt, up to maxBackoff
+func backoff(attempt int) time.Duration {
+	return min(baseBackoff<<attempt, maxBackoff)
+}
+
+// parseRetryAfter reads a Retry-After header in seconds, defaulting to a
+// minute when it is missing or malformed
+func parseRetryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(header))
+	if err != nil || seconds < 0 {
+		return 60 * time.Second
+	}
+
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,113 @@
+package fake
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"kleio/internal/discogs"
+	"net/http"
+	"net/url"
+)
+
+// The consumer credentials the fake server accepts unless told otherwise
+const (
+	DefaultConsumerKey    = "kleio-fake-key"
+	DefaultConsumerSecret = "kleio-fake-secret"
+)
+
+type requestToken struct {
+	secret   string
+	callback string
+	verifier string // Set once the user has approved the token
+}
+
+// requestToken issues a request token to a correctly signed consumer
+func (s *Server) requestToken(w http.ResponseWriter, r *http.Request) {
+	params, ok := s.Consumer.Verify(r, requestURL(r), "")
+	if !ok || params["oauth_callback"] == "" {
+		writeMessage(w, http.StatusUnauthorized, "Invalid consumer.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, secret := randomToken(), randomToken()
+	s.requestTokens[token] = requestToken{secret: secret, callback: params["oauth_callback"]}
+
+	writeForm(w, url.Values{
+		"oauth_token":              {token},
+		"oauth_token_secret":       {secret},
+		"oauth_callback_confirmed": {"true"},
+	})
+}
+
+// authorize stands in for the page where the user approves the sign in. It
+// approves straight away and sends them back to the consumer's callback.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := r.URL.Query().Get("oauth_token")
+	pending, ok := s.requestTokens[token]
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "Unknown request token.")
+		return
+	}
+
+	pending.verifier = randomToken()
+	s.requestTokens[token] = pending
+
+	callback, err := url.Parse(pending.callback)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, "Invalid callback.")
+		return
+	}
+	query := callback.Query()
+	query.Set("oauth_token", token)
+	query.Set("oauth_verifier", pending.verifier)
+	callback.RawQuery = query.Encode()
+
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+// accessToken trades an approved request token for an access token
+func (s *Server) accessToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	params := discogs.ParseOAuthHeader(r.Header.Get("Authorization"))
+	pending, ok := s.requestTokens[params["oauth_token"]]
+	if !ok || pending.verifier == "" {
+		writeMessage(w, http.StatusUnauthorized, "Invalid request token.")
+		return
+	}
+
+	if _, ok := s.Consumer.Verify(r, requestURL(r), pending.secret); !ok || params["oauth_verifier"] != pending.verifier {
+		writeMessage(w, http.StatusUnauthorized, "Invalid signature or verifier.")
+		return
+	}
+	delete(s.requestTokens, params["oauth_token"])
+
+	token, secret := randomToken(), randomToken()
+	s.accessTokens[token] = secret
+
+	writeForm(w, url.Values{"oauth_token": {token}, "oauth_token_secret": {secret}})
+}
+
+// requestURL is the URL the client signed, which the server only sees the
+// path and query of
+func requestURL(r *http.Request) *url.URL {
+	return &url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeForm(w http.ResponseWriter, values url.Values) {
+	w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(values.Encode()))
+}
@@ -0,0 +1,670 @@
+// Package fake serves a canned Discogs collection over HTTP so syncs can be
+// exercised end to end without the network. Point DISCOGS_BASE_URL at it.
+package fake
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"kleio/internal/database"
+	"kleio/internal/discogs"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed fixtures/collection.json
+var collectionFixture []byte
+
+// Collection is everything the fake server knows about
+type Collection struct {
+	Identity discogs.Identity                  `json:"identity"`
+	Folders  []database.Folder                 `json:"folders"` // Custom folders, "All" (0) is implied
+	Fields   []database.DiscogsCollectionField `json:"fields"`
+	Releases []database.DiscogsRelease         `json:"releases"`
+	Wants    []database.DiscogsWant            `json:"wants"`
+	Details  map[int]discogs.ReleaseDetails    `json:"details"`
+}
+
+// Server is an http.Handler that mimics the Discogs endpoints Kleio uses,
+// including the X-Discogs-Ratelimit headers and 429 responses
+type Server struct {
+	mu         sync.Mutex
+	mux        *http.ServeMux
+	collection Collection
+
+	// RateLimit is the number of requests allowed per RateLimitWindow
+	RateLimit       int
+	RateLimitWindow time.Duration
+	// RetryAfter is sent with every 429 response
+	RetryAfter time.Duration
+	// ThrottleEvery forces a 429 on every nth request when above zero
+	ThrottleEvery int
+	// Consumer is the application allowed to sign in with OAuth. Every
+	// request to authorize is approved straight away.
+	Consumer discogs.OAuthConsumer
+
+	requestTokens map[string]requestToken
+	accessTokens  map[string]string // Token to secret
+
+	windowStart time.Time
+	used        int
+	requests    int
+}
+
+// DefaultCollection returns the canned collection bundled with the package
+func DefaultCollection() (Collection, error) {
+	var collection Collection
+	if err := json.Unmarshal(collectionFixture, &collection); err != nil {
+		return Collection{}, fmt.Errorf("failed to decode collection fixture: %w", err)
+	}
+
+	return collection, nil
+}
+
+// New creates a server backed by the canned collection
+func New() (*Server, error) {
+	collection, err := DefaultCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithCollection(collection), nil
+}
+
+// NewWithCollection creates a server backed by the given collection, with the
+// same authenticated rate limit as Discogs
+func NewWithCollection(collection Collection) *Server {
+	if collection.Details == nil {
+		collection.Details = make(map[int]discogs.ReleaseDetails)
+	}
+
+	server := &Server{
+		mux:             http.NewServeMux(),
+		collection:      collection,
+		RateLimit:       60,
+		RateLimitWindow: time.Minute,
+		RetryAfter:      time.Second,
+		Consumer:        discogs.OAuthConsumer{Key: DefaultConsumerKey, Secret: DefaultConsumerSecret},
+		requestTokens:   make(map[string]requestToken),
+		accessTokens:    make(map[string]string),
+	}
+
+	server.mux.HandleFunc("GET /oauth/identity", server.identity)
+	server.mux.HandleFunc("GET /oauth/request_token", server.requestToken)
+	server.mux.HandleFunc("GET /oauth/authorize", server.authorize)
+	server.mux.HandleFunc("POST /oauth/access_token", server.accessToken)
+	server.mux.HandleFunc("GET /users/{username}/collection/folders", server.folders)
+	server.mux.HandleFunc("GET /users/{username}/collection/folders/{folderID}/releases", server.releases)
+	server.mux.HandleFunc("GET /users/{username}/collection/fields", server.fields)
+	server.mux.HandleFunc("GET /users/{username}/wants", server.wants)
+	server.mux.HandleFunc("GET /users/{username}/collection/value", server.collectionValue)
+	server.mux.HandleFunc("GET /marketplace/stats/{releaseID}", server.marketplaceStats)
+	server.mux.HandleFunc("GET /releases/{releaseID}", server.release)
+	server.mux.HandleFunc("GET /images/{name}", server.image)
+	server.mux.HandleFunc(
+		"POST /users/{username}/collection/folders/{folderID}/releases/{releaseID}/instances/{instanceID}",
+		server.updateInstance,
+	)
+
+	return server
+}
+
+// AddRelease adds a release to the collection, e.g. to drive an incremental sync
+func (s *Server) AddRelease(release database.DiscogsRelease, details discogs.ReleaseDetails) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.collection.Releases = append(s.collection.Releases, release)
+	s.collection.Details[release.ID] = details
+}
+
+// RemoveRelease drops a collection instance, e.g. to drive archiving. It
+// reports whether the instance existed.
+func (s *Server) RemoveRelease(instanceID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, release := range s.collection.Releases {
+		if release.InstanceID == instanceID {
+			s.collection.Releases = append(s.collection.Releases[:i], s.collection.Releases[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// UpdateReleaseDetails replaces what the release resource returns, e.g. to
+// add durations to a tracklist before a refresh. It reports whether the
+// release existed.
+func (s *Server) UpdateReleaseDetails(details discogs.ReleaseDetails) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.collection.Details[details.ID]; !ok {
+		return false
+	}
+
+	s.collection.Details[details.ID] = details
+	return true
+}
+
+// Requests returns how many requests the server has handled
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	slog.Debug("Fake Discogs request", "method", r.Method, "path", r.URL.Path)
+
+	// Artwork comes from the image CDN and the authorize page is on the
+	// website, neither needs a token or is rate limited
+	if strings.HasPrefix(r.URL.Path, "/images/") || r.URL.Path == "/oauth/authorize" {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+
+	if !s.allowRequest(w) {
+		return
+	}
+
+	// The token endpoints check their own signatures
+	isTokenRequest := r.URL.Path == "/oauth/request_token" || r.URL.Path == "/oauth/access_token"
+	if !isTokenRequest && !s.authenticated(r) {
+		writeMessage(w, http.StatusUnauthorized, "You must authenticate to access this resource.")
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// authenticated reports whether r carries a personal access token or is
+// signed with an access token the server issued
+func (s *Server) authenticated(r *http.Request) bool {
+	if token(r) != "" {
+		return true
+	}
+
+	params := discogs.ParseOAuthHeader(r.Header.Get("Authorization"))
+	if params == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	secret, ok := s.accessTokens[params["oauth_token"]]
+	s.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	_, ok = s.Consumer.Verify(r, requestURL(r), secret)
+	return ok
+}
+
+// allowRequest counts the request against the rate limit, writes the rate
+// limit headers and answers with 429 when the limit is exhausted
+func (s *Server) allowRequest(w http.ResponseWriter) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.RateLimitWindow {
+		s.windowStart = now
+		s.used = 0
+	}
+
+	s.requests++
+	throttled := s.used >= s.RateLimit ||
+		(s.ThrottleEvery > 0 && s.requests%s.ThrottleEvery == 0)
+	if !throttled {
+		s.used++
+	}
+
+	w.Header().Set("X-Discogs-Ratelimit", strconv.Itoa(s.RateLimit))
+	w.Header().Set("X-Discogs-Ratelimit-Used", strconv.Itoa(s.used))
+	w.Header().Set("X-Discogs-Ratelimit-Remaining", strconv.Itoa(max(s.RateLimit-s.used, 0)))
+
+	if throttled {
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.RetryAfter.Seconds())))
+		writeMessage(w, http.StatusTooManyRequests, "You are making requests too quickly.")
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) identity(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, s.collection.Identity)
+}
+
+type folderResponse struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Count       int    `json:"count"`
+	ResourceURL string `json:"resource_url"`
+}
+
+func (s *Server) folders(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isUser(w, r) {
+		return
+	}
+
+	folders := []folderResponse{{
+		ID:          discogs.AllFolderID,
+		Name:        "All",
+		Count:       len(s.collection.Releases),
+		ResourceURL: s.folderURL(discogs.AllFolderID),
+	}}
+	for _, folder := range s.collection.Folders {
+		folders = append(folders, folderResponse{
+			ID:          folder.ID,
+			Name:        folder.Name,
+			Count:       len(s.releasesInFolder(folder.ID)),
+			ResourceURL: s.folderURL(folder.ID),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"folders": folders})
+}
+
+func (s *Server) fields(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isUser(w, r) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"fields": s.collection.Fields})
+}
+
+func (s *Server) releases(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isUser(w, r) {
+		return
+	}
+
+	folderID, err := strconv.Atoi(r.PathValue("folderID"))
+	if err != nil || !s.folderExists(folderID) {
+		writeMessage(w, http.StatusNotFound, "Folder not found.")
+		return
+	}
+
+	query := r.URL.Query()
+	page := queryInt(query.Get("page"), 1)
+	perPage := min(queryInt(query.Get("per_page"), 50), 100)
+
+	releases := s.releasesInFolder(folderID)
+	sortReleases(releases, query.Get("sort"), query.Get("sort_order"))
+
+	var response database.DiscogsResponse
+	response.Pagination.Page = page
+	response.Pagination.PerPage = perPage
+	response.Pagination.Items = len(releases)
+	response.Pagination.Pages = (len(releases) + perPage - 1) / perPage
+	if response.Pagination.Pages > page {
+		response.Pagination.URLs.Next = fmt.Sprintf("%s/releases?page=%d&per_page=%d", s.folderURL(folderID), page+1, perPage)
+		response.Pagination.URLs.Last = fmt.Sprintf("%s/releases?page=%d&per_page=%d", s.folderURL(folderID), response.Pagination.Pages, perPage)
+	}
+
+	start := min((page-1)*perPage, len(releases))
+	end := min(start+perPage, len(releases))
+	response.Releases = releases[start:end]
+	for i := range response.Releases {
+		info := &response.Releases[i].BasicInfo
+		info.Thumb = imageURL(r, info.Thumb)
+		info.CoverImage = imageURL(r, info.CoverImage)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) wants(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isUser(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	page := queryInt(query.Get("page"), 1)
+	perPage := min(queryInt(query.Get("per_page"), 50), 100)
+
+	var response database.DiscogsWantlistResponse
+	response.Pagination.Page = page
+	response.Pagination.PerPage = perPage
+	response.Pagination.Items = len(s.collection.Wants)
+	response.Pagination.Pages = (len(s.collection.Wants) + perPage - 1) / perPage
+
+	start := min((page-1)*perPage, len(s.collection.Wants))
+	end := min(start+perPage, len(s.collection.Wants))
+	response.Wants = s.collection.Wants[start:end]
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// lowestPrice makes up a stable price for a release. Every seventh release
+// has no copies for sale.
+func lowestPrice(releaseID int) (float64, bool) {
+	if releaseID%7 == 0 {
+		return 0, false
+	}
+
+	return float64(8+releaseID%40) + 0.99, true
+}
+
+func (s *Server) collectionValue(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isUser(w, r) {
+		return
+	}
+
+	var median float64
+	for _, release := range s.collection.Releases {
+		price, ok := lowestPrice(release.ID)
+		if !ok {
+			price = 15
+		}
+		median += price * 1.5
+	}
+
+	writeJSON(w, http.StatusOK, discogs.CollectionValue{
+		Minimum: formatDollars(median * 0.6),
+		Median:  formatDollars(median),
+		Maximum: formatDollars(median * 2.2),
+	})
+}
+
+func (s *Server) marketplaceStats(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	releaseID, err := strconv.Atoi(r.PathValue("releaseID"))
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Release not found.")
+		return
+	}
+	if _, ok := s.collection.Details[releaseID]; !ok {
+		writeMessage(w, http.StatusNotFound, "Release not found.")
+		return
+	}
+
+	stats := discogs.MarketplaceStats{}
+	if price, ok := lowestPrice(releaseID); ok {
+		stats.LowestPrice = &discogs.Price{Value: price, Currency: "USD"}
+		stats.NumForSale = 1 + releaseID%23
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// formatDollars formats an amount the way Discogs does, e.g. "$1,234.56"
+func formatDollars(amount float64) string {
+	cents := int(amount*100 + 0.5)
+	whole := strconv.Itoa(cents / 100)
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+
+	return fmt.Sprintf("$%s.%02d", whole, cents%100)
+}
+
+// fixtureImagePrefix is where the fixture's artwork claims to live. The fake
+// server hands these URLs out pointing at itself so images can be downloaded.
+const fixtureImagePrefix = "https://i.discogs.com/fake/"
+
+func imageURL(r *http.Request, fixtureURL string) string {
+	name, ok := strings.CutPrefix(fixtureURL, fixtureImagePrefix)
+	if !ok {
+		return fixtureURL
+	}
+
+	return fmt.Sprintf("http://%s/images/%s", r.Host, name)
+}
+
+// image serves a generated PNG for any name ending in .jpg or .png, coloured
+// by the name so each release gets a different one
+func (s *Server) image(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !strings.HasSuffix(name, ".jpg") && !strings.HasSuffix(name, ".png") {
+		writeMessage(w, http.StatusNotFound, "Image not found.")
+		return
+	}
+
+	size := 150
+	if strings.Contains(name, "cover") {
+		size = 600
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	sum := hash.Sum32()
+	fill := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: fill}, image.Point{}, draw.Src)
+
+	w.Header().Set("Content-Type", "image/png")
+	if err := png.Encode(w, img); err != nil {
+		slog.Error("Failed to encode fake image", "error", err, "name", name)
+	}
+}
+
+func (s *Server) release(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	releaseID, err := strconv.Atoi(r.PathValue("releaseID"))
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Release not found.")
+		return
+	}
+
+	details, ok := s.collection.Details[releaseID]
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "Release not found.")
+		return
+	}
+
+	// Like Discogs, the resource carries the summary collection items have,
+	// with its images listed rather than a cover
+	if details.Title == "" {
+		for _, release := range s.collection.Releases {
+			if release.ID == releaseID {
+				masterID := details.MasterID
+				details.DiscogsBasicInfo = release.BasicInfo
+				if masterID != 0 {
+					details.MasterID = masterID
+				}
+				break
+			}
+		}
+	}
+	details.Thumb = imageURL(r, details.Thumb)
+	if details.CoverImage != "" {
+		details.Images = []discogs.ReleaseImage{{
+			Type:   "primary",
+			URI:    imageURL(r, details.CoverImage),
+			URI150: details.Thumb,
+		}}
+		details.CoverImage = ""
+	}
+
+	writeJSON(w, http.StatusOK, details)
+}
+
+// updateInstance changes the rating and/or folder of a collection instance.
+// Like Discogs, the folder in the path has to be the one it is in now.
+func (s *Server) updateInstance(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isUser(w, r) {
+		return
+	}
+
+	folderID, _ := strconv.Atoi(r.PathValue("folderID"))
+	releaseID, _ := strconv.Atoi(r.PathValue("releaseID"))
+	instanceID, _ := strconv.Atoi(r.PathValue("instanceID"))
+
+	var update discogs.InstanceUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Invalid JSON body.")
+		return
+	}
+
+	if update.Rating != nil && (*update.Rating < 0 || *update.Rating > 5) {
+		writeMessage(w, http.StatusUnprocessableEntity, "Rating must be between 0 and 5.")
+		return
+	}
+
+	if update.FolderID != nil && (*update.FolderID == discogs.AllFolderID || !s.folderExists(*update.FolderID)) {
+		writeMessage(w, http.StatusNotFound, "Folder not found.")
+		return
+	}
+
+	for i := range s.collection.Releases {
+		release := &s.collection.Releases[i]
+		if release.ID != releaseID || release.InstanceID != instanceID || release.FolderID != folderID {
+			continue
+		}
+
+		if update.Rating != nil {
+			release.Rating = *update.Rating
+		}
+		if update.FolderID != nil {
+			release.FolderID = *update.FolderID
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeMessage(w, http.StatusNotFound, "Release instance not found.")
+}
+
+func (s *Server) isUser(w http.ResponseWriter, r *http.Request) bool {
+	if !strings.EqualFold(r.PathValue("username"), s.collection.Identity.Username) {
+		writeMessage(w, http.StatusNotFound, "User does not exist or may have been deleted.")
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) folderExists(folderID int) bool {
+	if folderID == discogs.AllFolderID {
+		return true
+	}
+
+	for _, folder := range s.collection.Folders {
+		if folder.ID == folderID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Server) releasesInFolder(folderID int) []database.DiscogsRelease {
+	var releases []database.DiscogsRelease
+	for _, release := range s.collection.Releases {
+		if folderID == discogs.AllFolderID || release.FolderID == folderID {
+			releases = append(releases, release)
+		}
+	}
+
+	return releases
+}
+
+func (s *Server) folderURL(folderID int) string {
+	return fmt.Sprintf(
+		"%s/users/%s/collection/folders/%d",
+		discogs.DefaultBaseURL,
+		s.collection.Identity.Username,
+		folderID,
+	)
+}
+
+// sortReleases applies the Discogs sort and sort_order parameters. Only the
+// fields Kleio uses are supported, anything else keeps the collection order.
+func sortReleases(releases []database.DiscogsRelease, field, order string) {
+	var less func(a, b database.DiscogsRelease) bool
+	switch field {
+	case "added":
+		less = func(a, b database.DiscogsRelease) bool { return dateAdded(a).Before(dateAdded(b)) }
+	case "title":
+		less = func(a, b database.DiscogsRelease) bool { return a.BasicInfo.Title < b.BasicInfo.Title }
+	case "year":
+		less = func(a, b database.DiscogsRelease) bool { return a.BasicInfo.Year < b.BasicInfo.Year }
+	default:
+		return
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		if order == "desc" {
+			return less(releases[j], releases[i])
+		}
+		return less(releases[i], releases[j])
+	})
+}
+
+func dateAdded(release database.DiscogsRelease) time.Time {
+	added, _ := time.Parse(time.RFC3339, release.DateAdded)
+	return added
+}
+
+// token reads a personal access token from the Authorization header, or the
+// token query parameter Discogs also accepts
+func token(r *http.Request) string {
+	if value, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Discogs token="); ok {
+		return value
+	}
+
+	return r.URL.Query().Get("token")
+}
+
+func queryInt(value string, fallback int) int {
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		return fallback
+	}
+
+	return parsed
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("Failed to encode fake Discogs response", "error", err)
+	}
+}
@@ -0,0 +1,257 @@
+package discogs
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAuthorizeURL is the Discogs page where a user approves a request token
+const DefaultAuthorizeURL = "https://www.discogs.com/oauth/authorize"
+
+// OAuthConsumer is the key and secret Discogs issues to a registered
+// application. Both are needed to sign OAuth 1.0a requests.
+type OAuthConsumer struct {
+	Key    string
+	Secret string
+}
+
+func (c OAuthConsumer) Configured() bool {
+	return c.Key != "" && c.Secret != ""
+}
+
+// OAuthToken is a request or access token and its secret
+type OAuthToken struct {
+	Token  string
+	Secret string
+}
+
+// OAuthEnabled reports whether the client has consumer credentials to offer
+// an OAuth sign in with
+func (c *Client) OAuthEnabled() bool {
+	return c.Consumer.Configured()
+}
+
+// GetRequestToken starts an OAuth sign in. Discogs sends the user back to
+// callbackURL once they approve it at AuthorizeURL.
+func (c *Client) GetRequestToken(callbackURL string) (OAuthToken, error) {
+	return c.exchangeToken(http.MethodGet, "/oauth/request_token", OAuthToken{}, map[string]string{
+		"oauth_callback": callbackURL,
+	})
+}
+
+// AuthorizeURL is the page where the user approves requestToken
+func (c *Client) AuthorizeURL(requestToken string) string {
+	authorizeURL := DefaultAuthorizeURL
+	// Anything standing in for the API, such as the fake server, is expected
+	// to serve the authorize page as well
+	if c.baseURL != DefaultBaseURL {
+		authorizeURL = c.baseURL + "/oauth/authorize"
+	}
+
+	return authorizeURL + "?" + url.Values{"oauth_token": {requestToken}}.Encode()
+}
+
+// GetAccessToken trades an approved request token and the verifier Discogs
+// sent to the callback for an access token
+func (c *Client) GetAccessToken(requestToken OAuthToken, verifier string) (OAuthToken, error) {
+	return c.exchangeToken(http.MethodPost, "/oauth/access_token", requestToken, map[string]string{
+		"oauth_verifier": verifier,
+	})
+}
+
+// exchangeToken makes one of the OAuth token requests, which answer with a
+// form encoded token and secret
+func (c *Client) exchangeToken(method, path string, token OAuthToken, extra map[string]string) (OAuthToken, error) {
+	if !c.Consumer.Configured() {
+		return OAuthToken{}, errors.New("no OAuth consumer key and secret configured")
+	}
+
+	c.limiter.Wait()
+
+	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c.Consumer.Sign(req, token, extra)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	c.limiter.Observe(resp.Header)
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		slog.Error("OAuth token request failed", "status", resp.StatusCode, "body", string(body), "path", path)
+		return OAuthToken{}, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	result := OAuthToken{Token: values.Get("oauth_token"), Secret: values.Get("oauth_token_secret")}
+	if result.Token == "" || result.Secret == "" {
+		return OAuthToken{}, errors.New("response did not include an OAuth token and secret")
+	}
+
+	return result, nil
+}
+
+// Sign sets an HMAC-SHA1 OAuth Authorization header on req, covering its
+// method, URL and query string. extra holds protocol parameters such as
+// oauth_callback or oauth_verifier.
+func (c OAuthConsumer) Sign(req *http.Request, token OAuthToken, extra map[string]string) {
+	params := map[string]string{
+		"oauth_consumer_key":     c.Key,
+		"oauth_nonce":            nonce(),
+		"oauth_signature_method": "HMAC-SHA1",
+		"oauth_timestamp":        strconv.FormatInt(time.Now().Unix(), 10),
+		"oauth_version":          "1.0",
+	}
+	if token.Token != "" {
+		params["oauth_token"] = token.Token
+	}
+	for key, value := range extra {
+		params[key] = value
+	}
+
+	params["oauth_signature"] = c.signature(req.Method, req.URL, params, token.Secret)
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	header := make([]string, len(keys))
+	for i, key := range keys {
+		header[i] = fmt.Sprintf(`%s="%s"`, key, percentEncode(params[key]))
+	}
+	req.Header.Set("Authorization", "OAuth "+strings.Join(header, ", "))
+}
+
+// Verify checks the signature of a request signed by Sign, given the secret of
+// the token it names. It returns the OAuth parameters from the header.
+func (c OAuthConsumer) Verify(req *http.Request, requestURL *url.URL, tokenSecret string) (map[string]string, bool) {
+	params := ParseOAuthHeader(req.Header.Get("Authorization"))
+	if params == nil || params["oauth_consumer_key"] != c.Key {
+		return nil, false
+	}
+
+	signature := params["oauth_signature"]
+	unsigned := make(map[string]string, len(params))
+	for key, value := range params {
+		if key != "oauth_signature" {
+			unsigned[key] = value
+		}
+	}
+
+	expected := c.signature(req.Method, requestURL, unsigned, tokenSecret)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, false
+	}
+
+	return params, true
+}
+
+// ParseOAuthHeader reads the parameters of an OAuth Authorization header, or
+// returns nil when it isn't one
+func ParseOAuthHeader(header string) map[string]string {
+	rest, ok := strings.CutPrefix(header, "OAuth ")
+	if !ok {
+		return nil
+	}
+
+	params := make(map[string]string)
+	for _, part := range strings.Split(rest, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		value, err := url.PathUnescape(strings.Trim(value, `"`))
+		if err != nil {
+			return nil
+		}
+		params[key] = value
+	}
+
+	return params
+}
+
+// signature computes the RFC 5849 HMAC-SHA1 signature over the method, the
+// URL without its query, and the query and OAuth parameters together
+func (c OAuthConsumer) signature(method string, requestURL *url.URL, params map[string]string, tokenSecret string) string {
+	var pairs [][2]string
+	for key, values := range requestURL.Query() {
+		for _, value := range values {
+			pairs = append(pairs, [2]string{percentEncode(key), percentEncode(value)})
+		}
+	}
+	for key, value := range params {
+		pairs = append(pairs, [2]string{percentEncode(key), percentEncode(value)})
+	}
+	// Sorted by encoded name, then value
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+
+	normalized := make([]string, len(pairs))
+	for i, pair := range pairs {
+		normalized[i] = pair[0] + "=" + pair[1]
+	}
+
+	baseURL := url.URL{
+		Scheme: strings.ToLower(requestURL.Scheme),
+		Host:   strings.ToLower(requestURL.Host),
+		Path:   requestURL.Path,
+	}
+	base := strings.Join([]string{
+		strings.ToUpper(method),
+		percentEncode(baseURL.String()),
+		percentEncode(strings.Join(normalized, "&")),
+	}, "&")
+
+	mac := hmac.New(sha1.New, []byte(percentEncode(c.Secret)+"&"+percentEncode(tokenSecret)))
+	mac.Write([]byte(base))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// percentEncode escapes everything but the RFC 3986 unreserved characters
+func percentEncode(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+func nonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+
+	return hex.EncodeToString(b)
+}
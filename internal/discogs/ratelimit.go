@@ -0,0 +1,200 @@
+package discogs
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRateLimit is the number of requests Discogs allows an
+	// authenticated client per RateLimitWindow
+	DefaultRateLimit = 60
+	RateLimitWindow  = time.Minute
+
+	// waitNotifyThreshold keeps OnWait quiet for the short pauses between
+	// requests that a steady sync makes anyway
+	waitNotifyThreshold = time.Second
+)
+
+// RateLimitState is a snapshot of the limiter for the API and progress events
+type RateLimitState struct {
+	Limit       int        `json:"limit"`                // Requests allowed per window
+	Used        int        `json:"used"`                 // From the last X-Discogs-Ratelimit-Used header
+	Remaining   int        `json:"remaining"`            // From the last X-Discogs-Ratelimit-Remaining header
+	Tokens      float64    `json:"tokens"`               // Requests that can be made right now
+	Waiting     int        `json:"waiting"`              // Callers blocked on the limiter
+	RateLimited int        `json:"rateLimited"`          // 429 responses seen since startup
+	PausedUntil *time.Time `json:"pausedUntil,omitzero"` // Set while honouring a Retry-After
+	UpdatedAt   *time.Time `json:"updatedAt,omitzero"`   // When the headers were last seen
+}
+
+// RateLimiter is a token bucket shared by every request to Discogs. It refills
+// at Limit per RateLimitWindow, is pulled down to whatever the response headers
+// say is remaining, and stops all callers while a Retry-After runs out.
+type RateLimiter struct {
+	mutex sync.Mutex
+
+	limit       int
+	tokens      float64
+	refilledAt  time.Time
+	pausedUntil time.Time
+
+	used        int
+	remaining   int
+	updatedAt   time.Time
+	waiting     int
+	rateLimited int
+
+	// OnWait, if set, is called before a caller sleeps for a second or more
+	OnWait func(wait time.Duration, state RateLimitState)
+}
+
+func NewRateLimiter(limit int) *RateLimiter {
+	if limit <= 0 {
+		limit = DefaultRateLimit
+	}
+
+	return &RateLimiter{
+		limit:      limit,
+		tokens:     float64(limit),
+		refilledAt: time.Now(),
+		remaining:  limit,
+	}
+}
+
+// Wait blocks until a request may be made and takes a token for it
+func (l *RateLimiter) Wait() {
+	for {
+		l.mutex.Lock()
+		wait := l.reserve(time.Now())
+		if wait <= 0 {
+			l.mutex.Unlock()
+			return
+		}
+		l.waiting++
+		state := l.state()
+		onWait := l.OnWait
+		l.mutex.Unlock()
+
+		slog.Debug("Waiting for Discogs rate limit", "wait", wait, "tokens", state.Tokens)
+		if onWait != nil && wait >= waitNotifyThreshold {
+			onWait(wait, state)
+		}
+		time.Sleep(wait)
+
+		l.mutex.Lock()
+		l.waiting--
+		l.mutex.Unlock()
+	}
+}
+
+// reserve takes a token if one is available, otherwise it returns how long to
+// wait before trying again
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	l.refill(now)
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	perToken := RateLimitWindow / time.Duration(l.limit)
+	return time.Duration((1 - l.tokens) * float64(perToken))
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.refilledAt)
+	if elapsed <= 0 {
+		return
+	}
+
+	l.tokens += elapsed.Seconds() / RateLimitWindow.Seconds() * float64(l.limit)
+	l.tokens = min(l.tokens, float64(l.limit))
+	l.refilledAt = now
+}
+
+// Observe brings the bucket in line with the X-Discogs-Ratelimit headers.
+// Discogs counts over a moving window, so the bucket only ever shrinks to
+// match it and refills at its own pace.
+func (l *RateLimiter) Observe(header http.Header) {
+	limit, limitErr := strconv.Atoi(header.Get("X-Discogs-Ratelimit"))
+	used, usedErr := strconv.Atoi(header.Get("X-Discogs-Ratelimit-Used"))
+	remaining, remainingErr := strconv.Atoi(header.Get("X-Discogs-Ratelimit-Remaining"))
+	if limitErr != nil || usedErr != nil || remainingErr != nil {
+		slog.Warn("Failed to parse rate limit headers",
+			"limit", header.Get("X-Discogs-Ratelimit"),
+			"used", header.Get("X-Discogs-Ratelimit-Used"),
+			"remaining", header.Get("X-Discogs-Ratelimit-Remaining"))
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.refill(now)
+
+	if limit > 0 {
+		l.limit = limit
+	}
+	l.used = used
+	l.remaining = remaining
+	l.updatedAt = now
+	l.tokens = min(l.tokens, float64(remaining), float64(l.limit))
+
+	slog.Debug("Updated rate limits",
+		"limit", l.limit,
+		"used", l.used,
+		"remaining", l.remaining,
+		"tokens", l.tokens)
+}
+
+// Pause stops every caller for d, after a 429 response
+func (l *RateLimiter) Pause(d time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+	l.tokens = 0
+	l.rateLimited++
+
+	slog.Warn("Pausing Discogs requests", "pause", d, "until", l.pausedUntil)
+}
+
+func (l *RateLimiter) State() RateLimitState {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.refill(time.Now())
+	return l.state()
+}
+
+func (l *RateLimiter) state() RateLimitState {
+	state := RateLimitState{
+		Limit:       l.limit,
+		Used:        l.used,
+		Remaining:   l.remaining,
+		Tokens:      l.tokens,
+		Waiting:     l.waiting,
+		RateLimited: l.rateLimited,
+	}
+	if time.Now().Before(l.pausedUntil) {
+		pausedUntil := l.pausedUntil
+		state.PausedUntil = &pausedUntil
+	}
+	if !l.updatedAt.IsZero() {
+		updatedAt := l.updatedAt
+		state.UpdatedAt = &updatedAt
+	}
+
+	return state
+}
@@ -0,0 +1,125 @@
+package discogs
+
+import (
+	"kleio/internal/database"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func rateLimitHeader(limit, used, remaining string) http.Header {
+	header := http.Header{}
+	header.Set("X-Discogs-Ratelimit", limit)
+	header.Set("X-Discogs-Ratelimit-Used", used)
+	header.Set("X-Discogs-Ratelimit-Remaining", remaining)
+
+	return header
+}
+
+func TestRateLimiterHonoursRemaining(t *testing.T) {
+	limiter := NewRateLimiter(DefaultRateLimit)
+	now := time.Now()
+
+	limiter.Observe(rateLimitHeader("60", "57", "3"))
+	for i := range 3 {
+		if wait := limiter.reserve(now); wait != 0 {
+			t.Fatalf("request %d waited %s with requests remaining", i+1, wait)
+		}
+	}
+
+	// Out of requests, the next one waits for a token to refill at the
+	// limit's pace of one a second
+	wait := limiter.reserve(now)
+	if wait <= 0 || wait > time.Second {
+		t.Fatalf("wait with none remaining = %s, want up to a second", wait)
+	}
+
+	state := limiter.State()
+	if state.Used != 57 || state.Remaining != 3 || state.UpdatedAt == nil {
+		t.Errorf("state = %+v, want the observed headers", state)
+	}
+}
+
+func TestRateLimiterAdoptsObservedLimit(t *testing.T) {
+	limiter := NewRateLimiter(DefaultRateLimit)
+	limiter.Observe(rateLimitHeader("600", "600", "0"))
+
+	// 600 a minute refills a token every 100ms
+	start := time.Now()
+	limiter.Wait()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("Wait took %s, want about 100ms", elapsed)
+	}
+}
+
+func TestRateLimiterIgnoresMalformedHeaders(t *testing.T) {
+	limiter := NewRateLimiter(DefaultRateLimit)
+	limiter.Observe(rateLimitHeader("60", "", "0"))
+
+	if wait := limiter.reserve(time.Now()); wait != 0 {
+		t.Fatalf("malformed headers emptied the bucket, wait = %s", wait)
+	}
+}
+
+func TestClientWaitsOutRetryAfter(t *testing.T) {
+	const retryAfter = 3 * time.Second
+
+	var mutex sync.Mutex
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, time.Now())
+		first := len(requests) == 1
+		mutex.Unlock()
+
+		w.Header().Set("X-Discogs-Ratelimit", "60")
+		w.Header().Set("X-Discogs-Ratelimit-Used", "1")
+		w.Header().Set("X-Discogs-Ratelimit-Remaining", "59")
+		if first {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"folders": []}`))
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(DefaultRateLimit)
+	var waits []time.Duration
+	limiter.OnWait = func(wait time.Duration, state RateLimitState) {
+		waits = append(waits, wait)
+	}
+	client := NewClient(server.URL, limiter)
+
+	if _, err := client.GetFolders(database.User{Username: "kleio", Token: "token"}); err != nil {
+		t.Fatalf("request failed after a 429: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("server saw %d requests, want the 429 and one retry", len(requests))
+	}
+	if gap := requests[1].Sub(requests[0]); gap < retryAfter {
+		t.Errorf("retried after %s, want at least the Retry-After of %s", gap, retryAfter)
+	}
+	if len(waits) == 0 {
+		t.Error("OnWait wasn't told about the pause")
+	}
+	if state := limiter.State(); state.RateLimited != 1 {
+		t.Errorf("rate limited count = %d, want 1", state.RateLimited)
+	}
+}
+
+func TestPauseStopsEveryCaller(t *testing.T) {
+	limiter := NewRateLimiter(DefaultRateLimit)
+	limiter.Pause(time.Minute)
+
+	now := time.Now()
+	if wait := limiter.reserve(now); wait < 59*time.Second {
+		t.Fatalf("wait during a pause = %s, want the rest of the minute", wait)
+	}
+	if state := limiter.State(); state.PausedUntil == nil {
+		t.Error("state doesn't show the pause")
+	}
+}
@@ -0,0 +1,119 @@
+// Package images keeps downloaded artwork in a content addressed store: each
+// file is named by the sha256 of its contents, so identical images are only
+// stored once and a file never changes once written.
+package images
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var ErrInvalidHash = errors.New("invalid image hash")
+
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store in dir, which is created on first write
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Put stores data and returns its hash. Storing the same bytes twice is a
+// no-op.
+func (s *Store) Put(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	path, err := s.Path(hash)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create image directory: %w", err)
+	}
+
+	// Write then rename so a reader never sees a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create image file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write image file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write image file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to store image file: %w", err)
+	}
+
+	return hash, nil
+}
+
+// Path is where the image with the given hash is kept, fanned out by the
+// first two characters so no directory grows too large
+func (s *Store) Path(hash string) (string, error) {
+	if len(hash) != sha256.Size*2 {
+		return "", ErrInvalidHash
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", ErrInvalidHash
+	}
+
+	return filepath.Join(s.dir, hash[:2], hash), nil
+}
+
+// Open opens a stored image for reading
+func (s *Store) Open(hash string) (*os.File, error) {
+	path, err := s.Path(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+// Sweep deletes every stored image whose hash keep doesn't contain and
+// returns how many were removed. Files being written by Put are left alone.
+func (s *Store) Sweep(keep map[string]bool) (int, error) {
+	removed := 0
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		hash := entry.Name()
+		if expected, err := s.Path(hash); err != nil || expected != path || keep[hash] {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove image file: %w", err)
+		}
+		removed++
+		return nil
+	})
+
+	return removed, err
+}
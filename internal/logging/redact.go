@@ -0,0 +1,174 @@
+// Package logging keeps credentials out of log output. Install it with Setup
+// before anything logs.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are words that mark an attribute key whose value is never
+// logged, such as token in "token", "access_token" or "oauthToken". They only
+// match whole words, so a count of "tokens" is still logged.
+var sensitiveKeys = []string{"token", "secret", "password", "authorization", "signature", "verifier"}
+
+// credentialPattern finds credentials inside free text, such as a token in a
+// query string or an Authorization header value
+var credentialPattern = regexp.MustCompile(
+	`(?i)((?:token|oauth_token|oauth_token_secret|oauth_signature|oauth_verifier|secret)\s*=\s*"?)[^&\s",]+`,
+)
+
+var (
+	secretsMu sync.RWMutex
+	secrets   = map[string]struct{}{}
+)
+
+// Setup sends slog output through a redacting text handler on stderr
+func Setup() {
+	slog.SetDefault(slog.New(NewRedactingHandler(slog.NewTextHandler(os.Stderr, nil))))
+}
+
+// RegisterSecret makes sure value is redacted wherever it turns up in a log
+// line, whatever it is called
+func RegisterSecret(value string) {
+	// Anything shorter would mangle unrelated output
+	if len(value) < 8 {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets[value] = struct{}{}
+}
+
+// Redact removes credentials from s
+func Redact(s string) string {
+	s = credentialPattern.ReplaceAllString(s, "${1}"+redacted)
+
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+
+	return s
+}
+
+// redactingHandler scrubs the message and attributes of every record before
+// passing it on
+type redactingHandler struct {
+	inner slog.Handler
+}
+
+func NewRedactingHandler(inner slog.Handler) slog.Handler {
+	return &redactingHandler{inner: inner}
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	clean := slog.NewRecord(record.Time, record.Level, Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		clean.AddAttrs(redactAttr(attr))
+		return true
+	})
+
+	return h.inner.Handle(ctx, clean)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		clean[i] = redactAttr(attr)
+	}
+
+	return &redactingHandler{inner: h.inner.WithAttrs(clean)}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{inner: h.inner.WithGroup(name)}
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	if isSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		clean := make([]any, len(group))
+		for i, member := range group {
+			clean[i] = redactAttr(member)
+		}
+		return slog.Group(attr.Key, clean...)
+	case slog.KindAny:
+		// Errors and anything else printable may carry a URL or a
+		// response body, so they are logged as their redacted text. fmt
+		// copes with nil pointers that would panic if called directly.
+		switch printable := value.Any().(type) {
+		case error, fmt.Stringer:
+			return slog.String(attr.Key, Redact(fmt.Sprint(printable)))
+		}
+		if text := fmt.Sprintf("%+v", value.Any()); Redact(text) != text {
+			return slog.String(attr.Key, Redact(text))
+		}
+	}
+
+	return slog.Attr{Key: attr.Key, Value: value}
+}
+
+func isSensitiveKey(key string) bool {
+	for _, word := range keyWords(key) {
+		if slices.Contains(sensitiveKeys, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keyWords splits an attribute key such as "oauth_token" or "accessToken" into
+// its lower case words
+func keyWords(key string) []string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, strings.ToLower(word.String()))
+			word.Reset()
+		}
+	}
+
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			// The start of a word in camelCase, or the last capital of an
+			// acronym such as the T in "OAuthToken"
+			flush()
+		}
+		word.WriteRune(r)
+	}
+	flush()
+
+	return words
+}
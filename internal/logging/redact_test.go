@@ -0,0 +1,30 @@
+package logging
+
+import "testing"
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		sensitive bool
+	}{
+		{"token", true},
+		{"Token", true},
+		{"access_token", true},
+		{"oauth_token_secret", true},
+		{"accessToken", true},
+		{"OAuthTokenSecret", true},
+		{"consumer-secret", true},
+		{"Authorization", true},
+		{"tokens", false},
+		{"tokenBucket", true},
+		{"remaining", false},
+		{"secretsFound", false},
+		{"releaseID", false},
+	}
+
+	for _, test := range tests {
+		if got := isSensitiveKey(test.key); got != test.sensitive {
+			t.Errorf("isSensitiveKey(%q) = %v, want %v", test.key, got, test.sensitive)
+		}
+	}
+}
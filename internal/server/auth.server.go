@@ -1,8 +1,14 @@
 package server
 
 import (
+	"database/sql"
+	"errors"
+	"kleio/internal/controller"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 )
 
 func (s *Server) getAuth(w http.ResponseWriter, r *http.Request) {
@@ -37,3 +43,81 @@ func (s *Server) SaveToken(w http.ResponseWriter, r *http.Request) {
 
 	writeData(w, payload)
 }
+
+// startOAuth begins a Discogs sign in and returns the authorizeUrl to send
+// the user to. Discogs brings them back to OAUTH_CALLBACK_URL, which defaults
+// to the callback route on the host this request came in on.
+func (s *Server) startOAuth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	callbackURL := os.Getenv("OAUTH_CALLBACK_URL")
+	if callbackURL == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		callbackURL = scheme + "://" + r.Host + "/api/auth/oauth/callback"
+	}
+
+	authorizeURL, err := s.controller.StartOAuth(callbackURL)
+	if err != nil {
+		if errors.Is(err, controller.ErrOAuthNotConfigured) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Failed to start Discogs sign in", http.StatusBadGateway)
+		return
+	}
+
+	writeData(w, map[string]string{"authorizeUrl": authorizeURL})
+}
+
+// oauthCallback is where Discogs sends the user after they approve or deny
+// the sign in. It finishes signing in and redirects to OAUTH_REDIRECT_URL,
+// the app root by default, with ?oauth=denied or ?oauth=failed when it
+// didn't work.
+func (s *Server) oauthCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	redirectURL := os.Getenv("OAUTH_REDIRECT_URL")
+	if redirectURL == "" {
+		redirectURL = "/"
+	}
+
+	query := r.URL.Query()
+	if query.Get("denied") != "" {
+		http.Redirect(w, r, withQuery(redirectURL, "oauth", "denied"), http.StatusFound)
+		return
+	}
+
+	requestToken, verifier := query.Get("oauth_token"), query.Get("oauth_verifier")
+	if requestToken == "" || verifier == "" {
+		http.Error(w, "oauth_token and oauth_verifier are required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.controller.CompleteOAuth(requestToken, verifier); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			slog.Warn("OAuth callback for an unknown request token")
+		}
+		http.Redirect(w, r, withQuery(redirectURL, "oauth", "failed"), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+func withQuery(rawURL, key, value string) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+
+	return rawURL + separator + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"errors"
+	"kleio/internal/backup"
+	"kleio/internal/controller"
+	"kleio/internal/database"
+	"net/http"
+)
+
+func (s *Server) getBackups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshots, err := s.controller.ListBackups()
+	if err != nil {
+		http.Error(w, "Failed to list backups", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, snapshots)
+}
+
+// createBackup takes a snapshot of the database straight away
+func (s *Server) createBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot, err := s.controller.CreateBackup(controller.BackupLabelManual)
+	if errors.Is(err, database.ErrBackupUnsupported) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to back up database", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeData(w, snapshot)
+}
+
+// restoreBackup swaps a snapshot in for the live database, answering with
+// the snapshot of the data it replaced
+func (s *Server) restoreBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, err := getPathName(r.URL.Path, "backups")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	preRestore, err := s.controller.RestoreBackup(name)
+	if err != nil {
+		switch {
+		case errors.Is(err, backup.ErrInvalidName), errors.Is(err, backup.ErrNotFound):
+			http.Error(w, "Backup not found", http.StatusNotFound)
+		case errors.Is(err, database.ErrBackupUnsupported):
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+		case errors.Is(err, database.ErrInvalidSnapshot):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(err, controller.ErrSyncInProgress):
+			http.Error(w, "A sync is in progress, try again when it finishes", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to restore backup", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeData(w, map[string]any{
+		"restored":   name,
+		"preRestore": preRestore,
+	})
+}
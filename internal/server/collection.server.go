@@ -1,6 +1,8 @@
 package server
 
 import (
+	"fmt"
+	"kleio/internal/database"
 	"log/slog"
 	"net/http"
 	"time"
@@ -20,7 +22,7 @@ func (s *Server) updateCollection(w http.ResponseWriter, r *http.Request) {
 	slog.Info("Collection sync requested by user")
 
 	// Check if there's already a sync in progress
-	latestSync, err := s.DB.GetLatestSync()
+	latestSync, err := s.controller.GetLatestSync()
 	if err != nil {
 		slog.Error("Failed to check latest sync before starting new one", "error", err)
 		http.Error(w, "Failed to check sync status", http.StatusInternalServerError)
@@ -40,9 +42,22 @@ func (s *Server) updateCollection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	slog.Info("Starting new collection sync in background")
+	// An explicit mode forces a full reconciliation or an incremental sync,
+	// otherwise the controller picks one
+	mode := database.SyncMode(r.URL.Query().Get("mode"))
+	if mode != "" && mode != database.SyncModeFull && mode != database.SyncModeIncremental {
+		http.Error(w, "Invalid sync mode", http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Starting new collection sync in background", "mode", mode)
 	go func() {
-		err := s.controller.AsyncCollection()
+		var err error
+		if mode == "" {
+			err = s.controller.AsyncCollection()
+		} else {
+			err = s.controller.AsyncCollectionWithMode(mode)
+		}
 		if err != nil {
 			slog.Error("Background sync failed", "error", err)
 		} else {
@@ -55,24 +70,62 @@ func (s *Server) updateCollection(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) checkSync(w http.ResponseWriter, r *http.Request) {
-	sync, err := s.DB.GetLatestSync()
+	sync, err := s.controller.GetLatestSync()
 	if err != nil {
 		http.Error(w, "Failed to check sync", http.StatusInternalServerError)
 		return
 	}
 
-	if sync.Status == "in_progress" && time.Since(sync.SyncStart) > 1*time.Minute {
-		err := s.DB.CompleteSync(sync.ID, false)
-		if err != nil {
-			slog.Error("Failed to complete sync", "error", err)
-			err := s.DB.CleanupAbandonedSyncs()
-			if err != nil {
-				slog.Error("Failed to cleanup abandoned syncs", "error", err)
+	writeData(w, sync)
+}
+
+// syncEvents streams sync progress as Server-Sent Events until the client
+// disconnects
+func (s *Server) syncEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("Failed to clear write deadline for sync events", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	events, unsubscribe := s.controller.Events.Subscribe()
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	if err := rc.Flush(); err != nil {
+		slog.Error("Sync events stream does not support flushing", "error", err)
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if err := writeEvent(w, string(event.Type), event); err != nil {
+				slog.Debug("Sync events client went away", "error", err)
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
 			}
-			http.Error(w, "Failed to complete sync", http.StatusInternalServerError)
+		}
+
+		if err := rc.Flush(); err != nil {
 			return
 		}
 	}
-
-	writeData(w, sync)
 }
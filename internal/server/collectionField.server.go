@@ -0,0 +1,62 @@
+package server
+
+import (
+	"errors"
+	"kleio/internal/database"
+	"net/http"
+)
+
+func (s *Server) getCollectionFields(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fields, err := s.controller.GetCollectionFields()
+	if err != nil {
+		http.Error(w, "Failed to get collection fields", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, fields)
+}
+
+// filterCollection returns releases by the value of a collection field, e.g.
+// ?field=Media%20Condition&op=gte&value=VG%2B. The field is a name or ID and op
+// defaults to eq. gte and lte compare dropdown options by their Discogs order,
+// which for condition grades is best first.
+func (s *Server) filterCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := database.NoteFilter{
+		Field: query.Get("field"),
+		Op:    database.NoteFilterOp(query.Get("op")),
+		Value: query.Get("value"),
+	}
+	if filter.Field == "" || filter.Value == "" {
+		http.Error(w, "field and value are required", http.StatusBadRequest)
+		return
+	}
+	if filter.Op == "" {
+		filter.Op = database.NoteFilterEq
+	}
+
+	releases, err := s.controller.FilterReleasesByNote(filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrFieldNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, database.ErrInvalidNoteFilter):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to filter collection", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeData(w, releases)
+}
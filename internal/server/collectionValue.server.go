@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// getCollectionValue returns the collection value series for charting,
+// optionally limited to the last ?days=N days
+func (s *Server) getCollectionValue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var since time.Time
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 1 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		since = time.Now().AddDate(0, 0, -days)
+	}
+
+	snapshots, err := s.controller.GetCollectionValueHistory(since)
+	if err != nil {
+		http.Error(w, "Failed to get collection value", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, snapshots)
+}
@@ -0,0 +1,18 @@
+package server
+
+import "net/http"
+
+func (s *Server) getDiscogsWrites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writes, err := s.controller.GetDiscogsWrites()
+	if err != nil {
+		http.Error(w, "Failed to get Discogs writes", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, writes)
+}
@@ -19,7 +19,7 @@ func getToken(r *http.Request) (string, error) {
 	}
 
 	if requestBody.Token == "" {
-		slog.Error("Token is required", "token", requestBody.Token)
+		slog.Error("Token is required")
 		return "", nil
 	}
 
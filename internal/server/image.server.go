@@ -0,0 +1,62 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"kleio/internal/controller"
+	"kleio/internal/database"
+	"net/http"
+	"path"
+	"time"
+)
+
+// getReleaseImage serves /api/images/:releaseId/{thumb,cover} from the local
+// image cache, or redirects to Discogs when it hasn't been downloaded yet
+func (s *Server) getReleaseImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	releaseID, err := getPathID(r.URL.Path, "images")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	kind := database.ImageKind(path.Base(r.URL.Path))
+	if kind != database.ImageThumb && kind != database.ImageCover {
+		http.Error(w, "Image must be thumb or cover", http.StatusBadRequest)
+		return
+	}
+
+	image, file, err := s.controller.OpenReleaseImage(releaseID, kind)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Release not found", http.StatusNotFound)
+		case errors.Is(err, controller.ErrImageNotCached) && image.SourceURL != "":
+			// Not cached yet, so don't let the browser hold on to the redirect
+			w.Header().Set("Cache-Control", "no-store")
+			http.Redirect(w, r, image.SourceURL, http.StatusFound)
+		case errors.Is(err, controller.ErrImageNotCached):
+			http.Error(w, "Release has no image", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to get image", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer file.Close()
+
+	// The URL stays the same when the artwork changes, so browsers revalidate
+	// daily against the content hash
+	w.Header().Set("Content-Type", image.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("ETag", `"`+image.Hash+`"`)
+
+	var modified time.Time
+	if image.FetchedAt != nil {
+		modified = *image.FetchedAt
+	}
+	http.ServeContent(w, r, "", modified, file)
+}
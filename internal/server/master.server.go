@@ -0,0 +1,72 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// getMasters lists owned pressings grouped by master. ?minPressings=2 limits
+// it to albums owned more than once and ?stats=true adds play statistics.
+func (s *Server) getMasters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	minPressings := 1
+	if value := r.URL.Query().Get("minPressings"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid minPressings", http.StatusBadRequest)
+			return
+		}
+		minPressings = parsed
+	}
+
+	withStats, err := queryBool(r, "stats")
+	if err != nil {
+		http.Error(w, "Invalid stats flag", http.StatusBadRequest)
+		return
+	}
+
+	masters, err := s.controller.GetMasters(minPressings, withStats)
+	if err != nil {
+		http.Error(w, "Failed to get masters", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, masters)
+}
+
+func (s *Server) getMaster(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := getPathID(r.URL.Path, "masters")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	withStats, err := queryBool(r, "stats")
+	if err != nil {
+		http.Error(w, "Invalid stats flag", http.StatusBadRequest)
+		return
+	}
+
+	master, err := s.controller.GetMaster(id, withStats)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Master not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get master", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, master)
+}
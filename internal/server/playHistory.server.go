@@ -129,8 +129,20 @@ func (s *Server) deletePlayHistory(w http.ResponseWriter, r *http.Request) {
 	writeData(w, payload)
 }
 
+// getPlayCounts returns plays per release. With ?by=master each release
+// counts the plays of every pressing of its master.
 func (s *Server) getPlayCounts(w http.ResponseWriter, r *http.Request) {
-	playCounts, err := s.controller.GetPlayCountByRelease()
+	getPlayCounts := s.controller.GetPlayCountByRelease
+	switch r.URL.Query().Get("by") {
+	case "", "release":
+	case "master":
+		getPlayCounts = s.controller.GetPlayCountByMaster
+	default:
+		http.Error(w, "Invalid grouping", http.StatusBadRequest)
+		return
+	}
+
+	playCounts, err := getPlayCounts()
 	if err != nil {
 		http.Error(w, "Failed to get play counts", http.StatusInternalServerError)
 		return
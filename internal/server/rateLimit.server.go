@@ -0,0 +1,12 @@
+package server
+
+import "net/http"
+
+func (s *Server) getRateLimit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeData(w, s.controller.GetRateLimit())
+}
@@ -1,6 +1,19 @@
 package server
 
-import "net/http"
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"kleio/internal/controller"
+	"kleio/internal/database"
+	"kleio/internal/discogs"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
 
 func (s *Server) deleteRelease(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
@@ -8,7 +21,7 @@ func (s *Server) deleteRelease(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := getIDFrom3_2Parts(r.URL.Path)
+	id, err := getPathID(r.URL.Path, "releases")
 	if err != nil {
 		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
@@ -24,22 +37,311 @@ func (s *Server) deleteRelease(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) archiveRelease(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
+	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	id, err := getIDFrom3_2Parts(r.URL.Path)
+	id, err := getPathID(r.URL.Path, "releases")
 	if err != nil {
 		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
 	}
 
-	releases, err := s.controller.ArchiveRelease(id)
+	// The body is optional, archives default to a user action
+	var requestBody struct {
+		Reason database.ArchiveReason `json:"reason"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil && !errors.Is(err, io.EOF) {
+		slog.Error("Failed to decode request body", "error", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	reason := requestBody.Reason
+	if reason == "" {
+		reason = database.ArchiveReasonUserAction
+	}
+	if !reason.IsValid() {
+		http.Error(w, "Invalid archive reason", http.StatusBadRequest)
+		return
+	}
+
+	payload, err := s.controller.ArchiveRelease(id, reason)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Release not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "Failed to archive release", http.StatusInternalServerError)
 		return
 	}
 
+	writeData(w, payload)
+}
+
+func (s *Server) restoreRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := getPathID(r.URL.Path, "releases")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	payload, err := s.controller.RestoreRelease(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Release not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to restore release", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, payload)
+}
+
+const maxReleasePageSize = 200
+
+// listReleases pages through the active collection. It filters by folder,
+// genre, style, artist, label and format, yearFrom and yearTo, played=true or
+// false and lastPlayedBefore. sort, order=desc, limit and cursor page through
+// it, and include lists the relations to load, e.g. include=artists,labels.
+func (s *Server) listReleases(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	query := database.ReleaseQuery{
+		Genre:  params.Get("genre"),
+		Style:  params.Get("style"),
+		Artist: params.Get("artist"),
+		Label:  params.Get("label"),
+		Format: params.Get("format"),
+		Sort:   database.ReleaseSort(params.Get("sort")),
+		Cursor: params.Get("cursor"),
+	}
+
+	var err error
+	if query.FolderID, err = queryInt(r, "folder"); err != nil {
+		http.Error(w, "Invalid folder", http.StatusBadRequest)
+		return
+	}
+	if query.YearFrom, err = queryInt(r, "yearFrom"); err != nil {
+		http.Error(w, "Invalid yearFrom", http.StatusBadRequest)
+		return
+	}
+	if query.YearTo, err = queryInt(r, "yearTo"); err != nil {
+		http.Error(w, "Invalid yearTo", http.StatusBadRequest)
+		return
+	}
+
+	if value := params.Get("played"); value != "" {
+		played, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid played flag", http.StatusBadRequest)
+			return
+		}
+		query.Played = &played
+	}
+
+	if value := params.Get("lastPlayedBefore"); value != "" {
+		before, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "Invalid lastPlayedBefore time format", http.StatusBadRequest)
+			return
+		}
+		query.LastPlayedBefore = &before
+	}
+
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		http.Error(w, "Invalid order", http.StatusBadRequest)
+		return
+	}
+
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxReleasePageSize {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		query.Limit = limit
+	}
+
+	for _, relation := range strings.Split(params.Get("include"), ",") {
+		if relation = strings.TrimSpace(relation); relation != "" {
+			query.Include = append(query.Include, database.ReleaseRelation(relation))
+		}
+	}
+
+	page, err := s.controller.ListReleases(query)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidReleaseQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to list releases", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, page)
+}
+
+func (s *Server) getArchivedReleases(w http.ResponseWriter, r *http.Request) {
+	releases, err := s.controller.GetArchivedReleases()
+	if err != nil {
+		http.Error(w, "Failed to get archived releases", http.StatusInternalServerError)
+		return
+	}
+
 	writeData(w, releases)
 }
+
+func (s *Server) updateReleaseRating(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := getPathID(r.URL.Path, "releases")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody struct {
+		Rating *int `json:"rating"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		slog.Error("Failed to decode request body", "error", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Discogs ratings run from 1 to 5, with 0 clearing the rating
+	if requestBody.Rating == nil || *requestBody.Rating < 0 || *requestBody.Rating > 5 {
+		http.Error(w, "Rating must be between 0 and 5", http.StatusBadRequest)
+		return
+	}
+
+	payload, err := s.controller.UpdateReleaseRating(id, *requestBody.Rating)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Release not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update rating", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, payload)
+}
+
+func (s *Server) moveRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := getPathID(r.URL.Path, "releases")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody struct {
+		FolderID int `json:"folderId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		slog.Error("Failed to decode request body", "error", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	payload, err := s.controller.MoveRelease(id, requestBody.FolderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Release not found", http.StatusNotFound)
+		case errors.Is(err, database.ErrFolderNotFound):
+			http.Error(w, "Folder not found", http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to move release", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeData(w, payload)
+}
+
+func (s *Server) getRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := getPathID(r.URL.Path, "releases")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	release, err := s.controller.GetRelease(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Release not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get release", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, release)
+}
+
+// refreshRelease fetches a release from Discogs again, e.g. after its
+// tracklist was corrected, and returns it updated
+func (s *Server) refreshRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := getPathID(r.URL.Path, "releases")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	release, err := s.controller.RefreshRelease(id)
+	if err != nil {
+		var statusErr *discogs.StatusError
+		var rateLimitErr *discogs.RateLimitError
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Release not found", http.StatusNotFound)
+		case errors.Is(err, controller.ErrNotSignedIn):
+			http.Error(w, "Sign in to Discogs first", http.StatusUnauthorized)
+		case errors.Is(err, controller.ErrSyncInProgress):
+			http.Error(w, "A sync is in progress, try again when it finishes", http.StatusConflict)
+		case errors.As(err, &statusErr), errors.As(err, &rateLimitErr):
+			http.Error(w, "Failed to fetch release from Discogs", http.StatusBadGateway)
+		default:
+			http.Error(w, "Failed to refresh release", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeData(w, release)
+}
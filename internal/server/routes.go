@@ -17,12 +17,39 @@ func (s *Server) RegisterRoutes(app *fiber.App) {
 	// Auth and Collection routes
 	api.Get("/auth", adaptor.HTTPHandlerFunc(s.getAuth))
 	api.Post("/auth/token", adaptor.HTTPHandlerFunc(s.SaveToken))
+	api.Post("/auth/oauth/request", adaptor.HTTPHandlerFunc(s.startOAuth))
+	api.Get("/auth/oauth/callback", adaptor.HTTPHandlerFunc(s.oauthCallback))
 	api.Get("/collection", adaptor.HTTPHandlerFunc(s.getCollection))
 	api.Get("/collection/sync", adaptor.HTTPHandlerFunc(s.checkSync))
+	api.Get("/collection/value", adaptor.HTTPHandlerFunc(s.getCollectionValue))
+	api.Get("/collection/fields", adaptor.HTTPHandlerFunc(s.getCollectionFields))
+	api.Get("/collection/filter", adaptor.HTTPHandlerFunc(s.filterCollection))
 	api.Post("/collection/resync", adaptor.HTTPHandlerFunc(s.updateCollection))
 	api.Post("/discogs/collection/refresh", adaptor.HTTPHandlerFunc(s.updateCollection))
+	api.Get("/discogs/ratelimit", adaptor.HTTPHandlerFunc(s.getRateLimit))
+	api.Get("/discogs/writes", adaptor.HTTPHandlerFunc(s.getDiscogsWrites))
+	api.Get("/syncs", adaptor.HTTPHandlerFunc(s.getSyncHistory))
+	api.Get("/syncs/schedule", adaptor.HTTPHandlerFunc(s.getSyncSchedule))
+	api.Get("/syncs/:id/changes", adaptor.HTTPHandlerFunc(s.getSyncChanges))
+	api.Get("/syncs/:id/folders", adaptor.HTTPHandlerFunc(s.getSyncFolderChanges))
 	api.Delete("/releases/:id/delete", adaptor.HTTPHandlerFunc(s.deleteRelease))
+	api.Get("/releases", adaptor.HTTPHandlerFunc(s.listReleases))
+	api.Get("/releases/archived", adaptor.HTTPHandlerFunc(s.getArchivedReleases))
+	api.Get("/releases/:id", adaptor.HTTPHandlerFunc(s.getRelease))
 	api.Post("/releases/:id/archive", adaptor.HTTPHandlerFunc(s.archiveRelease))
+	api.Post("/releases/:id/restore", adaptor.HTTPHandlerFunc(s.restoreRelease))
+	api.Post("/releases/:id/refresh", adaptor.HTTPHandlerFunc(s.refreshRelease))
+	api.Put("/releases/:id/rating", adaptor.HTTPHandlerFunc(s.updateReleaseRating))
+	api.Put("/releases/:id/folder", adaptor.HTTPHandlerFunc(s.moveRelease))
+
+	api.Get("/images/:releaseId/:kind", adaptor.HTTPHandlerFunc(s.getReleaseImage))
+	api.Get("/masters", adaptor.HTTPHandlerFunc(s.getMasters))
+	api.Get("/masters/:id", adaptor.HTTPHandlerFunc(s.getMaster))
+	api.Get("/search", adaptor.HTTPHandlerFunc(s.search))
+
+	// Wantlist routes
+	api.Get("/wantlist", adaptor.HTTPHandlerFunc(s.getWantlist))
+	api.Delete("/wantlist/:id", adaptor.HTTPHandlerFunc(s.deleteWantlistItem))
 
 	api.Post("/styluses", adaptor.HTTPHandlerFunc(s.createStylus))
 	api.Put("/styluses/:id", adaptor.HTTPHandlerFunc(s.updateStylus))
@@ -45,6 +72,11 @@ func (s *Server) RegisterRoutes(app *fiber.App) {
 
 	api.Get("/export/history", adaptor.HTTPHandlerFunc(s.exportHistory))
 
+	// Database backups
+	api.Get("/admin/backups", adaptor.HTTPHandlerFunc(s.getBackups))
+	api.Post("/admin/backups", adaptor.HTTPHandlerFunc(s.createBackup))
+	api.Post("/admin/backups/:name/restore", adaptor.HTTPHandlerFunc(s.restoreBackup))
+
 	// Setup static file server for SPA
 	distDir := "./clio/dist"
 	app.Static("/", distDir)
@@ -26,24 +26,44 @@ func (s *Server) setupApp() http.Handler {
 
 	s.RegisterRoutes(app)
 
-	return adaptor.FiberApp(app)
+	// The Fiber adaptor buffers the whole response, so streaming routes are
+	// served by net/http directly and everything else falls through to Fiber
+	mux := http.NewServeMux()
+	mux.Handle("/api/collection/sync/events", s.corsMiddleware(http.HandlerFunc(s.syncEvents)))
+	mux.Handle("/", adaptor.FiberApp(app))
+
+	return mux
 }
 
 type Server struct {
 	port       int
-	DB         database.Database
 	controller *controller.Controller
 }
 
 func NewServer() *http.Server {
 	port, _ := strconv.Atoi(os.Getenv("APP_PORT"))
 	slog.Info("Starting server...", "port", port)
+	db := database.New()
 	NewServer := &Server{
-		port:       port,
-		DB:         database.New(),
-		controller: controller.InitNewController(),
+		port: port,
+		controller: controller.InitNewController(
+			controller.DatabaseStores(db),
+			db.DataDir(),
+		),
 	}
 
+	// Pick up a sync that was cut short by the last shutdown
+	go func() {
+		if err := NewServer.controller.ResumeInterruptedSync(); err != nil {
+			slog.Error("Failed to resume interrupted sync", "error", err)
+		}
+	}()
+
+	go NewServer.controller.RunWriteBackQueue()
+	go NewServer.controller.RunImageCache()
+	go NewServer.controller.RunSyncScheduler()
+	go NewServer.controller.RunBackupScheduler()
+
 	// Declare Server config
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", NewServer.port),
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) getSyncHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	history, err := s.controller.GetSyncHistory(limit)
+	if err != nil {
+		http.Error(w, "Failed to get sync history", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, history)
+}
+
+func (s *Server) getSyncChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := getPathID(r.URL.Path, "syncs")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := s.controller.GetSyncChanges(int64(id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Sync not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get sync changes", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, changes)
+}
+
+func (s *Server) getSyncFolderChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := getPathID(r.URL.Path, "syncs")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := s.controller.GetFolderChanges(int64(id))
+	if err != nil {
+		http.Error(w, "Failed to get folder changes", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, changes)
+}
+
+// getSyncSchedule reports the sync interval, quiet hours and when the next
+// scheduled sync will start
+func (s *Server) getSyncSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	schedule, err := s.controller.GetSyncSchedule()
+	if err != nil {
+		http.Error(w, "Failed to get sync schedule", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, schedule)
+}
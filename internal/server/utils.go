@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -21,6 +22,17 @@ func writeData(w http.ResponseWriter, data any) {
 	}
 }
 
+// writeEvent writes a single Server-Sent Event with a JSON payload
+func writeEvent(w http.ResponseWriter, event string, data any) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, jsonData)
+	return err
+}
+
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers
@@ -45,11 +57,51 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func getIDFrom3_2Parts(path string) (int, error) {
+// getPathID returns the numeric segment that follows resource in the path,
+// e.g. getPathID("/api/releases/42/archive", "releases") returns 42
+func getPathID(path, resource string) (int, error) {
 	parts := strings.Split(path, "/")
-	if len(parts) < 3 {
-		return 0, nil
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == resource {
+			return strconv.Atoi(parts[i+1])
+		}
+	}
+	return 0, fmt.Errorf("no %s id in path %s", resource, path)
+}
+
+// getPathName returns the segment that follows resource in the path, e.g.
+// getPathName("/api/admin/backups/a.db/restore", "backups") returns a.db
+func getPathName(path, resource string) (string, error) {
+	parts := strings.Split(path, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == resource && parts[i+1] != "" {
+			return parts[i+1], nil
+		}
 	}
-	idStr := parts[2]
-	return strconv.Atoi(idStr)
+	return "", fmt.Errorf("no %s name in path %s", resource, path)
+}
+
+// queryBool parses an optional boolean query parameter, false when absent
+func queryBool(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(value)
+}
+
+// queryInt parses an optional integer query parameter, nil when absent
+func queryInt(r *http.Request, name string) (*int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
 }
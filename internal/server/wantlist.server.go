@@ -0,0 +1,63 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"kleio/internal/database"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) getWantlist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Optional ?acquired=true|false
+	var acquired *bool
+	if value := r.URL.Query().Get("acquired"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid acquired filter", http.StatusBadRequest)
+			return
+		}
+		acquired = &parsed
+	}
+
+	wantlist, err := s.controller.GetWantlist(acquired)
+	if err != nil {
+		http.Error(w, "Failed to get wantlist", http.StatusInternalServerError)
+		return
+	}
+
+	writeData(w, wantlist)
+}
+
+func (s *Server) deleteWantlistItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := getPathID(r.URL.Path, "wantlist")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	wantlist, err := s.controller.DeleteWantlistItem(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Wantlist item not found", http.StatusNotFound)
+		case errors.Is(err, database.ErrWantStillOnDiscogs):
+			http.Error(w, "Remove the release from the Discogs wantlist first", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to delete wantlist item", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeData(w, wantlist)
+}